@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Handler returns the admin endpoint of the agent:
+//
+//	GET  /healthz              200 if every continuous gadget runs, 503 otherwise
+//	GET  /runs                 the RunStatus of every gadget
+//	GET  /errors               the recent errors, oldest first
+//	POST /runs/{name}/trigger  runs a periodic gadget now
+//
+// Responses are JSON.
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		unhealthy, err := a.Unhealthy()
+		switch {
+		case err != nil:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "stopped"})
+		case len(unhealthy) > 0:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "degraded", "down": unhealthy})
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+		}
+	})
+	mux.HandleFunc("GET /runs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.Status())
+	})
+	mux.HandleFunc("GET /errors", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.Errors())
+	})
+	mux.HandleFunc("POST /runs/{name}/trigger", func(w http.ResponseWriter, r *http.Request) {
+		err := a.Trigger(r.PathValue("name"))
+		switch {
+		case errors.Is(err, ErrUnknownGadget):
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusConflict, map[string]any{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusAccepted, map[string]any{"status": "triggered"})
+		}
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
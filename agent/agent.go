@@ -0,0 +1,373 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/sink"
+)
+
+// Errors of Trigger.
+var (
+	ErrUnknownGadget = errors.New("unknown gadget")
+	ErrNotPeriodic   = errors.New("gadget is not periodic")
+	ErrNotRunning    = errors.New("agent is not running")
+)
+
+// Gadget is a gadget run by an Agent.
+type Gadget struct {
+	// Name identifies the gadget in the admin endpoint.
+	Name  string
+	Image string
+	// Flags are passed to ig run.
+	Flags []string
+	// Every, if set, runs the gadget periodically, e.g. a snapshot gadget or
+	// a tracing one bounded with "--timeout", instead of supervising it
+	// continuously.
+	Every time.Duration
+	// Supervise configures the supervision of continuous gadgets.
+	Supervise ig.SuperviseOptions
+	// Sink, if set, receives the events of the gadget. The agent closes it
+	// once it stopped.
+	Sink sink.Sink
+}
+
+// Options configures an Agent.
+type Options struct {
+	Gadgets []Gadget
+	// Addr, if set, is where Run serves the admin endpoint (see Handler).
+	Addr string
+	// RetryInterval is how long a continuous gadget whose supervisor gave up
+	// waits before being supervised again, 30s if zero.
+	RetryInterval time.Duration
+	// MaxErrors is how many recent errors are kept, 20 if zero.
+	MaxErrors int
+}
+
+// RunStatus is the state of a gadget of an agent.
+type RunStatus struct {
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	Periodic bool   `json:"periodic"`
+	Running  bool   `json:"running"`
+	// RunID identifies the current run, or the last one.
+	RunID string `json:"runId,omitempty"`
+	// Runs counts the runs of periodic gadgets and the supervisions of
+	// continuous ones, Restarts the restarts of their supervisors.
+	Runs         int       `json:"runs"`
+	Restarts     int       `json:"restarts"`
+	Events       uint64    `json:"events"`
+	LastStarted  time.Time `json:"lastStarted"`
+	LastFinished time.Time `json:"lastFinished"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// ErrorRecord is an error of a gadget of an agent: a failed run, a stall
+// or a sink error.
+type ErrorRecord struct {
+	Time   time.Time `json:"time"`
+	Gadget string    `json:"gadget"`
+	Error  string    `json:"error"`
+}
+
+// Agent runs gadgets until its context is done. It is safe for concurrent
+// use.
+type Agent struct {
+	ig      *ig.IG
+	opts    Options
+	gadgets []*gadgetState
+	byName  map[string]*gadgetState
+
+	mu      sync.Mutex
+	running bool
+	errs    []ErrorRecord
+}
+
+type gadgetState struct {
+	g       Gadget
+	trigger chan struct{}
+
+	mu     sync.Mutex
+	status RunStatus
+	sup    *ig.Supervisor
+}
+
+// New returns an agent running the gadgets of opts with i. Gadget names
+// must be unique.
+func New(i *ig.IG, opts Options) (*Agent, error) {
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = 30 * time.Second
+	}
+	if opts.MaxErrors == 0 {
+		opts.MaxErrors = 20
+	}
+	a := &Agent{ig: i, opts: opts, byName: map[string]*gadgetState{}}
+	for _, g := range opts.Gadgets {
+		switch {
+		case g.Name == "":
+			return nil, fmt.Errorf("gadget %s has no name", g.Image)
+		case a.byName[g.Name] != nil:
+			return nil, fmt.Errorf("duplicate gadget %s", g.Name)
+		case g.Every < 0:
+			return nil, fmt.Errorf("gadget %s: invalid period %s", g.Name, g.Every)
+		}
+		st := &gadgetState{
+			g:       g,
+			trigger: make(chan struct{}, 1),
+			status:  RunStatus{Name: g.Name, Image: g.Image, Periodic: g.Every > 0},
+		}
+		a.gadgets = append(a.gadgets, st)
+		a.byName[g.Name] = st
+	}
+	return a, nil
+}
+
+// Run runs the gadgets, and serves the admin endpoint if Options.Addr is
+// set, until ctx is done. It then stops the gadgets, closes their sinks and
+// returns the errors of closing them or of serving.
+func (a *Agent) Run(ctx context.Context) error {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return errors.New("agent already running")
+	}
+	a.running = true
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.running = false
+		a.mu.Unlock()
+	}()
+
+	var errs []error
+	var srv *http.Server
+	var served chan error
+	if a.opts.Addr != "" {
+		l, err := net.Listen("tcp", a.opts.Addr)
+		if err != nil {
+			return fmt.Errorf("serving admin endpoint: %w", err)
+		}
+		srv = &http.Server{Handler: a.Handler()}
+		served = make(chan error, 1)
+		go func() { served <- srv.Serve(l) }()
+	}
+
+	var wg sync.WaitGroup
+	for _, st := range a.gadgets {
+		wg.Add(1)
+		go func(st *gadgetState) {
+			defer wg.Done()
+			if st.g.Every > 0 {
+				a.schedule(ctx, st)
+			} else {
+				a.supervise(ctx, st)
+			}
+		}(st)
+	}
+	wg.Wait()
+
+	for _, st := range a.gadgets {
+		if st.g.Sink != nil {
+			if err := st.g.Sink.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("closing sink of %s: %w", st.g.Name, err))
+			}
+		}
+	}
+	if srv != nil {
+		srv.Close()
+		if err := <-served; err != http.ErrServerClosed {
+			errs = append(errs, fmt.Errorf("serving admin endpoint: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// supervise keeps a continuous gadget running until ctx is done, starting a
+// new supervisor RetryInterval after one gave up.
+func (a *Agent) supervise(ctx context.Context, st *gadgetState) {
+	for {
+		sup, err := a.ig.Supervise(ctx, st.g.Image, st.g.Supervise, st.g.Flags...)
+		if err != nil {
+			a.recordErr(st, err)
+		} else {
+			st.mu.Lock()
+			st.sup = sup
+			st.status.Running = true
+			st.status.Runs++
+			st.status.LastStarted = time.Now()
+			st.mu.Unlock()
+
+			go func() {
+				for stall := range sup.Stalls() {
+					a.recordErr(st, fmt.Errorf("run %s stalled for %s, restarting", stall.RunID, stall.Idle.Round(time.Second)))
+				}
+			}()
+			a.forward(st, sup.Events())
+			err := sup.Wait()
+
+			st.mu.Lock()
+			st.status.Running = false
+			st.status.Restarts += sup.Restarts()
+			st.status.RunID = sup.Session().RunID()
+			st.status.LastFinished = time.Now()
+			st.sup = nil
+			st.mu.Unlock()
+			if err != nil {
+				a.recordErr(st, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.opts.RetryInterval):
+		}
+	}
+}
+
+// schedule runs a periodic gadget every period and on triggers, until ctx
+// is done.
+func (a *Agent) schedule(ctx context.Context, st *gadgetState) {
+	t := time.NewTicker(st.g.Every)
+	defer t.Stop()
+	for ctx.Err() == nil {
+		a.runOnce(ctx, st)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-st.trigger:
+		}
+	}
+}
+
+func (a *Agent) runOnce(ctx context.Context, st *gadgetState) {
+	s, err := a.ig.Start(ctx, st.g.Image, st.g.Flags...)
+	if err != nil {
+		a.recordErr(st, err)
+		return
+	}
+	st.mu.Lock()
+	st.status.Running = true
+	st.status.Runs++
+	st.status.RunID = s.RunID()
+	st.status.LastStarted = time.Now()
+	st.mu.Unlock()
+
+	a.forward(st, s.Events())
+	err = s.Wait()
+	if ctx.Err() != nil {
+		// Stopped by the agent: the gadget was killed by the cancellation.
+		err = nil
+	}
+
+	st.mu.Lock()
+	st.status.Running = false
+	st.status.LastFinished = time.Now()
+	st.mu.Unlock()
+	if err != nil {
+		a.recordErr(st, err)
+	}
+}
+
+// forward writes events to the sink of st until the channel is closed.
+func (a *Agent) forward(st *gadgetState, events <-chan ig.Event) {
+	for ev := range events {
+		st.mu.Lock()
+		st.status.Events++
+		st.mu.Unlock()
+		if st.g.Sink == nil {
+			continue
+		}
+		if err := st.g.Sink.Write(ev); err != nil {
+			a.recordErr(st, fmt.Errorf("writing event: %w", err))
+		}
+	}
+}
+
+func (a *Agent) recordErr(st *gadgetState, err error) {
+	rec := ErrorRecord{Time: time.Now(), Gadget: st.g.Name, Error: err.Error()}
+
+	st.mu.Lock()
+	st.status.LastError = rec.Error
+	st.mu.Unlock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.errs = append(a.errs, rec)
+	if n := len(a.errs) - a.opts.MaxErrors; n > 0 {
+		a.errs = append(a.errs[:0], a.errs[n:]...)
+	}
+}
+
+// Trigger runs the periodic gadget name now, or right after its current run.
+func (a *Agent) Trigger(name string) error {
+	st := a.byName[name]
+	if st == nil {
+		return fmt.Errorf("%w %s", ErrUnknownGadget, name)
+	}
+	if st.g.Every == 0 {
+		return fmt.Errorf("%w: %s", ErrNotPeriodic, name)
+	}
+	a.mu.Lock()
+	running := a.running
+	a.mu.Unlock()
+	if !running {
+		return ErrNotRunning
+	}
+
+	select {
+	case st.trigger <- struct{}{}:
+	default:
+		// A run is already pending.
+	}
+	return nil
+}
+
+// Status returns the state of every gadget, in the order of Options.Gadgets.
+func (a *Agent) Status() []RunStatus {
+	statuses := make([]RunStatus, len(a.gadgets))
+	for i, st := range a.gadgets {
+		st.mu.Lock()
+		statuses[i] = st.status
+		if st.sup != nil {
+			statuses[i].Restarts += st.sup.Restarts()
+			statuses[i].RunID = st.sup.Session().RunID()
+		}
+		st.mu.Unlock()
+	}
+	return statuses
+}
+
+// Errors returns the recent errors, oldest first.
+func (a *Agent) Errors() []ErrorRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]ErrorRecord{}, a.errs...)
+}
+
+// Unhealthy returns the names of the continuous gadgets that aren't
+// running, or an error if the agent isn't running.
+func (a *Agent) Unhealthy() ([]string, error) {
+	a.mu.Lock()
+	running := a.running
+	a.mu.Unlock()
+	if !running {
+		return nil, ErrNotRunning
+	}
+
+	var names []string
+	for _, s := range a.Status() {
+		if !s.Periodic && !s.Running {
+			names = append(names, s.Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,15 @@
+// Package agent builds a node agent running gadgets from this module: it
+// supervises continuous gadgets, runs periodic ones on a schedule, writes
+// their events to sinks and serves a small HTTP admin endpoint reporting
+// health, active runs and recent errors, and triggering runs on demand.
+//
+//	a, err := agent.New(i, agent.Options{
+//		Addr: "127.0.0.1:8090",
+//		Gadgets: []agent.Gadget{
+//			{Name: "exec", Image: "trace_exec", Sink: execs},
+//			{Name: "procs", Image: "snapshot_process", Every: time.Hour, Sink: procs},
+//		},
+//	})
+//	...
+//	err = a.Run(ctx)
+package agent
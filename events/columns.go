@@ -0,0 +1,341 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Column maps a column of the columns output of ig to event fields.
+type Column struct {
+	// Header is the header of the column, matched case-insensitively.
+	Header string
+	// Path is the dot-separated path of the field the column sets, e.g.
+	// "proc.pid".
+	Path string
+	// Numeric makes the value a number, as in JSON output, if it parses as
+	// one.
+	Numeric bool
+	// Set, if not nil, sets the fields of the column in place of Path, for
+	// columns packing several fields, e.g. "addr:port".
+	Set func(fields map[string]any, value string)
+}
+
+// ColumnParser parses the columns output of a gadget into events with the
+// fields of its JSON output, for ig versions printing columns only. Columns
+// are found from the header line, so their order and width don't matter.
+// Columns without a Column are kept with their lowercased header as path.
+type ColumnParser struct {
+	// Gadget is the gadget of the events, e.g. "trace_exec".
+	Gadget  string
+	Columns []Column
+}
+
+// column returns the Column of header, matching it case-insensitively.
+func (p ColumnParser) column(header string) Column {
+	for _, c := range p.Columns {
+		if strings.EqualFold(c.Header, header) {
+			return c
+		}
+	}
+	for _, c := range commonColumns {
+		if strings.EqualFold(c.Header, header) {
+			return c
+		}
+	}
+	return Column{Header: header, Path: strings.ToLower(header)}
+}
+
+// header is the layout of a columns output, from its header line.
+type header struct {
+	columns []Column
+	// starts are the offsets of the columns in the header line.
+	starts []int
+	line   string
+}
+
+func (p ColumnParser) parseHeader(line string) header {
+	h := header{line: line}
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i == len(line) {
+			break
+		}
+		start := i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		h.columns = append(h.columns, p.column(line[start:i]))
+		h.starts = append(h.starts, start)
+	}
+	return h
+}
+
+// values splits line into the values of the columns of h. The last column
+// takes the rest of the line, as it may hold spaces, e.g. arguments.
+func (h header) values(line string) []string {
+	n := len(h.columns)
+	values := make([]string, n)
+
+	// Aligned columns are cut at the offsets of the header, which keeps
+	// empty cells; lines whose values overflow their column are split on
+	// spaces instead.
+	aligned := len(line) > h.starts[n-1]
+	for _, s := range h.starts[1:] {
+		if !aligned {
+			break
+		}
+		aligned = s <= len(line) && line[s-1] == ' '
+	}
+	if aligned {
+		for i := range values {
+			end := len(line)
+			if i+1 < n {
+				end = h.starts[i+1]
+			}
+			values[i] = strings.TrimSpace(line[h.starts[i]:end])
+		}
+		return values
+	}
+
+	rest := strings.TrimSpace(line)
+	for i := 0; i < n-1 && rest != ""; i++ {
+		v, r, _ := strings.Cut(rest, " ")
+		values[i] = v
+		rest = strings.TrimLeft(r, " ")
+	}
+	values[n-1] = rest
+	return values
+}
+
+// event returns the event of a line of output.
+func (p ColumnParser) event(h header, line string) ig.Event {
+	fields := map[string]any{}
+	for i, v := range h.values(line) {
+		c := h.columns[i]
+		switch {
+		case c.Set != nil:
+			c.Set(fields, v)
+		case c.Numeric:
+			setField(fields, c.Path, number(v))
+		default:
+			setField(fields, c.Path, v)
+		}
+	}
+	return ig.Event{Gadget: p.Gadget, Raw: line, Fields: fields}
+}
+
+// Parse parses the columns output of a gadget. The first non-blank line is
+// the header; repeated headers are skipped.
+func (p ColumnParser) Parse(output string) ([]ig.Event, error) {
+	var h *header
+	var events []ig.Event
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \r")
+		switch {
+		case strings.TrimSpace(line) == "":
+		case h == nil:
+			parsed := p.parseHeader(line)
+			h = &parsed
+		case line == h.line:
+		default:
+			events = append(events, p.event(*h, line))
+		}
+	}
+	if h == nil && len(events) == 0 && strings.TrimSpace(output) != "" {
+		return nil, fmt.Errorf("parsing columns of %s: no header", p.Gadget)
+	}
+	return events, nil
+}
+
+// ParseStream parses columns lines, as streamed by ig.IG.RunStream with "-o
+// columns", into events. The channel is closed once lines is.
+func (p ColumnParser) ParseStream(lines <-chan string) <-chan ig.Event {
+	events := make(chan ig.Event, cap(lines))
+	go func() {
+		defer close(events)
+		var h *header
+		for line := range lines {
+			line = strings.TrimRight(line, " \r")
+			switch {
+			case strings.TrimSpace(line) == "":
+			case h == nil:
+				parsed := p.parseHeader(line)
+				h = &parsed
+			case line == h.line:
+			default:
+				events <- p.event(*h, line)
+			}
+		}
+	}()
+	return events
+}
+
+// DecodeColumns parses the columns output of a gadget with p and decodes the
+// events into Ts, e.g. the event types of this package.
+func DecodeColumns[T any](output string, p ColumnParser) ([]T, error) {
+	events, err := p.Parse(output)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]T, 0, len(events))
+	for i, ev := range events {
+		b, err := json.Marshal(ev.Fields)
+		if err != nil {
+			return decoded, err
+		}
+		var t T
+		if err := json.Unmarshal(b, &t); err != nil {
+			return decoded, fmt.Errorf("decoding event %d of %s: %w", i, p.Gadget, err)
+		}
+		decoded = append(decoded, t)
+	}
+	return decoded, nil
+}
+
+func setField(fields map[string]any, path string, v any) {
+	parts := strings.Split(path, ".")
+	m := fields
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := m[p].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[p] = child
+		}
+		m = child
+	}
+	m[parts[len(parts)-1]] = v
+}
+
+// number returns v as a float64, as decoded from JSON, if it is one.
+func number(v string) any {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// setEndpoint returns a Column setter splitting "addr:port" into the addr
+// and port fields of the endpoint at path.
+func setEndpoint(path string) func(map[string]any, string) {
+	return func(fields map[string]any, v string) {
+		addr, port, err := net.SplitHostPort(v)
+		if err != nil {
+			setField(fields, path+".addr", v)
+			return
+		}
+		setField(fields, path+".addr", addr)
+		setField(fields, path+".port", number(port))
+	}
+}
+
+// commonColumns are the columns of every gadget, in the built-in gadgets of
+// ig before image-based gadgets and in their columns output.
+var commonColumns = []Column{
+	{Header: "CONTAINER", Path: "runtime.containerName"},
+	{Header: "RUNTIME.CONTAINERNAME", Path: "runtime.containerName"},
+	{Header: "RUNTIME.CONTAINERID", Path: "runtime.containerId"},
+	{Header: "NODE", Path: "k8s.node"},
+	{Header: "K8S.NODE", Path: "k8s.node"},
+	{Header: "NAMESPACE", Path: "k8s.namespace"},
+	{Header: "K8S.NAMESPACE", Path: "k8s.namespace"},
+	{Header: "POD", Path: "k8s.podName"},
+	{Header: "K8S.POD", Path: "k8s.podName"},
+	{Header: "K8S.PODNAME", Path: "k8s.podName"},
+	{Header: "K8S.CONTAINER", Path: "k8s.containerName"},
+	{Header: "K8S.CONTAINERNAME", Path: "k8s.containerName"},
+	{Header: "TIMESTAMP", Set: func(fields map[string]any, v string) {
+		// Only full timestamps are kept; some versions print times of day.
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			setField(fields, "timestamp", v)
+		}
+	}},
+	{Header: "COMM", Path: "proc.comm"},
+	{Header: "PROC.COMM", Path: "proc.comm"},
+	{Header: "PID", Path: "proc.pid", Numeric: true},
+	{Header: "PROC.PID", Path: "proc.pid", Numeric: true},
+	{Header: "TID", Path: "proc.tid", Numeric: true},
+	{Header: "PROC.TID", Path: "proc.tid", Numeric: true},
+	{Header: "PPID", Path: "proc.parent.pid", Numeric: true},
+	{Header: "UID", Path: "proc.creds.uid", Numeric: true},
+	{Header: "GID", Path: "proc.creds.gid", Numeric: true},
+	{Header: "MNTNS", Path: "proc.mntns_id", Numeric: true},
+	{Header: "NETNS", Path: "netns_id", Numeric: true},
+}
+
+// tcpTypes are the event types of the T column of the legacy trace tcp.
+var tcpTypes = map[string]string{"C": "connect", "A": "accept", "X": "close"}
+
+// Column parsers of the legacy built-in gadgets of ig ("ig trace exec"),
+// also covering the columns output of their image-based successors.
+var (
+	LegacyTraceExec = ColumnParser{Gadget: "trace_exec", Columns: []Column{
+		{Header: "RET", Path: "error"},
+		{Header: "ERROR", Path: "error"},
+		{Header: "ARGS", Path: "args"},
+		{Header: "CWD", Path: "cwd"},
+		{Header: "LOGINUID", Path: "loginuid", Numeric: true},
+		{Header: "SESSIONID", Path: "sessionid", Numeric: true},
+	}}
+	LegacyTraceOpen = ColumnParser{Gadget: "trace_open", Columns: []Column{
+		{Header: "FD", Path: "fd", Numeric: true},
+		{Header: "ERR", Path: "error"},
+		{Header: "ERROR", Path: "error"},
+		{Header: "PATH", Path: "fname"},
+		{Header: "FNAME", Path: "fname"},
+		{Header: "FLAGS", Path: "flags"},
+		{Header: "MODE", Path: "mode"},
+	}}
+	LegacyTraceTCP = ColumnParser{Gadget: "trace_tcp", Columns: []Column{
+		{Header: "SRC", Set: setEndpoint("src")},
+		{Header: "DST", Set: setEndpoint("dst")},
+		{Header: "IP", Path: "src.version", Numeric: true},
+		{Header: "T", Set: func(fields map[string]any, v string) {
+			if t, ok := tcpTypes[v]; ok {
+				v = t
+			}
+			setField(fields, "type", v)
+		}},
+		{Header: "TYPE", Path: "type"},
+	}}
+	LegacyTraceDNS = ColumnParser{Gadget: "trace_dns", Columns: []Column{
+		{Header: "ID", Path: "id"},
+		{Header: "QR", Path: "qr"},
+		{Header: "TYPE", Path: "pkt_type"},
+		{Header: "QTYPE", Path: "qtype"},
+		{Header: "NAME", Path: "name"},
+		{Header: "RCODE", Path: "rcode"},
+		{Header: "NUMANSWERS", Path: "num_answers", Numeric: true},
+		{Header: "ADDRESSES", Path: "addresses"},
+		{Header: "SRC", Set: setEndpoint("src")},
+		{Header: "DST", Set: setEndpoint("dst")},
+	}}
+	// LegacySnapshotProcess maps process columns to the top-level fields of
+	// snapshot_process rather than proc.
+	LegacySnapshotProcess = ColumnParser{Gadget: "snapshot_process", Columns: []Column{
+		{Header: "COMM", Path: "comm"},
+		{Header: "PID", Path: "pid", Numeric: true},
+		{Header: "TID", Path: "tid", Numeric: true},
+		{Header: "PPID", Path: "ppid", Numeric: true},
+		{Header: "UID", Path: "uid", Numeric: true},
+		{Header: "GID", Path: "gid", Numeric: true},
+		{Header: "MNTNS", Path: "mntns_id", Numeric: true},
+	}}
+)
+
+// LegacyParsers are the column parsers of this package, by gadget name.
+var LegacyParsers = map[string]ColumnParser{
+	"trace_exec":       LegacyTraceExec,
+	"trace_open":       LegacyTraceOpen,
+	"trace_tcp":        LegacyTraceTCP,
+	"trace_tcpconnect": LegacyTraceTCP,
+	"trace_dns":        LegacyTraceDNS,
+	"snapshot_process": LegacySnapshotProcess,
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is the time of an event, printed by ig either in RFC 3339 or,
+// by older versions, as nanoseconds since the epoch.
+type Timestamp time.Time
+
+// Time returns the timestamp as a time.Time.
+func (t Timestamp) Time() time.Time {
+	return time.Time(t)
+}
+
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("parsing timestamp: %w", err)
+		}
+		*t = Timestamp(parsed)
+		return nil
+	}
+	ns, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parsing timestamp: %w", err)
+	}
+	*t = Timestamp(time.Unix(0, ns))
+	return nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).Format(time.RFC3339Nano))
+}
+
+// Process is the process an event comes from, the proc field of events.
+type Process struct {
+	Comm    string `json:"comm"`
+	PID     uint32 `json:"pid"`
+	TID     uint32 `json:"tid"`
+	MntNsID uint64 `json:"mntns_id"`
+	Creds   struct {
+		UID uint32 `json:"uid"`
+		GID uint32 `json:"gid"`
+	} `json:"creds"`
+	Parent struct {
+		Comm string `json:"comm"`
+		PID  uint32 `json:"pid"`
+	} `json:"parent"`
+}
+
+// K8s is the Kubernetes metadata of an event, the k8s field of events traced
+// in a cluster.
+type K8s struct {
+	Node          string `json:"node"`
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"podName"`
+	ContainerName string `json:"containerName"`
+	HostNetwork   bool   `json:"hostnetwork"`
+	PodLabels     Labels `json:"podLabels"`
+	Owner         struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"owner"`
+}
+
+// Runtime is the container metadata of an event, the runtime field of
+// events, empty for processes outside containers.
+type Runtime struct {
+	RuntimeName          string `json:"runtimeName"`
+	ContainerID          string `json:"containerId"`
+	ContainerName        string `json:"containerName"`
+	ContainerPID         uint32 `json:"containerPid"`
+	ContainerImageName   string `json:"containerImageName"`
+	ContainerImageDigest string `json:"containerImageDigest"`
+	ContainerStartedAt   string `json:"containerStartedAt"`
+}
+
+// Common are the fields of the events of every tracing gadget.
+type Common struct {
+	Timestamp Timestamp `json:"timestamp"`
+	Proc      Process   `json:"proc"`
+	K8s       K8s       `json:"k8s"`
+	Runtime   Runtime   `json:"runtime"`
+}
+
+// Endpoint is a network endpoint, the src and dst fields of network events.
+type Endpoint struct {
+	Addr    string `json:"addr"`
+	Port    uint16 `json:"port"`
+	Proto   string `json:"proto"`
+	Version uint8  `json:"version"`
+	// K8s describes the endpoint in a cluster, e.g. the pod or service at
+	// Addr.
+	K8s struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		Labels    Labels `json:"labels"`
+	} `json:"k8s"`
+}
+
+// String returns the endpoint as "addr:port", with IPv6 addresses in
+// brackets.
+func (e Endpoint) String() string {
+	return net.JoinHostPort(e.Addr, strconv.Itoa(int(e.Port)))
+}
+
+// Text is a string field that ig versions print as a string or as a number,
+// such as errors, kept as printed.
+type Text string
+
+func (t *Text) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*t = Text(s)
+		return nil
+	}
+	if bytes.Equal(b, []byte("null")) {
+		*t = ""
+		return nil
+	}
+	if len(b) > 0 && (b[0] == '{' || b[0] == '[') {
+		return fmt.Errorf("decoding text field: unexpected %s", b)
+	}
+	*t = Text(b)
+	return nil
+}
+
+// List is a list field that ig versions print as an array or as a
+// comma-separated string.
+type List []string
+
+func (l *List) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		return json.Unmarshal(b, (*[]string)(l))
+	}
+	var s Text
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*l = nil
+	if s != "" {
+		*l = strings.Split(string(s), ",")
+	}
+	return nil
+}
+
+// Labels are Kubernetes labels, which ig versions print as an object or as
+// a "key=value,key2=value2" string.
+type Labels map[string]string
+
+func (l *Labels) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		return json.Unmarshal(b, (*map[string]string)(l))
+	}
+	var s Text
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*l = nil
+	for _, kv := range strings.Split(string(s), ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			if *l == nil {
+				*l = Labels{}
+			}
+			(*l)[k] = v
+		}
+	}
+	return nil
+}
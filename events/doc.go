@@ -0,0 +1,16 @@
+// Package events decodes the JSON events of gadgets into Go types, checked
+// at compile time instead of walking the map[string]any of ig.Event:
+//
+//	execs, s, err := events.Stream[events.TraceExec](ctx, i, events.TraceExecImage, ig.RunFlags{})
+//	for e := range execs {
+//		fmt.Println(e.Proc.Comm, e.Args)
+//	}
+//
+// It has types for the events of common gadgets, such as TraceExec and
+// TraceDNS. Their fields follow the output of ig and tolerate its variations
+// across versions, e.g. timestamps printed as strings or numbers. Any
+// struct with json tags works for other gadgets, or to decode fewer fields.
+//
+// For ig versions printing columns only, ColumnParser turns columns output
+// into the same events, see LegacyParsers and DecodeColumns.
+package events
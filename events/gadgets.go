@@ -0,0 +1,95 @@
+package events
+
+import "github.com/pawarpranav83/ig-testing-framework/ig"
+
+// Images of the gadgets with event types in this package.
+const (
+	TraceExecImage       = ig.DefaultRegistry + "/trace_exec"
+	TraceOpenImage       = ig.DefaultRegistry + "/trace_open"
+	TraceTCPImage        = ig.DefaultRegistry + "/trace_tcp"
+	TraceDNSImage        = ig.DefaultRegistry + "/trace_dns"
+	SnapshotProcessImage = ig.DefaultRegistry + "/snapshot_process"
+)
+
+// TraceExec is an event of trace_exec: a process called exec.
+type TraceExec struct {
+	Common
+	// Args are the arguments of the new program, space-separated.
+	Args string `json:"args"`
+	// Error is the error of exec, empty or "0" on success depending on the
+	// ig version.
+	Error   Text   `json:"error"`
+	Cwd     string `json:"cwd"`
+	ExePath string `json:"exepath"`
+	// UpperLayer reports whether the executable is in the upper layer of an
+	// overlay file system, i.e. wasn't part of the container image;
+	// PUpperLayer reports it for the executable of the parent.
+	UpperLayer  bool   `json:"upper_layer"`
+	PUpperLayer bool   `json:"pupper_layer"`
+	LoginUID    uint32 `json:"loginuid"`
+	SessionID   uint32 `json:"sessionid"`
+}
+
+// TraceOpen is an event of trace_open: a process opened a file.
+type TraceOpen struct {
+	Common
+	FD    uint32 `json:"fd"`
+	FName string `json:"fname"`
+	// Flags and Mode are the flags and mode of open, rendered by ig, e.g.
+	// "O_RDONLY|O_CLOEXEC" and "----------"; FlagsRaw and ModeRaw are their
+	// values.
+	Flags    Text   `json:"flags"`
+	FlagsRaw int32  `json:"flags_raw"`
+	Mode     Text   `json:"mode"`
+	ModeRaw  uint16 `json:"mode_raw"`
+	Error    Text   `json:"error"`
+}
+
+// TraceTCP is an event of trace_tcp, which replaced trace_tcpconnect: a TCP
+// connection was opened, accepted or closed.
+type TraceTCP struct {
+	Common
+	// Type is "connect", "accept" or "close".
+	Type    string   `json:"type"`
+	Src     Endpoint `json:"src"`
+	Dst     Endpoint `json:"dst"`
+	NetNsID uint64   `json:"netns_id"`
+	Error   Text     `json:"error"`
+}
+
+// TraceDNS is an event of trace_dns: a DNS query or response went through a
+// traced network namespace.
+type TraceDNS struct {
+	Common
+	Src     Endpoint `json:"src"`
+	Dst     Endpoint `json:"dst"`
+	NetNsID uint64   `json:"netns_id"`
+	// ID is the ID of the DNS message, shared by a query and its response.
+	ID Text `json:"id"`
+	// QR is "Q" for queries and "R" for responses.
+	QR    string `json:"qr"`
+	QType string `json:"qtype"`
+	Name  string `json:"name"`
+	// RCode and Addresses are only set on responses: the response code,
+	// e.g. "Success" or "NameError", and the resolved addresses.
+	RCode     string `json:"rcode"`
+	Addresses List   `json:"addresses"`
+	// LatencyNS is the time between the query and the response, in
+	// nanoseconds, on responses.
+	LatencyNS uint64 `json:"latency_ns"`
+	PktType   string `json:"pkt_type"`
+}
+
+// SnapshotProcess is an entry of snapshot_process: a process running when
+// the snapshot was taken.
+type SnapshotProcess struct {
+	K8s     K8s     `json:"k8s"`
+	Runtime Runtime `json:"runtime"`
+	Comm    string  `json:"comm"`
+	PID     uint32  `json:"pid"`
+	TID     uint32  `json:"tid"`
+	PPID    uint32  `json:"ppid"`
+	UID     uint32  `json:"uid"`
+	GID     uint32  `json:"gid"`
+	MntNsID uint64  `json:"mntns_id"`
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/internal/stats"
+)
+
+// Stream starts a gadget as ig.IG.StartWith does and decodes each of its
+// events into a T with encoding/json. The arrays printed by snapshot
+// gadgets are flattened. Lines that aren't JSON are skipped, and events that
+// don't decode into a T are dropped, counted in ig.Stats.EventsDropped.
+//
+// The channel is closed once the gadget exited; consumers must drain it, as
+// GadgetSession.Events. The session stops the gadget and reports its error;
+// its Events must not be read.
+func Stream[T any](ctx context.Context, i *ig.IG, image string, f ig.RunFlags) (<-chan T, *ig.GadgetSession, error) {
+	s, err := i.StartWith(ctx, image, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make(chan T, 1024)
+	go func() {
+		defer close(events)
+		for ev := range s.Events() {
+			raw := bytes.TrimSpace([]byte(ev.Raw))
+			if len(raw) == 0 || (raw[0] != '{' && raw[0] != '[') {
+				continue
+			}
+			if raw[0] == '[' {
+				var batch []T
+				if json.Unmarshal(raw, &batch) != nil {
+					stats.EventsDropped.Add(1)
+					continue
+				}
+				for _, e := range batch {
+					events <- e
+				}
+				continue
+			}
+			var e T
+			if json.Unmarshal(raw, &e) != nil {
+				stats.EventsDropped.Add(1)
+				continue
+			}
+			events <- e
+		}
+	}()
+	return events, s, nil
+}
+
+// Run runs a gadget as ig.IG.RunWith does, with JSON output, and returns
+// its events decoded into Ts. The events printed are returned along with any
+// error of the run.
+func Run[T any](ctx context.Context, i *ig.IG, image string, f ig.RunFlags) ([]T, error) {
+	args, err := f.Args()
+	if err != nil {
+		return nil, err
+	}
+	var events []T
+	err = i.RunJSONInto(ctx, image, &events, args...)
+	return events, err
+}
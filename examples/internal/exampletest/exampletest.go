@@ -0,0 +1,28 @@
+// Package exampletest gates the examples, which run real gadgets, on the
+// hosts able to run them.
+package exampletest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Main runs the tests and examples of m, if the host can run gadgets: ig is
+// found and the process runs as root. Otherwise it reports why and exits
+// successfully, so go test ./... passes on developer machines. Use it as:
+//
+//	func TestMain(m *testing.M) { exampletest.Main(m) }
+func Main(m *testing.M) {
+	if os.Geteuid() != 0 {
+		fmt.Println("skipping examples: require root")
+		os.Exit(0)
+	}
+	if _, err := ig.New(); err != nil {
+		fmt.Printf("skipping examples: %s\n", err)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/examples/internal/exampletest"
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+func TestMain(m *testing.M) { exampletest.Main(m) }
+
+// Example profiles a busybox container listing directories in a loop.
+func Example() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	c, err := testutils.StartTestContainer(ctx, testutils.ContainerOptions{
+		Command: "while true; do ls / >/dev/null; sleep 0.2; done",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer testutils.RemoveTestContainer(context.Background(), c)
+
+	i, err := ig.New()
+	if err != nil {
+		panic(err)
+	}
+	p, err := record(ctx, i, c.Name, 5*time.Second)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("default action:", p.DefaultAction)
+	fmt.Println("allows execve:", p.allows("execve"))
+	// Output:
+	// default action: SCMP_ACT_ERRNO
+	// allows execve: true
+}
@@ -0,0 +1,137 @@
+// Command seccomp-profile records the system calls of a container with
+// advise_seccomp and prints a seccomp profile allowing only them, in the
+// format of docker run --security-opt seccomp=<file>:
+//
+//	seccomp-profile -containername web -duration 1m > web.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+const image = "ghcr.io/inspektor-gadget/gadget/advise_seccomp"
+
+func main() {
+	container := flag.String("containername", "", "container to profile (required)")
+	duration := flag.Duration("duration", 30*time.Second, "how long to record system calls")
+	flag.Parse()
+	if *container == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	i, err := ig.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := record(ctx, i, *container, *duration)
+	if err != nil {
+		log.Fatal(err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// record runs advise_seccomp on container until duration elapses or ctx is
+// done, and returns the profile of the system calls it reported.
+func record(ctx context.Context, i *ig.IG, container string, duration time.Duration) (*profile, error) {
+	s, err := i.StartWith(ctx, image, ig.RunFlags{ContainerName: container})
+	if err != nil {
+		return nil, err
+	}
+	p := newProfile()
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		for ev := range s.Events() {
+			p.allow(syscalls(ev)...)
+		}
+	}()
+
+	select {
+	case <-time.After(duration):
+	case <-ctx.Done():
+	case <-s.Done():
+	}
+	// advise_seccomp reports the system calls it saw when it is stopped.
+	if err := s.Stop(); err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, s.Stderr())
+	}
+	<-consumed
+	return p, nil
+}
+
+// syscalls returns the system call names of an event of advise_seccomp,
+// given as a list or a comma-separated string in its syscalls field, or as
+// a single name in its syscall field.
+func syscalls(ev ig.Event) []string {
+	var names []string
+	if v, ok := ev.Field("syscalls"); ok {
+		switch v := v.(type) {
+		case []any:
+			for _, n := range v {
+				names = append(names, fmt.Sprint(n))
+			}
+		case string:
+			names = append(names, strings.Split(v, ",")...)
+		}
+	}
+	if v, ok := ev.Field("syscall"); ok {
+		names = append(names, fmt.Sprint(v))
+	}
+	return names
+}
+
+// profile is a seccomp profile, as read by container runtimes.
+type profile struct {
+	DefaultAction string         `json:"defaultAction"`
+	Syscalls      []syscallRules `json:"syscalls"`
+
+	allowed map[string]bool
+}
+
+type syscallRules struct {
+	Names  []string `json:"names"`
+	Action string   `json:"action"`
+}
+
+func newProfile() *profile {
+	return &profile{DefaultAction: "SCMP_ACT_ERRNO", allowed: map[string]bool{}}
+}
+
+func (p *profile) allow(names ...string) {
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n == "" || p.allowed[n] {
+			continue
+		}
+		p.allowed[n] = true
+	}
+	rules := syscallRules{Names: make([]string, 0, len(p.allowed)), Action: "SCMP_ACT_ALLOW"}
+	for n := range p.allowed {
+		rules.Names = append(rules.Names, n)
+	}
+	sort.Strings(rules.Names)
+	p.Syscalls = []syscallRules{rules}
+}
+
+// allows reports whether the profile allows the system call name.
+func (p *profile) allows(name string) bool {
+	return p.allowed[name]
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/examples/internal/exampletest"
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+func TestMain(m *testing.M) { exampletest.Main(m) }
+
+// Example connects three times to a local echo server while trace_tcp runs,
+// and reads the counter of the server address.
+func Example() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	echo, err := testutils.StartTCPEchoServer("127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer echo.Close()
+
+	i, err := ig.New()
+	if err != nil {
+		panic(err)
+	}
+	s, err := i.StartWith(ctx, image, ig.RunFlags{
+		Host:   true,
+		Filter: []string{"dst.port==" + strconv.Itoa(echo.Port())},
+	})
+	if err != nil {
+		panic(err)
+	}
+	c := newConnects()
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		c.consume(s.Events())
+	}()
+	if err := s.WaitReady(ctx); err != nil {
+		panic(err)
+	}
+
+	for n := 0; n < 3; n++ {
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(echo.Port())))
+		if err != nil {
+			panic(err)
+		}
+		conn.Close()
+	}
+	// Stop once the gadget reported the workload and went quiet.
+	if err := s.WaitIdle(ctx, time.Second); err != nil {
+		panic(err)
+	}
+	if err := s.Stop(); err != nil {
+		panic(err)
+	}
+	<-consumed
+
+	fmt.Println("connections to the echo server:", c.total("127.0.0.1"))
+	// Output: connections to the echo server: 3
+}
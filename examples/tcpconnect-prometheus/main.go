@@ -0,0 +1,145 @@
+// Command tcpconnect-prometheus traces TCP connections with trace_tcp and
+// exposes their count as a Prometheus counter:
+//
+//	tcpconnect-prometheus -addr :9100 -containername web
+//	curl localhost:9100/metrics
+//	ig_tcp_connects_total{comm="curl",daddr="10.0.0.7",dport="80"} 12
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+const image = "ghcr.io/inspektor-gadget/gadget/trace_tcp"
+
+func main() {
+	addr := flag.String("addr", ":9100", "address to serve /metrics on")
+	container := flag.String("containername", "", "trace this container only")
+	host := flag.Bool("host", false, "trace the whole host")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	i, err := ig.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := i.StartWith(ctx, image, ig.RunFlags{ContainerName: *container, Host: *host})
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := newConnects()
+	go c.consume(s.Events())
+
+	srv := &http.Server{Addr: *addr, Handler: c}
+	go func() {
+		<-s.Done()
+		srv.Close()
+	}()
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	if err := s.Wait(); err != nil {
+		log.Fatalf("%s\n%s", err, s.Stderr())
+	}
+}
+
+// connKey are the labels of a counter.
+type connKey struct {
+	comm, daddr, dport string
+}
+
+// connects counts the connect events of trace_tcp.
+type connects struct {
+	mu     sync.Mutex
+	counts map[connKey]uint64
+}
+
+func newConnects() *connects {
+	return &connects{counts: map[connKey]uint64{}}
+}
+
+// consume counts the events of events until it is closed.
+func (c *connects) consume(events <-chan ig.Event) {
+	for ev := range events {
+		c.observe(ev)
+	}
+}
+
+func (c *connects) observe(ev ig.Event) {
+	if typ, _ := ev.Field("type"); typ != "connect" {
+		return
+	}
+	k := connKey{field(ev, "proc.comm"), field(ev, "dst.addr"), field(ev, "dst.port")}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[k]++
+}
+
+// total returns the number of connections to daddr.
+func (c *connects) total(daddr string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n uint64
+	for k, v := range c.counts {
+		if k.daddr == daddr {
+			n += v
+		}
+	}
+	return n
+}
+
+// ServeHTTP serves the counters in the Prometheus text format.
+func (c *connects) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.writeTo(w)
+}
+
+func (c *connects) writeTo(w io.Writer) {
+	c.mu.Lock()
+	lines := make([]string, 0, len(c.counts))
+	for k, v := range c.counts {
+		lines = append(lines, fmt.Sprintf("ig_tcp_connects_total{comm=%s,daddr=%s,dport=%s} %d",
+			label(k.comm), label(k.daddr), label(k.dport), v))
+	}
+	c.mu.Unlock()
+	sort.Strings(lines)
+
+	fmt.Fprintln(w, "# HELP ig_tcp_connects_total TCP connections traced by trace_tcp.")
+	fmt.Fprintln(w, "# TYPE ig_tcp_connects_total counter")
+	for _, l := range lines {
+		fmt.Fprintln(w, l)
+	}
+}
+
+func field(ev ig.Event, path string) string {
+	v, ok := ev.Field(path)
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// label quotes a label value as the Prometheus text format requires.
+func label(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/examples/internal/exampletest"
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+func TestMain(m *testing.M) { exampletest.Main(m) }
+
+// Example watches a container resolving a name unique to the run, as it
+// would watch a pod.
+func Example() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	c, err := testutils.StartTestContainer(ctx, testutils.ContainerOptions{})
+	if err != nil {
+		panic(err)
+	}
+	defer testutils.RemoveTestContainer(context.Background(), c)
+
+	i, err := ig.New()
+	if err != nil {
+		panic(err)
+	}
+	s, err := i.StartWith(ctx, image, runFlags("", "", c.Name))
+	if err != nil {
+		panic(err)
+	}
+	var lines []string
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		for ev := range s.Events() {
+			if line, ok := format(ev); ok {
+				lines = append(lines, line)
+			}
+		}
+	}()
+	if err := s.WaitReady(ctx); err != nil {
+		panic(err)
+	}
+
+	n := testutils.NewNonce()
+	if out, err := testutils.DNSQueries(n, "", "", 2).RunIn(ctx, c); err != nil {
+		panic(fmt.Sprintf("%s\n%s", err, out))
+	}
+	// Stop once the gadget reported the workload and went quiet.
+	if err := s.WaitIdle(ctx, time.Second); err != nil {
+		panic(err)
+	}
+	if err := s.Stop(); err != nil {
+		panic(err)
+	}
+	<-consumed
+
+	queried := false
+	for _, l := range lines {
+		queried = queried || strings.Contains(l, n.Domain(testutils.DefaultTestZone))
+	}
+	fmt.Println("saw the query:", queried)
+	// Output: saw the query: true
+}
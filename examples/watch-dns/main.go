@@ -0,0 +1,86 @@
+// Command watch-dns prints the DNS queries and responses of a Kubernetes pod,
+// or of a container, as trace_dns sees them, one per line:
+//
+//	watch-dns -namespace shop -pod cart-7d4f9
+//	cart   Q A    redis.shop.svc.cluster.local.
+//	cart   R A    redis.shop.svc.cluster.local. Success
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+const image = "ghcr.io/inspektor-gadget/gadget/trace_dns"
+
+func main() {
+	pod := flag.String("pod", "", "Kubernetes pod to watch")
+	namespace := flag.String("namespace", "", "namespace of the pod")
+	container := flag.String("containername", "", "container to watch, instead of a pod")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	i, err := ig.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := i.StartWith(ctx, image, runFlags(*namespace, *pod, *container))
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+	for ev := range s.Events() {
+		if line, ok := format(ev); ok {
+			fmt.Println(line)
+		}
+	}
+	if err := s.Wait(); err != nil {
+		log.Fatalf("%s\n%s", err, s.Stderr())
+	}
+}
+
+// runFlags scopes trace_dns to a pod, with filters on its Kubernetes
+// metadata, or to a container.
+func runFlags(namespace, pod, container string) ig.RunFlags {
+	f := ig.RunFlags{ContainerName: container}
+	if pod != "" {
+		f.Filter = append(f.Filter, "k8s.podName=="+pod)
+	}
+	if namespace != "" {
+		f.Filter = append(f.Filter, "k8s.namespace=="+namespace)
+	}
+	return f
+}
+
+// format renders a DNS event as a line, false for other events.
+func format(ev ig.Event) (string, bool) {
+	name := field(ev, "name")
+	if name == "" {
+		return "", false
+	}
+	line := fmt.Sprintf("%-6s %s %-4s %s", field(ev, "proc.comm"), field(ev, "qr"), field(ev, "qtype"), name)
+	if field(ev, "qr") == "R" {
+		line += " " + field(ev, "rcode")
+	}
+	return strings.TrimSpace(line), true
+}
+
+func field(ev ig.Event, path string) string {
+	v, ok := ev.Field(path)
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,64 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// maxLeakedEvents bounds how many leaked events AssertNoEventsFrom prints.
+const maxLeakedEvents = 10
+
+// AssertNoEventsFrom watches events for window, or until the channel is
+// closed, and fails the test if any of them matches outOfScope. It checks
+// that a gadget run with filters doesn't leak events from containers or
+// hosts it should ignore:
+//
+//	s, _ := i.Start(ctx, "trace_exec", "--containername", "web")
+//	// generate activity in "web" and in another container
+//	harness.AssertNoEventsFrom(t, s.Events(), stream.Not(stream.InContainer("web")), 10*time.Second)
+//
+// Run activity outside the scope during the window, or the assertion
+// proves nothing. Events received after window are left in the channel.
+func AssertNoEventsFrom(t *testing.T, events <-chan ig.Event, outOfScope func(ig.Event) bool, window time.Duration) {
+	t.Helper()
+
+	leaked, seen := collectLeaks(events, outOfScope, window)
+	if len(leaked) == 0 {
+		t.Logf("no out-of-scope events among %d in %s", seen, window)
+		return
+	}
+
+	lines := make([]string, 0, maxLeakedEvents)
+	for i, ev := range leaked {
+		if i == maxLeakedEvents {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(leaked)-maxLeakedEvents))
+			break
+		}
+		lines = append(lines, ev.Raw)
+	}
+	t.Fatalf("%d of %d events in %s were out of scope:\n  %s", len(leaked), seen, window, strings.Join(lines, "\n  "))
+}
+
+func collectLeaks(events <-chan ig.Event, outOfScope func(ig.Event) bool, window time.Duration) (leaked []ig.Event, seen int) {
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return leaked, seen
+			}
+			seen++
+			if outOfScope(ev) {
+				leaked = append(leaked, ev)
+			}
+		case <-timer.C:
+			return leaked, seen
+		}
+	}
+}
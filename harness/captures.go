@@ -0,0 +1,25 @@
+package harness
+
+import "regexp"
+
+// Captures maps the names of a regexp's capture groups to the text they
+// matched. Unnamed groups are left out.
+type Captures map[string]string
+
+// ExtractCaptures returns the named groups of every non-overlapping match of
+// re in output, in order.
+func ExtractCaptures(re *regexp.Regexp, output string) []Captures {
+	names := re.SubexpNames()
+
+	var out []Captures
+	for _, m := range re.FindAllStringSubmatch(output, -1) {
+		c := Captures{}
+		for i, name := range names {
+			if name != "" {
+				c[name] = m[i]
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,493 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/internal/capture"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+// Command is a command run as one step of a test.
+type Command struct {
+	// Name of the command, used in logs and failure messages.
+	Name string
+	// Cmd is the shell script to run, with Shell -c.
+	Cmd string
+	// Args, if set, is run directly instead of Cmd, without any shell:
+	// Args[0] is looked up in PATH and the rest are passed verbatim. This
+	// avoids quoting pitfalls and works without a shell in the environment.
+	Args []string
+	// Shell runs Cmd. Defaults to DefaultShell.
+	Shell string
+	// ExpectedString is the exact expected output of the command.
+	ExpectedString string
+	// ExpectedRegexp is a regexp the command output must match.
+	ExpectedRegexp string
+	// ExpectedRegexps are regexps matched against the output according to
+	// RegexpMode, for asserting several independent lines without cramming
+	// them into a single ExpectedRegexp.
+	ExpectedRegexps []string
+	// RegexpMode controls how ExpectedRegexps are matched. Defaults to
+	// MatchAll.
+	RegexpMode RegexpMode
+	// ExpectedEvents are matched against the JSON events decoded from the
+	// command output. Each expectation must be met by at least one event.
+	ExpectedEvents []*Expectation
+	// ExpectedEntries are complete entries (maps or structs with json tags)
+	// each of which must equal one of the JSON events decoded from the
+	// output, after NormalizeEntry. Failures show a field-level diff against
+	// the closest event.
+	ExpectedEntries []any
+	// NormalizeEntry, if set, is applied to every decoded event before
+	// comparing it with ExpectedEntries, to drop or fix volatile fields.
+	NormalizeEntry func(e map[string]any)
+	// ValidateOutput verifies the output. It must make the test fail on
+	// error.
+	ValidateOutput func(t *testing.T, output string)
+	// ValidateCaptures receives the named groups of every match of
+	// ExpectedRegexp, once the output matched it, so tests can assert
+	// relationships between captured values (e.g. the same pid on several
+	// lines). It must make the test fail on error.
+	ValidateCaptures func(t *testing.T, captures []Captures)
+	// StartAndStop indicates the command is started in the background by
+	// RunCommands and stopped once every other command ran, as needed for
+	// tracing gadgets that run until interrupted.
+	StartAndStop bool
+	// Timeout bounds how long the command may run. Regardless of Timeout,
+	// commands are stopped DeadlineMargin before the deadline of the test
+	// binary (go test -timeout).
+	Timeout time.Duration
+	// Retries is how many times a command run to completion is rerun if it
+	// fails or its output doesn't match the Expected* fields, for steps known
+	// to be flaky. ValidateOutput and ValidateCaptures only see the output of
+	// the attempt that is kept. See RetryFlaky.
+	Retries int
+	// MaxOutput caps how many bytes of stdout and of stderr are kept, each;
+	// the rest is counted and replaced by a truncation marker. Zero means
+	// DefaultMaxOutput, a negative value no limit.
+	MaxOutput int
+	// PreserveOutput verifies and logs the output as printed. By default, it
+	// is sanitized with ig.SanitizeAll first, so binary data and escape
+	// sequences from traced processes don't break decoding or garble logs.
+	PreserveOutput bool
+
+	// started reports whether the command was started by Start.
+	started bool
+	// command is the process of a started command.
+	command *exec.Cmd
+	stdout  capture.Buffer
+	stderr  capture.Buffer
+	// done is closed once the process was reaped, with its result in
+	// waitErr.
+	done      chan struct{}
+	waitErr   error
+	timer     *time.Timer
+	timedOut  atomic.Bool
+	startTime time.Time
+}
+
+// DefaultShell runs the Cmd of commands that don't set Shell.
+var DefaultShell = "/bin/sh"
+
+// DefaultMaxOutput is the output cap of commands that don't set MaxOutput,
+// so a runaway gadget can't exhaust the memory of the test binary.
+var DefaultMaxOutput = 64 << 20
+
+// DeadlineMargin is subtracted from the deadline of the test binary to get the
+// deadline of commands, leaving time to log their output and clean up
+// instead of the whole binary being killed by go test -timeout.
+var DeadlineMargin = 10 * time.Second
+
+// TerminateGrace is how long a command interrupted at its deadline gets to
+// exit before it is killed.
+var TerminateGrace = 5 * time.Second
+
+func (c *Command) createExecCmd() {
+	limit := c.MaxOutput
+	if limit == 0 {
+		limit = DefaultMaxOutput
+	}
+	c.stdout.Limit = limit
+	c.stderr.Limit = limit
+	c.stdout.Reset()
+	c.stderr.Reset()
+
+	var cmd *exec.Cmd
+	if len(c.Args) > 0 {
+		cmd = exec.Command(c.Args[0], c.Args[1:]...)
+	} else {
+		shell := c.Shell
+		if shell == "" {
+			shell = DefaultShell
+		}
+		cmd = exec.Command(shell, "-c", c.Cmd)
+	}
+	cmd.Stdout = &c.stdout
+	cmd.Stderr = &c.stderr
+	setProcessGroup(cmd)
+
+	c.command = cmd
+}
+
+// commandLine returns what the command runs, for logs.
+func (c *Command) commandLine() string {
+	if len(c.Args) == 0 {
+		return c.Cmd
+	}
+
+	quoted := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$`|&;<>()*?[]{}~#") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
+}
+
+// startProcess starts the command and reaps it in the background. done is
+// closed once it exited and its output was fully copied.
+func (c *Command) startProcess() error {
+	c.createExecCmd()
+	c.timedOut.Store(false)
+	c.startTime = time.Now()
+
+	if err := c.command.Start(); err != nil {
+		return err
+	}
+
+	c.done = make(chan struct{})
+	go func() {
+		c.waitErr = c.command.Wait()
+		close(c.done)
+	}()
+
+	return nil
+}
+
+// signal sends sig to the command's process tree. The tree outlives the
+// process if it spawned background children, so this is safe to call after
+// it exited.
+func (c *Command) signal(sig os.Signal) error {
+	if err := signalProcessGroup(c.command.Process, sig); err != nil {
+		return fmt.Errorf("sending %s to process group of %q: %w", sig, c.Name, err)
+	}
+	return nil
+}
+
+// kill kills the command's process tree and waits for the process to be
+// reaped.
+func (c *Command) kill() error {
+	err := c.signal(os.Kill)
+	<-c.done
+	return err
+}
+
+// terminate interrupts the command's process group, as Ctrl-C would, so
+// gadgets get a chance to flush their output, and kills it if it is still
+// running after TerminateGrace. Where interrupting isn't supported, it kills
+// it right away.
+func (c *Command) terminate() error {
+	if err := c.signal(os.Interrupt); err != nil {
+		if errors.Is(err, errInterruptUnsupported) {
+			return c.kill()
+		}
+		return err
+	}
+
+	select {
+	case <-c.done:
+		// Make sure no child survived the process.
+		return c.signal(os.Kill)
+	case <-time.After(TerminateGrace):
+		return c.kill()
+	}
+}
+
+// deadline returns when the command must be stopped: Timeout after now or
+// DeadlineMargin before the test binary deadline, whichever comes first.
+func (c *Command) deadline(t *testing.T) (time.Time, bool) {
+	d, ok := t.Deadline()
+	if ok {
+		d = d.Add(-DeadlineMargin)
+	}
+	if c.Timeout > 0 {
+		if td := time.Now().Add(c.Timeout); !ok || td.Before(d) {
+			d, ok = td, true
+		}
+	}
+	return d, ok
+}
+
+// output returns the stdout of the command, sanitized unless
+// PreserveOutput is set.
+func (c *Command) output() string {
+	if c.PreserveOutput {
+		return c.stdout.String()
+	}
+	return ig.Sanitize(c.stdout.String(), ig.SanitizeAll)
+}
+
+// errOutput is output for stderr.
+func (c *Command) errOutput() string {
+	if c.PreserveOutput {
+		return c.stderr.String()
+	}
+	return ig.Sanitize(c.stderr.String(), ig.SanitizeAll)
+}
+
+func (c *Command) verifyOutput() error {
+	output := c.output()
+
+	if c.ExpectedRegexp != "" {
+		r, err := regexp.Compile(c.ExpectedRegexp)
+		if err != nil {
+			return fmt.Errorf("compiling expected regexp of %q: %w", c.Name, err)
+		}
+		if !r.MatchString(output) {
+			return fmt.Errorf("output of %q didn't match the expected regexp: %s\n%s", c.Name, c.ExpectedRegexp, output)
+		}
+	}
+
+	if len(c.ExpectedRegexps) > 0 {
+		if err := matchRegexps(output, c.ExpectedRegexps, c.RegexpMode); err != nil {
+			return fmt.Errorf("checking output of %q: %w", c.Name, err)
+		}
+	}
+
+	if c.ExpectedString != "" && output != c.ExpectedString {
+		return fmt.Errorf("output of %q didn't match the expected string:\nexpected:\n%s\ngot:\n%s", c.Name, c.ExpectedString, output)
+	}
+
+	if len(c.ExpectedEvents) > 0 {
+		events, err := DecodeEvents(output)
+		if err != nil {
+			return fmt.Errorf("decoding events of %q: %w", c.Name, err)
+		}
+		for _, e := range c.ExpectedEvents {
+			if err := e.MatchAny(events); err != nil {
+				return fmt.Errorf("output of %q: %w", c.Name, err)
+			}
+		}
+	}
+
+	if len(c.ExpectedEntries) > 0 {
+		if err := matchOutputEntries(output, c.NormalizeEntry, c.ExpectedEntries...); err != nil {
+			return fmt.Errorf("output of %q: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// runValidators calls the user-provided validation hooks. They are only run
+// on output that already passed verifyOutput.
+func (c *Command) runValidators(t *testing.T) {
+	if c.ValidateOutput != nil {
+		c.ValidateOutput(t, c.output())
+	}
+	if c.ValidateCaptures != nil {
+		if c.ExpectedRegexp == "" {
+			t.Fatalf("command(%s) sets ValidateCaptures without ExpectedRegexp", c.Name)
+		}
+		// verifyOutput already checked the regexp compiles.
+		c.ValidateCaptures(t, ExtractCaptures(regexp.MustCompile(c.ExpectedRegexp), c.output()))
+	}
+}
+
+// runOnce runs the command to completion and verifies its output against
+// the Expected* fields.
+func (c *Command) runOnce(t *testing.T) error {
+	t.Logf("Run command(%s):\n%s\n", c.Name, c.commandLine())
+	if err := c.startProcess(); err != nil {
+		return fmt.Errorf("failed to run command(%s): %w", c.Name, err)
+	}
+
+	if d, ok := c.deadline(t); ok {
+		timer := time.NewTimer(time.Until(d))
+		select {
+		case <-c.done:
+		case <-timer.C:
+			c.timedOut.Store(true)
+			c.terminate()
+		}
+		timer.Stop()
+	}
+	<-c.done
+
+	t.Logf("Command returned(%s):\n%s\n%s\n", c.Name, c.errOutput(), c.output())
+	if c.timedOut.Load() {
+		return fmt.Errorf("command(%s) was terminated at its deadline", c.Name)
+	}
+	if c.waitErr != nil {
+		return fmt.Errorf("failed to run command(%s): %w", c.Name, c.waitErr)
+	}
+
+	return c.verifyOutput()
+}
+
+// Run runs the command to completion and verifies its output. A command
+// still running at its deadline is terminated and fails the test, with its
+// output logged. Commands with Retries set are rerun on failure.
+func (c *Command) Run(t *testing.T) {
+	start := time.Now()
+	attempts := 1 + c.Retries
+
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		err = c.runOnce(t)
+		if err == nil || attempt == attempts {
+			break
+		}
+		t.Logf("Attempt %d of %d of command(%s) failed, retrying: %s\n", attempt, attempts, c.Name, err)
+	}
+
+	Report.record(StepResult{
+		Test:     t.Name(),
+		Command:  c.Name,
+		Attempts: attempt,
+		Flaky:    err == nil && attempt > 1,
+		Failed:   err != nil,
+		Duration: time.Since(start),
+	})
+	if err != nil {
+		t.Fatalf("%s\n(%s)", err, testutils.SeedHint())
+	}
+	if attempt > 1 {
+		t.Logf("Command(%s) is flaky: passed on attempt %d of %d\n", c.Name, attempt, attempts)
+	}
+
+	c.runValidators(t)
+}
+
+// Start starts the command in the background. Use Stop to terminate it and
+// verify its output. A command still running at its deadline is terminated
+// and Stop fails the test.
+func (c *Command) Start(t *testing.T) {
+	if c.started {
+		t.Logf("Warn(%s): trying to start command but it was already started\n", c.Name)
+		return
+	}
+
+	if c.Retries > 0 {
+		t.Logf("Warn(%s): retries are ignored for started commands\n", c.Name)
+	}
+
+	t.Logf("Start command(%s): %s\n", c.Name, c.commandLine())
+	if err := c.startProcess(); err != nil {
+		t.Fatalf("failed to start command(%s): %s\n", c.Name, err)
+	}
+
+	if d, ok := c.deadline(t); ok {
+		c.timer = time.AfterFunc(time.Until(d), func() {
+			c.timedOut.Store(true)
+			c.terminate()
+		})
+	}
+
+	c.started = true
+}
+
+// Stop kills a command started with Start and verifies its output. A command
+// that failed on its own before being stopped fails the test; only the exit
+// caused by the kill itself is expected.
+func (c *Command) Stop(t *testing.T) {
+	if !c.started {
+		t.Logf("Warn(%s): trying to stop command but it was not started\n", c.Name)
+		return
+	}
+
+	t.Logf("Stop command(%s)\n", c.Name)
+	c.stopTimer()
+	err := c.kill()
+	c.started = false
+	t.Logf("Command returned(%s):\n%s\n%s\n", c.Name, c.errOutput(), c.output())
+	if err != nil {
+		t.Fatalf("failed to stop command(%s): %s\n", c.Name, err)
+	}
+	switch {
+	case c.timedOut.Load():
+		err = fmt.Errorf("command(%s) was terminated at its deadline before being stopped", c.Name)
+	case c.waitErr != nil && !killedExit(c.waitErr):
+		err = fmt.Errorf("command(%s) failed before being stopped: %w", c.Name, c.waitErr)
+	default:
+		err = c.verifyOutput()
+	}
+
+	Report.record(StepResult{
+		Test:     t.Name(),
+		Command:  c.Name,
+		Attempts: 1,
+		Failed:   err != nil,
+		Duration: time.Since(c.startTime),
+	})
+	if err != nil {
+		t.Fatalf("%s\n(%s)", err, testutils.SeedHint())
+	}
+
+	c.runValidators(t)
+}
+
+// Signal sends sig to the process group of a command started with Start,
+// e.g. syscall.SIGUSR1 to a workload traced by trace_signal. The command
+// keeps running; use Stop to end it. Only unix supports signals other than
+// os.Kill.
+func (c *Command) Signal(t *testing.T, sig os.Signal) {
+	if !c.started {
+		t.Logf("Warn(%s): trying to signal command but it was not started\n", c.Name)
+		return
+	}
+
+	t.Logf("Signal command(%s): %s\n", c.Name, sig)
+	if err := c.signal(sig); err != nil {
+		t.Fatalf("failed to signal command(%s): %s\n", c.Name, err)
+	}
+}
+
+// stopTimer cancels the deadline of a started command. If the deadline
+// already fired, it waits for the termination to complete.
+func (c *Command) stopTimer() {
+	if c.timer != nil && !c.timer.Stop() && c.timedOut.Load() {
+		<-c.done
+	}
+	c.timer = nil
+}
+
+// RunCommands runs cmds in order. Commands with StartAndStop set are started
+// in the background and stopped, in order, once all the others ran. Started
+// commands are killed if the test fails midway.
+func RunCommands(cmds []*Command, t *testing.T) {
+	t.Cleanup(func() {
+		for _, c := range cmds {
+			if c.started {
+				c.stopTimer()
+				c.kill()
+				c.started = false
+			}
+		}
+	})
+
+	for _, c := range cmds {
+		if c.StartAndStop {
+			c.Start(t)
+			continue
+		}
+		c.Run(t)
+	}
+
+	for _, c := range cmds {
+		if c.StartAndStop {
+			c.Stop(t)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// CompareOptions configures CompareRuns.
+type CompareOptions struct {
+	// Flags are passed to ig run for both images.
+	Flags []string
+	// Normalize drops or fixes the volatile fields of events before they
+	// are compared. Defaults to the normalizer of the defaults of each
+	// image (see NormalizerFor).
+	Normalize func(e map[string]any)
+	// Settle is how long the gadgets keep tracing after the workload
+	// returned, ig.RunAroundSettle if zero.
+	Settle time.Duration
+}
+
+// Comparison is the outcome of CompareRuns.
+type Comparison struct {
+	ImageA, ImageB   string
+	EventsA, EventsB int
+	// Common counts the normalized events both gadgets reported.
+	Common int
+	// OnlyA and OnlyB are the normalized events only one of the gadgets
+	// reported, as many times as it did more than the other.
+	OnlyA, OnlyB []map[string]any
+	// FieldsAdded and FieldsRemoved are the paths of the fields only the
+	// events of B, or only those of A, have, whatever their values.
+	FieldsAdded, FieldsRemoved []string
+}
+
+// Equal reports whether both gadgets reported the same normalized events.
+func (c *Comparison) Equal() bool {
+	return len(c.OnlyA) == 0 && len(c.OnlyB) == 0
+}
+
+// String summarizes the comparison, for test failures.
+func (c *Comparison) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d events, %s: %d events, %d in common", c.ImageA, c.EventsA, c.ImageB, c.EventsB, c.Common)
+	if len(c.FieldsAdded) > 0 {
+		fmt.Fprintf(&b, "\nfields added: %s", strings.Join(c.FieldsAdded, ", "))
+	}
+	if len(c.FieldsRemoved) > 0 {
+		fmt.Fprintf(&b, "\nfields removed: %s", strings.Join(c.FieldsRemoved, ", "))
+	}
+	for _, side := range []struct {
+		image  string
+		events []map[string]any
+	}{{c.ImageA, c.OnlyA}, {c.ImageB, c.OnlyB}} {
+		for _, e := range side.events {
+			fmt.Fprintf(&b, "\nonly %s: %s", side.image, formatValue(e))
+		}
+	}
+	return b.String()
+}
+
+// CompareRuns runs two versions of a gadget side by side against a single
+// run of workload and compares their normalized events, to validate a
+// gadget upgrade before rolling it out. The comparison is returned along
+// with any error of the gadgets or the workload.
+func CompareRuns(ctx context.Context, i *ig.IG, imageA, imageB string, workload func(ctx context.Context) error, opts CompareOptions) (*Comparison, error) {
+	settle := opts.Settle
+	if settle == 0 {
+		settle = ig.RunAroundSettle
+	}
+
+	images := [2]string{imageA, imageB}
+	var (
+		sessions  [2]*ig.GadgetSession
+		events    [2][]map[string]any
+		collected [2]chan struct{}
+	)
+	stop := func() error {
+		var errs []error
+		for k, s := range sessions {
+			if s != nil {
+				errs = append(errs, s.Stop())
+				<-collected[k]
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for k, image := range images {
+		s, err := i.Start(ctx, image, opts.Flags...)
+		if err != nil {
+			return nil, errors.Join(err, stop())
+		}
+		sessions[k], collected[k] = s, make(chan struct{})
+		normalize := opts.Normalize
+		if normalize == nil {
+			normalize = NormalizerFor(image)
+		}
+		go func(k int) {
+			defer close(collected[k])
+			for ev := range s.Events() {
+				if ev.Fields == nil {
+					continue
+				}
+				normalize(ev.Fields)
+				events[k] = append(events[k], ev.Fields)
+			}
+		}(k)
+	}
+	for _, s := range sessions {
+		if err := s.WaitReady(ctx); err != nil {
+			return nil, errors.Join(err, stop())
+		}
+	}
+
+	workErr := workload(ctx)
+	if workErr != nil {
+		workErr = fmt.Errorf("running workload: %w", workErr)
+	} else {
+		select {
+		case <-time.After(settle):
+		case <-ctx.Done():
+		}
+	}
+	err := errors.Join(workErr, stop())
+
+	c := compareEvents(events[0], events[1])
+	c.ImageA, c.ImageB = imageA, imageB
+	return c, err
+}
+
+func compareEvents(a, b []map[string]any) *Comparison {
+	c := &Comparison{EventsA: len(a), EventsB: len(b)}
+
+	// Events are compared by their JSON form, whose keys are sorted.
+	pending := map[string][]map[string]any{}
+	for _, e := range b {
+		key := eventKey(e)
+		pending[key] = append(pending[key], e)
+	}
+	for _, e := range a {
+		key := eventKey(e)
+		if len(pending[key]) > 0 {
+			pending[key] = pending[key][1:]
+			c.Common++
+			continue
+		}
+		c.OnlyA = append(c.OnlyA, e)
+	}
+	for _, e := range b {
+		key := eventKey(e)
+		if len(pending[key]) > 0 {
+			c.OnlyB = append(c.OnlyB, pending[key][0])
+			pending[key] = pending[key][1:]
+		}
+	}
+
+	pathsA, pathsB := map[string]bool{}, map[string]bool{}
+	for _, e := range a {
+		leafPaths("", e, pathsA)
+	}
+	for _, e := range b {
+		leafPaths("", e, pathsB)
+	}
+	for p := range pathsB {
+		if !pathsA[p] {
+			c.FieldsAdded = append(c.FieldsAdded, p)
+		}
+	}
+	for p := range pathsA {
+		if !pathsB[p] {
+			c.FieldsRemoved = append(c.FieldsRemoved, p)
+		}
+	}
+	sort.Strings(c.FieldsAdded)
+	sort.Strings(c.FieldsRemoved)
+	return c
+}
+
+func eventKey(e map[string]any) string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+func leafPaths(prefix string, e map[string]any, paths map[string]bool) {
+	for k, v := range e {
+		if m, ok := v.(map[string]any); ok {
+			leafPaths(prefix+k+".", m, paths)
+			continue
+		}
+		paths[prefix+k] = true
+	}
+}
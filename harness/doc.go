@@ -0,0 +1,6 @@
+// Package harness runs shell commands driving ig (or kubectl-gadget) from Go
+// tests and checks their output, following the shape of Inspektor Gadget's
+// own integration tests: a test is a list of Commands, some run to
+// completion and some started in the background and stopped once the
+// workload ran.
+package harness
@@ -0,0 +1,102 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// SplitYAMLDocuments splits a YAML stream, such as the network policies of
+// advise network-policy, into its documents: the text between "---"
+// separators, up to "..." end markers. Separators only count at the start
+// of a line, so block scalars can't split documents. Documents holding only
+// comments are dropped, and so is prose printed before the first separator,
+// unless a line of it reads as a mapping ("Warning: ..."), which YAML can't
+// tell from prose.
+func SplitYAMLDocuments(output string) []string {
+	var docs []string
+	var cur []string
+	seenSeparator := false
+	flush := func() {
+		doc := strings.TrimSpace(strings.Join(cur, "\n"))
+		cur = cur[:0]
+		if hasContent(doc) {
+			docs = append(docs, doc)
+		}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t"):
+			if !seenSeparator && !looksLikeYAML(strings.Join(cur, "\n")) {
+				cur = cur[:0]
+			}
+			flush()
+			seenSeparator = true
+			// "--- value" puts content on the separator line.
+			if rest := strings.TrimSpace(line[3:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				cur = append(cur, rest)
+			}
+		case line == "..." || strings.HasPrefix(line, "... "):
+			flush()
+		default:
+			cur = append(cur, line)
+		}
+	}
+	flush()
+	return docs
+}
+
+// hasContent reports whether a YAML document has lines other than comments.
+func hasContent(doc string) bool {
+	for _, line := range strings.Split(doc, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeYAML reports whether text has a mapping line, "key: value" or
+// "key:", outside comments.
+func looksLikeYAML(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "- ")
+		if k, _, ok := strings.Cut(line, ":"); ok && k != "" && !strings.ContainsAny(k, " \t") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractJSON returns the JSON objects and arrays found in output, such as
+// the seccomp profile of advise seccomp-profile among log lines. Documents
+// start at the beginning of a line, possibly indented, and may span
+// several lines; lines that don't start a valid document are skipped.
+func ExtractJSON(output string) []json.RawMessage {
+	var docs []json.RawMessage
+	data := []byte(output)
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			start := data[len(line)-len(trimmed):]
+			dec := json.NewDecoder(bytes.NewReader(start))
+			var raw json.RawMessage
+			if dec.Decode(&raw) == nil {
+				docs = append(docs, raw)
+				data = start[dec.InputOffset():]
+				continue
+			}
+		}
+		data = data[len(line):]
+	}
+	return docs
+}
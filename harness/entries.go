@@ -0,0 +1,175 @@
+package harness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Kinds of FieldDiff.
+const (
+	DiffChanged    = "changed"
+	DiffMissing    = "missing"
+	DiffUnexpected = "unexpected"
+)
+
+// FieldDiff is a field whose value differs between an expected and an actual
+// event. It marshals to JSON so failures can be consumed by tools.
+type FieldDiff struct {
+	// Path is the dot-separated path of the field.
+	Path string `json:"path"`
+	// Kind is DiffChanged, DiffMissing (only in the expected event) or
+	// DiffUnexpected (only in the actual event).
+	Kind     string `json:"kind"`
+	Expected any    `json:"expected,omitempty"`
+	Actual   any    `json:"actual,omitempty"`
+}
+
+func (d FieldDiff) String() string {
+	switch d.Kind {
+	case DiffMissing:
+		return fmt.Sprintf("%s: expected %s, actual <missing>", d.Path, formatValue(d.Expected))
+	case DiffUnexpected:
+		return fmt.Sprintf("%s: expected <missing>, actual %s", d.Path, formatValue(d.Actual))
+	}
+	return fmt.Sprintf("%s: expected %s, actual %s", d.Path, formatValue(d.Expected), formatValue(d.Actual))
+}
+
+// DiffEvents returns the fields that differ between expected and actual,
+// sorted by path. Nested objects are compared field by field; any other
+// value, arrays included, is compared as a whole.
+func DiffEvents(expected, actual map[string]any) []FieldDiff {
+	var diffs []FieldDiff
+	diffMaps("", expected, actual, &diffs)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+func diffMaps(prefix string, expected, actual map[string]any, diffs *[]FieldDiff) {
+	for k, ev := range expected {
+		path := prefix + k
+		av, ok := actual[k]
+		if !ok {
+			*diffs = append(*diffs, FieldDiff{Path: path, Kind: DiffMissing, Expected: ev})
+			continue
+		}
+
+		em, eok := ev.(map[string]any)
+		am, aok := av.(map[string]any)
+		if eok && aok {
+			diffMaps(path+".", em, am, diffs)
+			continue
+		}
+		if !valuesEqual(av, ev) {
+			*diffs = append(*diffs, FieldDiff{Path: path, Kind: DiffChanged, Expected: ev, Actual: av})
+		}
+	}
+	for k, av := range actual {
+		if _, ok := expected[k]; !ok {
+			*diffs = append(*diffs, FieldDiff{Path: prefix + k, Kind: DiffUnexpected, Actual: av})
+		}
+	}
+}
+
+// MismatchError reports an expected entry none of the actual events matched.
+type MismatchError struct {
+	// Expected is the entry, as decoded from its JSON form.
+	Expected map[string]any
+	// Closest is the event with the fewest differing fields, nil if there
+	// were no events at all.
+	Closest map[string]any
+	// Diffs are the fields in which Closest differs from Expected.
+	Diffs []FieldDiff
+	// Events is the number of events searched.
+	Events int
+}
+
+func (e *MismatchError) Error() string {
+	if e.Closest == nil {
+		exp, _ := json.Marshal(e.Expected)
+		return fmt.Sprintf("no events to match expected entry %s", exp)
+	}
+
+	lines := make([]string, len(e.Diffs))
+	for i, d := range e.Diffs {
+		lines[i] = d.String()
+	}
+	diff, _ := json.Marshal(e.Diffs)
+
+	return fmt.Sprintf("no event matched expected entry; closest of %d events differs in %d fields:\n  %s\ndiff: %s",
+		e.Events, len(e.Diffs), strings.Join(lines, "\n  "), diff)
+}
+
+// toEntry converts v, a map or a struct with json tags, to the shape events
+// have once decoded from JSON, so values compare regardless of Go types.
+func toEntry(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("expected entry must marshal to a JSON object: %w", err)
+	}
+	return m, nil
+}
+
+// MatchEntries checks that every expected entry (a map or a struct with json
+// tags) equals at least one of events. The returned error joins a
+// *MismatchError per unmatched entry.
+func MatchEntries(events []map[string]any, expected ...any) error {
+	var errs []error
+	for i, exp := range expected {
+		entry, err := toEntry(exp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expected entry %d: %w", i, err))
+			continue
+		}
+
+		mismatch := &MismatchError{Expected: entry, Events: len(events)}
+		matched := false
+		for _, ev := range events {
+			diffs := DiffEvents(entry, ev)
+			if len(diffs) == 0 {
+				matched = true
+				break
+			}
+			if mismatch.Closest == nil || len(diffs) < len(mismatch.Diffs) {
+				mismatch.Closest = ev
+				mismatch.Diffs = diffs
+			}
+		}
+		if !matched {
+			errs = append(errs, mismatch)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExpectEntriesToMatch decodes the JSON events in output, passes each one to
+// normalize (if not nil) to drop or fix volatile fields such as timestamps
+// and pids, and fails the test unless every expected entry equals one of
+// them.
+func ExpectEntriesToMatch(t *testing.T, output string, normalize func(e map[string]any), expected ...any) {
+	t.Helper()
+
+	if err := matchOutputEntries(output, normalize, expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func matchOutputEntries(output string, normalize func(e map[string]any), expected ...any) error {
+	events, err := DecodeEvents(output)
+	if err != nil {
+		return err
+	}
+	if normalize != nil {
+		for _, e := range events {
+			normalize(e)
+		}
+	}
+	return MatchEntries(events, expected...)
+}
@@ -0,0 +1,58 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DecodeEvents decodes the events in the output of ig run -o json (one JSON
+// object per line) or -o jsonpretty (a stream of possibly multi-line
+// objects). Top-level arrays, as printed by snapshot gadgets, are flattened.
+func DecodeEvents(output string) ([]map[string]any, error) {
+	var events []map[string]any
+
+	dec := json.NewDecoder(strings.NewReader(output))
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return events, fmt.Errorf("decoding event %d: %w", len(events), err)
+		}
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var batch []map[string]any
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return events, fmt.Errorf("decoding event batch: %w", err)
+			}
+			events = append(events, batch...)
+			continue
+		}
+
+		var e map[string]any
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return events, fmt.Errorf("decoding event %d: %w", len(events), err)
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// lookupField returns the value at the dot-separated path in event, e.g.
+// "dst.port" or "k8s.namespace".
+func lookupField(event map[string]any, path string) (any, bool) {
+	var cur any = event
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
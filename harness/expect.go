@@ -0,0 +1,196 @@
+package harness
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Expectation is a set of conditions on the fields of a decoded event,
+// built fluently:
+//
+//	harness.Expect().Field("comm").Equals("curl").Field("dst.port").In(80, 443)
+//
+// Fields are addressed by dot-separated paths into nested objects. Numbers
+// compare by value whatever their Go type, so Equals(80) matches the float64
+// produced by JSON decoding.
+type Expectation struct {
+	conds []condition
+}
+
+type condition struct {
+	path string
+	// desc describes the wanted value, e.g. `== "curl"`.
+	desc  string
+	check func(v any, present bool) bool
+}
+
+// Expect starts a new expectation.
+func Expect() *Expectation {
+	return &Expectation{}
+}
+
+// Field selects the field the next condition applies to.
+func (e *Expectation) Field(path string) *FieldMatcher {
+	return &FieldMatcher{e: e, path: path}
+}
+
+// FieldMatcher adds a condition on one field to an Expectation.
+type FieldMatcher struct {
+	e    *Expectation
+	path string
+}
+
+func (f *FieldMatcher) add(desc string, check func(v any, present bool) bool) *Expectation {
+	f.e.conds = append(f.e.conds, condition{path: f.path, desc: desc, check: check})
+	return f.e
+}
+
+// Equals requires the field to equal v.
+func (f *FieldMatcher) Equals(v any) *Expectation {
+	return f.add("== "+formatValue(v), func(got any, present bool) bool {
+		return present && valuesEqual(got, v)
+	})
+}
+
+// NotEquals requires the field to be present and differ from v.
+func (f *FieldMatcher) NotEquals(v any) *Expectation {
+	return f.add("!= "+formatValue(v), func(got any, present bool) bool {
+		return present && !valuesEqual(got, v)
+	})
+}
+
+// In requires the field to equal one of vs.
+func (f *FieldMatcher) In(vs ...any) *Expectation {
+	descs := make([]string, len(vs))
+	for i, v := range vs {
+		descs[i] = formatValue(v)
+	}
+	return f.add("in ["+strings.Join(descs, ", ")+"]", func(got any, present bool) bool {
+		if !present {
+			return false
+		}
+		for _, v := range vs {
+			if valuesEqual(got, v) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Matches requires the field, formatted as a string, to match the regexp
+// expr. It panics if expr doesn't compile, like regexp.MustCompile.
+func (f *FieldMatcher) Matches(expr string) *Expectation {
+	re := regexp.MustCompile(expr)
+	return f.add("=~ /"+expr+"/", func(got any, present bool) bool {
+		return present && re.MatchString(fmt.Sprint(got))
+	})
+}
+
+// Contains requires the field, formatted as a string, to contain sub.
+func (f *FieldMatcher) Contains(sub string) *Expectation {
+	return f.add("contains "+formatValue(sub), func(got any, present bool) bool {
+		return present && strings.Contains(fmt.Sprint(got), sub)
+	})
+}
+
+// Exists requires the field to be present, whatever its value.
+func (f *FieldMatcher) Exists() *Expectation {
+	return f.add("exists", func(_ any, present bool) bool {
+		return present
+	})
+}
+
+// Missing requires the field to be absent.
+func (f *FieldMatcher) Missing() *Expectation {
+	return f.add("is missing", func(_ any, present bool) bool {
+		return !present
+	})
+}
+
+// Satisfies requires fn to return true for the field's value. desc describes
+// the condition in failure messages.
+func (f *FieldMatcher) Satisfies(desc string, fn func(v any) bool) *Expectation {
+	return f.add(desc, func(got any, present bool) bool {
+		return present && fn(got)
+	})
+}
+
+func (e *Expectation) String() string {
+	parts := make([]string, len(e.conds))
+	for i, c := range e.conds {
+		parts[i] = c.path + " " + c.desc
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// failures returns a line per condition event doesn't meet.
+func (e *Expectation) failures(event map[string]any) []string {
+	var out []string
+	for _, c := range e.conds {
+		v, present := lookupField(event, c.path)
+		if c.check(v, present) {
+			continue
+		}
+		got := "<missing>"
+		if present {
+			got = formatValue(v)
+		}
+		out = append(out, fmt.Sprintf("%s: got %s, want %s", c.path, got, c.desc))
+	}
+	return out
+}
+
+// Match returns an error listing every condition event doesn't meet.
+func (e *Expectation) Match(event map[string]any) error {
+	fails := e.failures(event)
+	if len(fails) == 0 {
+		return nil
+	}
+	return fmt.Errorf("event didn't match %s:\n  %s", e, strings.Join(fails, "\n  "))
+}
+
+// MatchAny returns nil if at least one event meets every condition.
+// Otherwise the error shows how the closest event, the one failing the fewest
+// conditions, differs from the expectation.
+func (e *Expectation) MatchAny(events []map[string]any) error {
+	if len(events) == 0 {
+		return fmt.Errorf("no events to match %s", e)
+	}
+
+	var closest []string
+	for _, ev := range events {
+		fails := e.failures(ev)
+		if len(fails) == 0 {
+			return nil
+		}
+		if closest == nil || len(fails) < len(closest) {
+			closest = fails
+		}
+	}
+
+	return fmt.Errorf("none of %d events matched %s; closest event differs in:\n  %s",
+		len(events), e, strings.Join(closest, "\n  "))
+}
+
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
+
+// valuesEqual compares a decoded value with an expected one, treating all
+// numeric types alike.
+func valuesEqual(got, want any) bool {
+	gf, gok := ig.Number(got)
+	wf, wok := ig.Number(want)
+	if gok && wok {
+		return gf == wf
+	}
+	return reflect.DeepEqual(got, want)
+}
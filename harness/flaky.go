@@ -0,0 +1,14 @@
+package harness
+
+// RetryFlaky marks cmd as a known-flaky step, rerun up to n more times when
+// it fails. Every attempt is logged and a command passing on a retry is
+// tagged as flaky in Report. It returns cmd to allow use in command lists:
+//
+//	harness.RunCommands([]*harness.Command{
+//		harness.RetryFlaky(2, pullCmd),
+//		runCmd,
+//	}, t)
+func RetryFlaky(n int, cmd *Command) *Command {
+	cmd.Retries = n
+	return cmd
+}
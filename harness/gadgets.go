@@ -0,0 +1,236 @@
+package harness
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// GadgetDefaults describe how to compare the events of a gadget, so
+// ExpectEntries-style helpers work for known gadgets without each test
+// writing its own normalizer.
+type GadgetDefaults struct {
+	// Keep, if not empty, restricts normalized events to these fields: the
+	// ones a workload determines, such as the command name. Expected entries
+	// then list the kept fields their events have, and nothing else.
+	Keep []string
+	// Volatile fields, such as timestamps and PIDs, are dropped from
+	// normalized events.
+	Volatile []string
+	// Match lists, in order, the fields identifying an event of the
+	// gadget, the values of which GadgetEntry takes. They should be kept.
+	Match []string
+}
+
+// CommonVolatile are the fields of every gadget that differ from run to run.
+var CommonVolatile = []string{
+	"timestamp",
+	"timestamp_raw",
+	"proc.pid",
+	"proc.tid",
+	"proc.mntns_id",
+	"proc.parent.pid",
+	"runtime.containerId",
+	"runtime.containerImageDigest",
+	"runtime.containerStartedAt",
+}
+
+var (
+	gadgetDefaultsMu sync.RWMutex
+	// Histogram gadgets, such as profile_blockio, have no defaults: their
+	// buckets only depend on timing.
+	gadgetDefaults = map[string]GadgetDefaults{
+		"trace_exec": {
+			Keep:  []string{"proc.comm", "args", "error", "runtime.containerName"},
+			Match: []string{"proc.comm", "args"},
+		},
+		"trace_open": {
+			Keep:  []string{"proc.comm", "fname", "error", "runtime.containerName"},
+			Match: []string{"proc.comm", "fname"},
+		},
+		"trace_tcp": {
+			Keep:  []string{"proc.comm", "type", "dst.addr", "dst.port", "runtime.containerName"},
+			Match: []string{"proc.comm", "type", "dst.addr", "dst.port"},
+		},
+		"trace_dns": {
+			Keep:  []string{"proc.comm", "name", "qtype", "qr", "rcode", "runtime.containerName"},
+			Match: []string{"proc.comm", "name", "qr"},
+		},
+		"trace_signal": {
+			Keep:  []string{"proc.comm", "sig", "runtime.containerName"},
+			Match: []string{"proc.comm", "sig"},
+		},
+		"trace_bind": {
+			Keep:  []string{"proc.comm", "addr.addr", "addr.port", "addr.proto", "error", "runtime.containerName"},
+			Match: []string{"proc.comm", "addr.port"},
+		},
+		"trace_capabilities": {
+			Keep:  []string{"proc.comm", "cap", "syscall", "verdict", "audit", "runtime.containerName"},
+			Match: []string{"proc.comm", "cap"},
+		},
+		"trace_mount": {
+			Keep:  []string{"proc.comm", "op", "src", "dest", "fs", "error", "runtime.containerName"},
+			Match: []string{"proc.comm", "op", "dest"},
+		},
+		"trace_oomkill": {
+			Keep:  []string{"fcomm", "tcomm", "runtime.containerName"},
+			Match: []string{"tcomm"},
+		},
+		"trace_sni": {
+			Keep:  []string{"proc.comm", "name", "runtime.containerName"},
+			Match: []string{"proc.comm", "name"},
+		},
+		"trace_fsslower": {
+			Keep:  []string{"proc.comm", "op", "file", "runtime.containerName"},
+			Match: []string{"proc.comm", "op", "file"},
+		},
+		"trace_malloc": {
+			Keep:  []string{"proc.comm", "operation", "runtime.containerName"},
+			Match: []string{"proc.comm", "operation"},
+		},
+		"audit_seccomp": {
+			Keep:  []string{"proc.comm", "syscall", "code", "runtime.containerName"},
+			Match: []string{"proc.comm", "syscall"},
+		},
+		"snapshot_process": {
+			Keep:  []string{"comm", "uid", "gid", "runtime.containerName"},
+			Match: []string{"comm"},
+		},
+		"snapshot_socket": {
+			Keep:  []string{"src.addr", "src.port", "src.proto", "dst.addr", "dst.port", "state", "runtime.containerName"},
+			Match: []string{"src.proto", "src.port", "state"},
+		},
+		"top_file": {
+			Keep:  []string{"proc.comm", "file", "t", "runtime.containerName"},
+			Match: []string{"proc.comm", "file"},
+		},
+		"top_tcp": {
+			Keep:  []string{"proc.comm", "src.addr", "src.port", "dst.addr", "dst.port", "runtime.containerName"},
+			Match: []string{"proc.comm", "dst.addr", "dst.port"},
+		},
+		"top_blockio": {
+			Keep:  []string{"proc.comm", "major", "minor", "rw", "runtime.containerName"},
+			Match: []string{"proc.comm", "rw"},
+		},
+	}
+)
+
+// RegisterGadget sets the defaults of the gadget named name, e.g.
+// "trace_exec", replacing any previous ones.
+func RegisterGadget(name string, d GadgetDefaults) {
+	gadgetDefaultsMu.Lock()
+	defer gadgetDefaultsMu.Unlock()
+
+	gadgetDefaults[name] = d
+}
+
+// DefaultsFor returns the defaults registered for gadget, a name or an
+// image, whatever its registry or tag.
+func DefaultsFor(gadget string) (GadgetDefaults, bool) {
+	gadgetDefaultsMu.RLock()
+	defer gadgetDefaultsMu.RUnlock()
+
+	d, ok := gadgetDefaults[ig.GadgetName(gadget)]
+	return d, ok
+}
+
+// NormalizerFor returns the normalizer of the defaults of gadget. Events of
+// gadgets without defaults only lose CommonVolatile.
+func NormalizerFor(gadget string) func(e map[string]any) {
+	d, ok := DefaultsFor(gadget)
+	if !ok {
+		d.Volatile = CommonVolatile
+	}
+	return func(e map[string]any) {
+		if len(d.Keep) > 0 {
+			kept := map[string]any{}
+			for _, path := range d.Keep {
+				if v, ok := lookupField(e, path); ok {
+					setField(kept, path, v)
+				}
+			}
+			for k := range e {
+				delete(e, k)
+			}
+			for k, v := range kept {
+				e[k] = v
+			}
+		}
+		for _, path := range d.Volatile {
+			deleteField(e, path)
+		}
+	}
+}
+
+// Entry builds an expected entry from dot-separated paths, e.g.
+// Entry(map[string]any{"proc.comm": "cat", "fname": "/etc/passwd"}).
+func Entry(fields map[string]any) map[string]any {
+	e := map[string]any{}
+	for path, v := range fields {
+		setField(e, path, v)
+	}
+	return e
+}
+
+// GadgetEntry builds an expected entry of gadget from the values of the
+// leading Match fields of its defaults, e.g. GadgetEntry(t, "trace_open",
+// "cat", "/etc/passwd"). It fails the test if gadget has no defaults or more
+// values than Match fields are given.
+func GadgetEntry(t *testing.T, gadget string, values ...any) map[string]any {
+	t.Helper()
+
+	d, ok := DefaultsFor(gadget)
+	if !ok {
+		t.Fatalf("no defaults registered for gadget %s", gadget)
+	}
+	if len(values) > len(d.Match) {
+		t.Fatalf("%d values given for gadget %s, which only matches on %v", len(values), gadget, d.Match)
+	}
+	e := map[string]any{}
+	for i, v := range values {
+		setField(e, d.Match[i], v)
+	}
+	return e
+}
+
+// InContainer adds the container name to an expected entry and returns it,
+// e.g. InContainer(GadgetEntry(t, "trace_exec", "cat"), c.Name).
+func InContainer(e map[string]any, name string) map[string]any {
+	setField(e, "runtime.containerName", name)
+	return e
+}
+
+// ExpectGadgetEntriesToMatch is ExpectEntriesToMatch with the normalizer of
+// the defaults of gadget.
+func ExpectGadgetEntriesToMatch(t *testing.T, gadget, output string, expected ...any) {
+	t.Helper()
+
+	ExpectEntriesToMatch(t, output, NormalizerFor(gadget), expected...)
+}
+
+func setField(e map[string]any, path string, v any) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := e[p].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			e[p] = child
+		}
+		e = child
+	}
+	e[parts[len(parts)-1]] = v
+}
+
+func deleteField(e map[string]any, path string) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := e[p].(map[string]any)
+		if !ok {
+			return
+		}
+		e = child
+	}
+	delete(e, parts[len(parts)-1])
+}
@@ -0,0 +1,213 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+// UpdateGoldenEnv, set to a non-empty value, makes RunGoldenDir write the
+// normalized events it observes to the expected files instead of comparing.
+const UpdateGoldenEnv = "IG_UPDATE_GOLDEN"
+
+// Files of a gadget directory of RunGoldenDir.
+const (
+	GoldenWorkloadFile = "workload.sh"
+	GoldenFlagsFile    = "flags"
+	GoldenImageFile    = "image"
+	GoldenExpectedFile = "expected.json"
+)
+
+// GoldenOptions configures RunGoldenDir.
+type GoldenOptions struct {
+	// IG runs the gadgets.
+	IG *ig.IG
+	// Container runs the workloads. If nil, they run on the host.
+	Container *testutils.TestContainer
+	// ImagePrefix is prepended to directory names to get images, e.g.
+	// "ghcr.io/inspektor-gadget/gadget/". Ignored by directories with an
+	// image file.
+	ImagePrefix string
+	// Normalize is called on every event to drop or fix volatile fields
+	// before comparison, with the gadget directory name. Defaults to the
+	// normalizer of the defaults of the gadget (see NormalizerFor).
+	Normalize func(gadget string, e map[string]any)
+	// Idle is how long gadgets must stay silent after the workload ran
+	// before they are stopped, 1s if zero. See ig.GadgetSession.WaitIdle.
+	Idle time.Duration
+	// Settle bounds how long gadgets keep tracing after the workload ran,
+	// for gadgets that never go idle, 10s if zero.
+	Settle time.Duration
+}
+
+// RunGoldenDir runs a subtest per subdirectory of dir, one per gadget. Each
+// directory holds:
+//
+//	workload.sh    script generating the activity to trace
+//	expected.json  expected normalized events, as JSON lines or an array
+//	flags          optional extra ig run arguments, one per line
+//	image          optional image, instead of ImagePrefix + directory name
+//
+// The subtest starts the gadget, waits for it to be ready, runs the workload,
+// waits for the gadget to go idle and checks every expected event was seen,
+// as ExpectEntriesToMatch does. Covering a new gadget takes
+// adding a directory. Run with IG_UPDATE_GOLDEN=1 to regenerate expected.json
+// from what the gadgets print.
+func RunGoldenDir(t *testing.T, dir string, opts GoldenOptions) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading golden directory: %s", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		t.Run(name, func(t *testing.T) {
+			runGolden(t, name, filepath.Join(dir, name), opts)
+		})
+	}
+}
+
+func runGolden(t *testing.T, name, dir string, opts GoldenOptions) {
+	workload, err := os.ReadFile(filepath.Join(dir, GoldenWorkloadFile))
+	if err != nil {
+		t.Fatalf("reading workload: %s", err)
+	}
+	image := opts.ImagePrefix + name
+	if b, err := os.ReadFile(filepath.Join(dir, GoldenImageFile)); err == nil {
+		image = strings.TrimSpace(string(b))
+	}
+	flags, err := readLines(filepath.Join(dir, GoldenFlagsFile))
+	if err != nil {
+		t.Fatalf("reading flags: %s", err)
+	}
+	idle, settle := opts.Idle, opts.Settle
+	if idle == 0 {
+		idle = time.Second
+	}
+	if settle == 0 {
+		settle = 10 * time.Second
+	}
+
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d.Add(-DeadlineMargin))
+		defer cancel()
+	}
+
+	normalize := NormalizerFor(image)
+	if opts.Normalize != nil {
+		normalize = func(e map[string]any) { opts.Normalize(name, e) }
+	}
+
+	s, err := opts.IG.Start(ctx, image, flags...)
+	if err != nil {
+		t.Fatalf("starting gadget: %s", err)
+	}
+	var events []map[string]any
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for ev := range s.Events() {
+			if ev.Fields == nil {
+				continue
+			}
+			normalize(ev.Fields)
+			events = append(events, ev.Fields)
+		}
+	}()
+
+	if err := s.WaitReady(ctx); err != nil {
+		s.Stop()
+		t.Fatalf("starting gadget: %s", err)
+	}
+	w := testutils.Workload{Script: string(workload)}
+	var out string
+	if opts.Container != nil {
+		out, err = w.RunIn(ctx, opts.Container)
+	} else {
+		out, err = w.RunLocal(ctx)
+	}
+	if err != nil {
+		s.Stop()
+		t.Fatalf("running workload: %s\n%s", err, out)
+	}
+	settleCtx, cancel := context.WithTimeout(ctx, settle)
+	// A gadget still printing at the end of settle is stopped all the same.
+	s.WaitIdle(settleCtx, idle)
+	cancel()
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("stopping gadget: %s", err)
+	}
+	<-collected
+
+	expectedPath := filepath.Join(dir, GoldenExpectedFile)
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := writeGolden(expectedPath, events); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("updated %s with %d events", expectedPath, len(events))
+		return
+	}
+
+	b, err := os.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("reading expected events: %s", err)
+	}
+	expected, err := DecodeEvents(string(b))
+	if err != nil {
+		t.Fatalf("decoding %s: %s", expectedPath, err)
+	}
+	want := make([]any, len(expected))
+	for i, e := range expected {
+		want[i] = e
+	}
+	if err := MatchEntries(events, want...); err != nil {
+		t.Fatalf("gadget %s: %s", name, err)
+	}
+}
+
+// readLines returns the non-empty lines of path, none if it doesn't exist.
+func readLines(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+			lines = append(lines, l)
+		}
+	}
+	return lines, nil
+}
+
+func writeGolden(path string, events []map[string]any) error {
+	var b strings.Builder
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
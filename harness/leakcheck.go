@@ -0,0 +1,41 @@
+package harness
+
+import (
+	"strings"
+	"testing"
+)
+
+// CheckLeaks snapshots the goroutines and child processes of the test binary
+// and, when the test ends, fails it if any started since are still around
+// after LeakGrace, or if a child was left unreaped:
+//
+//	func TestGadget(t *testing.T) {
+//		harness.CheckLeaks(t)
+//		...
+//	}
+//
+// Grandchildren whose parent was killed are reparented away from the test
+// binary and escape the check unless TrackOrphans was called. The process
+// checks only run on Linux; elsewhere only goroutines are checked.
+func CheckLeaks(t testing.TB) {
+	t.Helper()
+
+	snap := takeLeakSnapshot()
+	if snap.procsErr != nil {
+		t.Logf("not checking process leaks: %s", snap.procsErr)
+	}
+	t.Cleanup(func() {
+		if leaks := snap.checkLeaks(); len(leaks) > 0 {
+			t.Errorf("test leaked:\n%s", strings.Join(leaks, "\n"))
+		}
+	})
+}
+
+// TrackOrphans makes the test binary adopt the orphaned descendants of its
+// children (see PR_SET_CHILD_SUBREAPER), so CheckLeaks catches grandchildren
+// left alive by a killed shell. It applies to the rest of the process
+// lifetime and is only supported on Linux. Adopted processes that exit stay
+// zombies, and are reported as such, unless a reaper collects them.
+func TrackOrphans() error {
+	return setChildSubreaper()
+}
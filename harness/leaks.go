@@ -0,0 +1,94 @@
+package harness
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// procInfo is a process descending from the test binary.
+type procInfo struct {
+	PID   int
+	PPID  int
+	State string
+	Comm  string
+}
+
+func (p procInfo) String() string {
+	return fmt.Sprintf("%d (%s) state %s, parent %d", p.PID, p.Comm, p.State, p.PPID)
+}
+
+// zombie reports whether the process exited without being reaped.
+func (p procInfo) zombie() bool {
+	return p.State == "Z"
+}
+
+// LeakGrace is how long leak checks wait for goroutines and processes to
+// wind down before reporting them as leaked.
+var LeakGrace = 5 * time.Second
+
+// leakSnapshot is the state leaks are checked against.
+type leakSnapshot struct {
+	goroutines int
+	procs      map[int]bool
+	// procsErr is set where child processes can't be listed, which skips
+	// the process checks.
+	procsErr error
+}
+
+func takeLeakSnapshot() leakSnapshot {
+	s := leakSnapshot{goroutines: runtime.NumGoroutine(), procs: map[int]bool{}}
+	procs, err := childProcesses()
+	s.procsErr = err
+	for _, p := range procs {
+		s.procs[p.PID] = true
+	}
+	return s
+}
+
+// checkLeaks waits up to LeakGrace for the goroutines and child processes
+// started since s to go away, and describes those that didn't.
+func (s leakSnapshot) checkLeaks() []string {
+	deadline := time.Now().Add(LeakGrace)
+	for {
+		leaks := s.leaks()
+		if len(leaks) == 0 || time.Now().After(deadline) {
+			return leaks
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (s leakSnapshot) leaks() []string {
+	var leaks []string
+
+	if n := runtime.NumGoroutine(); n > s.goroutines {
+		leaks = append(leaks, fmt.Sprintf("%d goroutines leaked:\n%s", n-s.goroutines, goroutineDump()))
+	}
+
+	if s.procsErr == nil {
+		procs, err := childProcesses()
+		if err != nil {
+			return append(leaks, fmt.Sprintf("listing child processes: %s", err))
+		}
+		sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+		for _, p := range procs {
+			switch {
+			case p.zombie():
+				leaks = append(leaks, "zombie process "+p.String())
+			case !s.procs[p.PID]:
+				leaks = append(leaks, "leaked process "+p.String())
+			}
+		}
+	}
+
+	return leaks
+}
+
+func goroutineDump() string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return strings.TrimSpace(string(buf))
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package harness
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// lockFile takes an exclusive lock on path by creating it, held until unlock
+// is called. A process dying with the lock leaves path behind, to be removed
+// by hand.
+func lockFile(ctx context.Context, path string) (unlock func(), err error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// processAlive always reports true: holders that died are only forgotten on
+// unix.
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,176 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Outcomes of a test of a Matrix.
+const (
+	MatrixPass = "pass"
+	MatrixFail = "fail"
+	MatrixSkip = "skip"
+	// MatrixError marks the tests of a version that couldn't be installed.
+	MatrixError = "error"
+)
+
+// MatrixTest is a test of the suite run by RunMatrix against every version.
+type MatrixTest struct {
+	Name string
+	Run  func(t *testing.T, i *ig.IG)
+}
+
+// MatrixOptions configures RunMatrix.
+type MatrixOptions struct {
+	// InstallDir caches the installed binaries, see ig.Install.
+	InstallDir string
+	// Options configure the IG of every version, on top of its path.
+	Options []ig.Option
+}
+
+// Matrix is the outcome of every test against every version of RunMatrix.
+type Matrix struct {
+	Versions []string `json:"versions"`
+	Tests    []string `json:"tests"`
+	// Results maps test names, then versions, to Matrix outcomes.
+	Results map[string]map[string]string `json:"results"`
+	// Errors maps the versions that couldn't be installed to the error.
+	Errors map[string]string `json:"errors,omitempty"`
+
+	mu sync.Mutex
+}
+
+func (m *Matrix) set(test, version, outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Results[test] == nil {
+		m.Results[test] = map[string]string{}
+	}
+	m.Results[test][version] = outcome
+}
+
+// Compatible returns the versions every test passed or skipped on.
+func (m *Matrix) Compatible() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var versions []string
+	for _, v := range m.Versions {
+		ok := m.Errors[v] == ""
+		for _, t := range m.Tests {
+			if r := m.Results[t][v]; r != MatrixPass && r != MatrixSkip {
+				ok = false
+			}
+		}
+		if ok {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// WriteMarkdown writes the matrix as a Markdown table, a row per test and a
+// column per version.
+func (m *Matrix) WriteMarkdown(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("| test |")
+	for _, v := range m.Versions {
+		fmt.Fprintf(&b, " %s |", v)
+	}
+	b.WriteString("\n|---|" + strings.Repeat("---|", len(m.Versions)) + "\n")
+	for _, t := range m.Tests {
+		fmt.Fprintf(&b, "| %s |", t)
+		for _, v := range m.Versions {
+			r := m.Results[t][v]
+			if r == "" {
+				r = "-"
+			}
+			fmt.Fprintf(&b, " %s |", r)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the matrix as JSON.
+func (m *Matrix) WriteJSON(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// RunMatrix installs every ig version with ig.Install and runs the tests
+// against each, as subtests named <version>/<test>, then logs the matrix of
+// their outcomes and returns it, e.g. to write it out as a compatibility
+// report. Versions that can't be installed fail their subtest, with their
+// tests marked MatrixError.
+func RunMatrix(t *testing.T, versions []string, opts MatrixOptions, tests ...MatrixTest) *Matrix {
+	t.Helper()
+
+	m := &Matrix{Versions: versions, Results: map[string]map[string]string{}, Errors: map[string]string{}}
+	for _, test := range tests {
+		m.Tests = append(m.Tests, test.Name)
+	}
+
+	for _, version := range versions {
+		t.Run(version, func(t *testing.T) {
+			i, err := matrixIG(version, opts)
+			if err != nil {
+				m.mu.Lock()
+				m.Errors[version] = err.Error()
+				m.mu.Unlock()
+				for _, test := range tests {
+					m.set(test.Name, version, MatrixError)
+				}
+				t.Fatal(err)
+			}
+			for _, test := range tests {
+				t.Run(test.Name, func(t *testing.T) {
+					defer func() {
+						switch {
+						case t.Failed():
+							m.set(test.Name, version, MatrixFail)
+						case t.Skipped():
+							m.set(test.Name, version, MatrixSkip)
+						default:
+							m.set(test.Name, version, MatrixPass)
+						}
+					}()
+					test.Run(t, i)
+				})
+			}
+		})
+	}
+
+	var b strings.Builder
+	m.WriteMarkdown(&b)
+	t.Logf("ig version matrix:\n%s", b.String())
+	return m
+}
+
+func matrixIG(version string, opts MatrixOptions) (*ig.IG, error) {
+	path, err := ig.Install(context.Background(), version, opts.InstallDir)
+	if err != nil {
+		return nil, err
+	}
+	i, err := ig.New(append([]ig.Option{ig.WithPath(path)}, opts.Options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("ig %s: %w", version, err)
+	}
+	return i, nil
+}
@@ -0,0 +1,58 @@
+package harness
+
+import (
+	"strings"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+// FromNonce returns a predicate matching the events carrying n in any of
+// their string fields, such as a comm, a file name, a DNS name or a URL, i.e.
+// the events caused by the workloads of the test n belongs to and not by
+// concurrent tests on the same runner.
+func FromNonce(n testutils.Nonce) func(ig.Event) bool {
+	return func(e ig.Event) bool {
+		return HasNonce(e.Fields, n)
+	}
+}
+
+// HasNonce reports whether any string field of event contains n.
+func HasNonce(event map[string]any, n testutils.Nonce) bool {
+	if n == "" {
+		return false
+	}
+	return containsString(event, string(n))
+}
+
+// FilterNonce returns the events of Run output, as decoded by DecodeEvents,
+// carrying n, ready for MatchEntries.
+func FilterNonce(events []map[string]any, n testutils.Nonce) []map[string]any {
+	var out []map[string]any
+	for _, e := range events {
+		if HasNonce(e, n) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func containsString(v any, s string) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.Contains(v, s)
+	case map[string]any:
+		for _, c := range v {
+			if containsString(c, s) {
+				return true
+			}
+		}
+	case []any:
+		for _, c := range v {
+			if containsString(c, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+//go:build !unix
+
+package harness
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// errInterruptUnsupported is returned when asked to interrupt a process,
+// which only unix supports.
+var errInterruptUnsupported = errors.New("interrupting processes is not supported on " + runtime.GOOS)
+
+// setProcessGroup is a no-op: process trees are killed by PID instead.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killedExit reports whether err may be the exit of a killed process. Killed
+// processes only report an exit code here, so any non-zero exit qualifies.
+func killedExit(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr)
+}
+
+// signalProcessGroup kills the process tree rooted at p. Only os.Kill is
+// supported. On Windows the whole tree is killed with taskkill, elsewhere
+// only p itself. A process that already exited is not an error.
+func signalProcessGroup(p *os.Process, sig os.Signal) error {
+	if sig != os.Kill {
+		if sig == os.Interrupt {
+			return errInterruptUnsupported
+		}
+		return errors.New("only os.Kill is supported on " + runtime.GOOS)
+	}
+
+	if runtime.GOOS == "windows" {
+		if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run(); err == nil {
+			return nil
+		}
+		// taskkill fails if the process is gone; fall back to make sure.
+	}
+	if err := p.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package harness
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// errInterruptUnsupported is never returned on unix.
+var errInterruptUnsupported = errors.New("interrupting processes is not supported")
+
+// setProcessGroup puts the process and everything it spawns in a process
+// group of its own, so signals can reach the whole tree.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killedExit reports whether err is the exit of a process killed by SIGKILL,
+// as Stop does.
+func killedExit(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	ws, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGKILL
+}
+
+// signalProcessGroup sends sig to the process group led by p. A group with
+// no process left is not an error.
+func signalProcessGroup(p *os.Process, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return errors.New("unsupported signal type")
+	}
+	// With Setpgid the process group ID is the PID of the process.
+	if err := syscall.Kill(-p.Pid, s); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
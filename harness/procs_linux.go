@@ -0,0 +1,79 @@
+//go:build linux
+
+package harness
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// childProcesses returns the descendants of the current process, as listed
+// in /proc.
+func childProcesses() ([]procInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[int]procInfo{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			// The process exited while listing.
+			continue
+		}
+		if p, ok := parseStat(pid, string(b)); ok {
+			all[pid] = p
+		}
+	}
+
+	self := os.Getpid()
+	var out []procInfo
+	for _, p := range all {
+		for ppid := p.PPID; ppid > 1; ppid = all[ppid].PPID {
+			if ppid == self {
+				out = append(out, p)
+				break
+			}
+			if _, ok := all[ppid]; !ok {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseStat parses /proc/<pid>/stat: "pid (comm) state ppid ...", where comm
+// may contain spaces and parentheses.
+func parseStat(pid int, stat string) (procInfo, bool) {
+	open, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return procInfo{}, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return procInfo{}, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procInfo{}, false
+	}
+	return procInfo{PID: pid, PPID: ppid, State: fields[0], Comm: stat[open+1 : end]}, true
+}
+
+// prSetChildSubreaper is PR_SET_CHILD_SUBREAPER of prctl(2).
+const prSetChildSubreaper = 36
+
+func setChildSubreaper() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		return fmt.Errorf("becoming child subreaper: %w", errno)
+	}
+	return nil
+}
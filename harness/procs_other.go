@@ -0,0 +1,17 @@
+//go:build !linux
+
+package harness
+
+import (
+	"errors"
+	"runtime"
+)
+
+// childProcesses needs /proc, so it isn't supported here.
+func childProcesses() ([]procInfo, error) {
+	return nil, errors.New("listing child processes is not supported on " + runtime.GOOS)
+}
+
+func setChildSubreaper() error {
+	return errors.New("adopting orphans is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,80 @@
+package harness
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexpMode controls how Command.ExpectedRegexps are matched.
+type RegexpMode int
+
+const (
+	// MatchAll requires every regexp to match, anywhere in the output.
+	MatchAll RegexpMode = iota
+	// MatchAllInOrder requires every regexp to match, each one after the
+	// end of the previous match.
+	MatchAllInOrder
+	// MatchAnyOf requires at least one regexp to match.
+	MatchAnyOf
+)
+
+func (m RegexpMode) String() string {
+	switch m {
+	case MatchAll:
+		return "all"
+	case MatchAllInOrder:
+		return "all in order"
+	case MatchAnyOf:
+		return "any of"
+	}
+	return fmt.Sprintf("RegexpMode(%d)", int(m))
+}
+
+// matchRegexps checks output against exprs according to mode.
+func matchRegexps(output string, exprs []string, mode RegexpMode) error {
+	res := make([]*regexp.Regexp, len(exprs))
+	for i, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("compiling expected regexp %d: %w", i, err)
+		}
+		res[i] = re
+	}
+
+	switch mode {
+	case MatchAll:
+		var missing []string
+		for _, re := range res {
+			if !re.MatchString(output) {
+				missing = append(missing, re.String())
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("output didn't match %d of %d expected regexps:\n  %s\n%s",
+				len(missing), len(res), strings.Join(missing, "\n  "), output)
+		}
+	case MatchAllInOrder:
+		off := 0
+		for i, re := range res {
+			loc := re.FindStringIndex(output[off:])
+			if loc == nil {
+				return fmt.Errorf("output didn't match expected regexp %d (%s) after the match of the previous ones at offset %d:\n%s",
+					i, re, off, output)
+			}
+			off += loc[1]
+		}
+	case MatchAnyOf:
+		for _, re := range res {
+			if re.MatchString(output) {
+				return nil
+			}
+		}
+		return fmt.Errorf("output didn't match any of the expected regexps:\n  %s\n%s",
+			strings.Join(exprs, "\n  "), output)
+	default:
+		return fmt.Errorf("unknown regexp mode %s", mode)
+	}
+
+	return nil
+}
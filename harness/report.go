@@ -0,0 +1,93 @@
+package harness
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+// StepResult is the outcome of one Command of a test.
+type StepResult struct {
+	// Test is the name of the test that ran the command.
+	Test string `json:"test"`
+	// Command is the name of the command.
+	Command string `json:"command"`
+	// Attempts is how many times the command was run.
+	Attempts int `json:"attempts"`
+	// Flaky reports a command that failed at least once before passing.
+	Flaky bool `json:"flaky,omitempty"`
+	// Failed reports a command that failed on its last attempt.
+	Failed   bool          `json:"failed,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// SuiteReport collects the results of the commands run by the tests of a
+// package, so flaky steps keep being tracked while CI stays green.
+type SuiteReport struct {
+	mu    sync.Mutex
+	steps []StepResult
+}
+
+// Report is the suite report every Command records its result in. Write it
+// out from TestMain once the tests ran.
+var Report = &SuiteReport{}
+
+func (r *SuiteReport) record(s StepResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.steps = append(r.steps, s)
+}
+
+// Steps returns every recorded result, in completion order.
+func (r *SuiteReport) Steps() []StepResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]StepResult(nil), r.steps...)
+}
+
+// Flaky returns the results of commands that only passed after a retry.
+func (r *SuiteReport) Flaky() []StepResult {
+	var out []StepResult
+	for _, s := range r.Steps() {
+		if s.Flaky {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// WriteJSON writes the report as JSON, with the random seed of the run so a
+// failing run can be reproduced, and the fingerprint of the host.
+func (r *SuiteReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Seed  int64              `json:"seed"`
+		Host  ig.HostFingerprint `json:"host"`
+		Steps []StepResult       `json:"steps"`
+	}{
+		Seed:  testutils.GetSeed(),
+		Host:  ig.Fingerprint(),
+		Steps: r.Steps(),
+	})
+}
+
+// WriteFile writes the report as JSON to path.
+func (r *SuiteReport) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
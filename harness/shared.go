@@ -0,0 +1,248 @@
+package harness
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+// SharedDirEnv overrides the directory where shared fixtures keep their
+// state, by default ig-shared-fixtures in the temporary directory. Test
+// binaries only share the fixtures of the same directory.
+const SharedDirEnv = "IG_SHARED_DIR"
+
+// SharedKeepEnv, set to a non-empty value, keeps shared fixtures once their
+// last user released them, so test packages running one after the other
+// reuse them too. The CI job then tears them down with Fixture.Destroy.
+const SharedKeepEnv = "IG_SHARED_KEEP"
+
+// Fixture is an expensive resource, such as a kind cluster, shared by the
+// test binaries of a repository instead of each provisioning its own: go
+// test runs every package in a process of its own. The first binary to
+// acquire the fixture sets it up, later ones, concurrent or not, reuse it,
+// and the last one to release it tears it down.
+//
+// The value returned by Setup is stored as JSON, so T must survive a round
+// trip through encoding/json.
+type Fixture[T any] struct {
+	// Name identifies the fixture across test binaries.
+	Name string
+	// Setup provisions the fixture. It runs with the fixture locked, other
+	// binaries waiting for it.
+	Setup func(ctx context.Context) (T, error)
+	// Teardown, if not nil, destroys the fixture.
+	Teardown func(ctx context.Context, v T) error
+}
+
+// sharedState is the state file of a fixture.
+type sharedState struct {
+	Value   json.RawMessage `json:"value"`
+	Created time.Time       `json:"created"`
+	// Holders are the PIDs of the processes using the fixture, once per
+	// Acquire.
+	Holders []int `json:"holders"`
+}
+
+func sharedDir() string {
+	if dir := os.Getenv(SharedDirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "ig-shared-fixtures")
+}
+
+func (f Fixture[T]) path(ext string) string {
+	return filepath.Join(sharedDir(), f.Name+ext)
+}
+
+// Acquire returns the fixture, setting it up if no test binary did yet.
+// Callers must call release once done with it. Holders that died without
+// releasing the fixture are forgotten.
+func (f Fixture[T]) Acquire(ctx context.Context) (v T, release func() error, err error) {
+	err = f.locked(ctx, func(st *sharedState) error {
+		if st.Value == nil {
+			sv, err := f.Setup(ctx)
+			if err != nil {
+				return fmt.Errorf("setting up fixture %s: %w", f.Name, err)
+			}
+			b, err := json.Marshal(sv)
+			if err != nil {
+				f.teardown(ctx, sv)
+				return fmt.Errorf("encoding fixture %s: %w", f.Name, err)
+			}
+			st.Value, st.Created = b, time.Now()
+		}
+		if err := json.Unmarshal(st.Value, &v); err != nil {
+			return fmt.Errorf("decoding fixture %s: %w", f.Name, err)
+		}
+		st.Holders = append(st.Holders, os.Getpid())
+		return nil
+	})
+	if err != nil {
+		return v, nil, err
+	}
+	return v, func() error { return f.release(context.Background()) }, nil
+}
+
+// Use acquires the fixture for the duration of the test, failing it if the
+// fixture can't be set up.
+func (f Fixture[T]) Use(t testing.TB) T {
+	t.Helper()
+
+	v, release, err := f.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := release(); err != nil {
+			t.Errorf("releasing fixture: %s", err)
+		}
+	})
+	return v
+}
+
+func (f Fixture[T]) release(ctx context.Context) error {
+	return f.locked(ctx, func(st *sharedState) error {
+		pid := os.Getpid()
+		for i, h := range st.Holders {
+			if h == pid {
+				st.Holders = append(st.Holders[:i], st.Holders[i+1:]...)
+				break
+			}
+		}
+		if len(st.Holders) > 0 || os.Getenv(SharedKeepEnv) != "" {
+			return nil
+		}
+		return f.destroyState(ctx, st)
+	})
+}
+
+// Destroy tears down the fixture whoever still holds it, e.g. in the last
+// step of a CI job run with IG_SHARED_KEEP.
+func (f Fixture[T]) Destroy(ctx context.Context) error {
+	return f.locked(ctx, func(st *sharedState) error {
+		return f.destroyState(ctx, st)
+	})
+}
+
+func (f Fixture[T]) destroyState(ctx context.Context, st *sharedState) error {
+	if st.Value == nil {
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(st.Value, &v); err != nil {
+		return fmt.Errorf("decoding fixture %s: %w", f.Name, err)
+	}
+	if err := f.teardown(ctx, v); err != nil {
+		return fmt.Errorf("tearing down fixture %s: %w", f.Name, err)
+	}
+	*st = sharedState{}
+	return nil
+}
+
+func (f Fixture[T]) teardown(ctx context.Context, v T) error {
+	if f.Teardown == nil {
+		return nil
+	}
+	return f.Teardown(ctx, v)
+}
+
+// locked calls fn with the state of the fixture, holding its lock file, and
+// saves the state fn leaves. An empty state removes the state file.
+func (f Fixture[T]) locked(ctx context.Context, fn func(*sharedState) error) error {
+	if err := os.MkdirAll(sharedDir(), 0o755); err != nil {
+		return fmt.Errorf("creating shared fixture directory: %w", err)
+	}
+	unlock, err := lockFile(ctx, f.path(".lock"))
+	if err != nil {
+		return fmt.Errorf("locking fixture %s: %w", f.Name, err)
+	}
+	defer unlock()
+
+	var st sharedState
+	b, err := os.ReadFile(f.path(".json"))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("reading fixture %s: %w", f.Name, err)
+	default:
+		if err := json.Unmarshal(b, &st); err != nil {
+			return fmt.Errorf("decoding fixture %s: %w", f.Name, err)
+		}
+	}
+	alive := st.Holders[:0]
+	for _, pid := range st.Holders {
+		if processAlive(pid) {
+			alive = append(alive, pid)
+		}
+	}
+	st.Holders = alive
+
+	fnErr := fn(&st)
+	if st.Value == nil {
+		if err := os.Remove(f.path(".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Join(fnErr, err)
+		}
+		return fnErr
+	}
+	return errors.Join(fnErr, writeShared(f.path(".json"), &st))
+}
+
+func writeShared(path string, st *sharedState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SharedCluster returns a fixture for a kind or minikube cluster created
+// with opts, named after name.
+func SharedCluster(name string, opts ...testutils.ClusterOption) Fixture[*testutils.Cluster] {
+	return Fixture[*testutils.Cluster]{
+		Name: "cluster-" + name,
+		Setup: func(ctx context.Context) (*testutils.Cluster, error) {
+			return testutils.CreateCluster(ctx, append([]testutils.ClusterOption{testutils.WithClusterName(name)}, opts...)...)
+		},
+		Teardown: func(ctx context.Context, c *testutils.Cluster) error {
+			return c.Delete(ctx)
+		},
+	}
+}
+
+// SharedPull returns a fixture pulling images once for every test binary.
+// Images are left in place when it is torn down.
+func SharedPull(i *ig.IG, images ...string) Fixture[[]string] {
+	sorted := append([]string(nil), images...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return Fixture[[]string]{
+		Name: "pull-" + hex.EncodeToString(sum[:8]),
+		Setup: func(ctx context.Context) ([]string, error) {
+			return sorted, i.PullAll(ctx, sorted, 0, nil)
+		},
+	}
+}
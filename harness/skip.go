@@ -0,0 +1,114 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// btfPath is where the kernel exposes its own BTF when built with
+// CONFIG_DEBUG_INFO_BTF.
+const btfPath = "/sys/kernel/btf/vmlinux"
+
+// SkipIfNotRoot skips the test unless it runs as root, which ig needs to load
+// eBPF programs.
+func SkipIfNotRoot(t testing.TB) {
+	t.Helper()
+
+	if os.Geteuid() != 0 {
+		t.Skip("skipping test: requires root")
+	}
+}
+
+// SkipIfNotLinux skips the test on other operating systems, for tests that
+// run gadgets locally rather than on a remote host or cluster.
+func SkipIfNotLinux(t testing.TB) {
+	t.Helper()
+
+	if runtime.GOOS != "linux" {
+		t.Skipf("skipping test: requires Linux, running %s", runtime.GOOS)
+	}
+}
+
+// SkipIfKernelOlderThan skips the test on kernels older than version, e.g.
+// "5.10", and on other operating systems than Linux.
+func SkipIfKernelOlderThan(t testing.TB, version string) {
+	t.Helper()
+	SkipIfNotLinux(t)
+
+	want, err := parseKernelVersion(version)
+	if err != nil {
+		t.Fatalf("invalid kernel version %q: %s", version, err)
+	}
+
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		t.Fatalf("reading kernel release: %s", err)
+	}
+	got, err := parseKernelVersion(string(release))
+	if err != nil {
+		t.Fatalf("parsing kernel release: %s", err)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			if got[i] < want[i] {
+				t.Skipf("skipping test: requires kernel %s or newer, running %s", version, strings.TrimSpace(string(release)))
+			}
+			return
+		}
+	}
+}
+
+// parseKernelVersion parses the numeric prefix of a kernel release such as
+// "5.15.0-91-generic" into major, minor and patch.
+func parseKernelVersion(s string) ([3]int, error) {
+	var v [3]int
+
+	s = strings.TrimSpace(s)
+	if i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, fmt.Errorf("expected major.minor[.patch], got %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, fmt.Errorf("invalid component %q: %w", p, err)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// SkipIfNoBTF skips the test if the kernel doesn't expose its BTF, which
+// gadgets need unless BTFHub-style BTF files are provided.
+func SkipIfNoBTF(t testing.TB) {
+	t.Helper()
+	SkipIfNotLinux(t)
+
+	if _, err := os.Stat(btfPath); err != nil {
+		t.Skipf("skipping test: kernel BTF not available at %s", btfPath)
+	}
+}
+
+// SkipIfIgOlderThan skips the test if the ig binary driven by i is older
+// than version, e.g. "v0.30.0".
+func SkipIfIgOlderThan(t testing.TB, i *ig.IG, version string) {
+	t.Helper()
+
+	want, err := ig.ParseVersion(version)
+	if err != nil {
+		t.Fatalf("invalid ig version: %s", err)
+	}
+	if got := i.Version(); !got.AtLeast(want) {
+		t.Skipf("skipping test: requires ig %s or newer, running %s", want, got)
+	}
+}
@@ -0,0 +1,144 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/testutils"
+)
+
+// ChaosAction is what a soak iteration does to a running gadget.
+type ChaosAction string
+
+const (
+	// ChaosStartStop stops the gadget gracefully.
+	ChaosStartStop ChaosAction = "start-stop"
+	// ChaosKill kills ig with SIGKILL, as a crash or the OOM killer would.
+	ChaosKill ChaosAction = "kill"
+	// ChaosRestartRuntime restarts the container runtime under the gadget,
+	// then stops it.
+	ChaosRestartRuntime ChaosAction = "restart-runtime"
+)
+
+// SoakScenario configures RunSoak.
+type SoakScenario struct {
+	IG     *ig.IG
+	Gadget ig.GadgetSpec
+	// Iterations is how many times the gadget is started, 10 if zero.
+	Iterations int
+	// Actions are cycled through, one per iteration. Defaults to
+	// ChaosStartStop and ChaosKill.
+	Actions []ChaosAction
+	// Runtime is restarted by ChaosRestartRuntime.
+	Runtime testutils.Runtime
+	// RunFor is how long the gadget runs before the action, 2s if zero.
+	RunFor time.Duration
+}
+
+// RunSoak repeatedly starts the gadget of sc and stops, kills or disturbs
+// it, with a subtest per iteration. Every iteration checks the session ends
+// in the expected state, its event stream is closed and no goroutine,
+// process or zombie outlives it (see LeakGrace), hardening the library for
+// long-running embedders.
+func RunSoak(t *testing.T, sc SoakScenario) {
+	t.Helper()
+
+	iterations := sc.Iterations
+	if iterations == 0 {
+		iterations = 10
+	}
+	actions := sc.Actions
+	if len(actions) == 0 {
+		actions = []ChaosAction{ChaosStartStop, ChaosKill}
+	}
+	runFor := sc.RunFor
+	if runFor == 0 {
+		runFor = 2 * time.Second
+	}
+
+	for i := 0; i < iterations; i++ {
+		action := actions[i%len(actions)]
+		ok := t.Run(fmt.Sprintf("%03d-%s", i, action), func(t *testing.T) {
+			snap := takeLeakSnapshot()
+			soakIteration(t, sc, action, runFor)
+			if leaks := snap.checkLeaks(); len(leaks) > 0 {
+				t.Fatalf("leaks after %s:\n%s", action, strings.Join(leaks, "\n"))
+			}
+		})
+		if !ok {
+			return
+		}
+	}
+}
+
+func soakIteration(t *testing.T, sc SoakScenario, action ChaosAction, runFor time.Duration) {
+	ctx := context.Background()
+
+	s, err := sc.IG.Start(ctx, sc.Gadget.Image, sc.Gadget.Flags...)
+	if err != nil {
+		t.Fatalf("starting gadget: %s", err)
+	}
+	events := 0
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range s.Events() {
+			events++
+		}
+	}()
+
+	select {
+	case <-s.Done():
+		t.Fatalf("gadget exited before the %s action: %v\n%s", action, s.Wait(), s.Stderr())
+	case <-time.After(runFor):
+	}
+
+	want := ig.StateStopped
+	switch action {
+	case ChaosStartStop:
+		if err := s.Stop(); err != nil {
+			t.Fatalf("stopping gadget: %s", err)
+		}
+	case ChaosKill:
+		p, err := os.FindProcess(s.PID())
+		if err == nil {
+			err = p.Kill()
+		}
+		if err != nil {
+			t.Fatalf("killing ig: %s", err)
+		}
+		if err := s.Wait(); err == nil {
+			t.Fatalf("killed gadget reported no error")
+		}
+		want = ig.StateFailed
+	case ChaosRestartRuntime:
+		if err := sc.Runtime.Restart(ctx); err != nil {
+			s.Stop()
+			t.Fatal(err)
+		}
+		// ig may or may not survive its runtime going away; either way the
+		// session must end cleanly.
+		if err := s.Stop(); err != nil {
+			t.Logf("gadget failed across the runtime restart: %s", err)
+			want = s.State()
+		}
+	default:
+		s.Stop()
+		t.Fatalf("unknown chaos action %q", action)
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(LeakGrace):
+		t.Fatalf("event stream not closed after %s", action)
+	}
+	if got := s.State(); got != want {
+		t.Fatalf("session ended in state %s, want %s", got, want)
+	}
+	t.Logf("run %s: %s after %d events", s.RunID(), action, events)
+}
@@ -0,0 +1,167 @@
+package ig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OCI annotations of gadget images interpreted by the library. The
+// org.opencontainers ones are the standard OCI annotations.
+const (
+	AnnotationTitle         = "org.opencontainers.image.title"
+	AnnotationDescription   = "org.opencontainers.image.description"
+	AnnotationVersion       = "org.opencontainers.image.version"
+	AnnotationSource        = "org.opencontainers.image.source"
+	AnnotationDocumentation = "org.opencontainers.image.documentation"
+	// AnnotationMinIGVersion is the oldest ig version the gadget supports,
+	// e.g. "v0.30.0".
+	AnnotationMinIGVersion = "io.inspektor-gadget.min-ig-version"
+	// AnnotationCategory classifies the gadget, e.g. "trace" or "profile".
+	AnnotationCategory = "io.inspektor-gadget.category"
+	// AnnotationRecommendedParams are the params the gadget is best run
+	// with, as a JSON object of flag names (without dashes) to values, e.g.
+	// {"ignore-failed": "false"}.
+	AnnotationRecommendedParams = "io.inspektor-gadget.recommended-params"
+)
+
+// ErrIGTooOld is returned for gadgets requiring a newer ig, see
+// AnnotationMinIGVersion.
+var ErrIGTooOld = errors.New("ig too old for gadget")
+
+// ImageAnnotations are the annotations of a gadget image.
+type ImageAnnotations struct {
+	// Raw holds every annotation, including those without a field.
+	Raw map[string]string
+
+	Title         string
+	Description   string
+	Version       string
+	Source        string
+	Documentation string
+	// MinIGVersion is zero if the image doesn't require a version.
+	MinIGVersion Version
+	Category     string
+	// RecommendedParams map flag names, without dashes, to values.
+	RecommendedParams map[string]string
+}
+
+// ParseAnnotations interprets the annotations of a gadget image.
+func ParseAnnotations(raw map[string]string) (ImageAnnotations, error) {
+	a := ImageAnnotations{
+		Raw:           raw,
+		Title:         raw[AnnotationTitle],
+		Description:   raw[AnnotationDescription],
+		Version:       raw[AnnotationVersion],
+		Source:        raw[AnnotationSource],
+		Documentation: raw[AnnotationDocumentation],
+		Category:      raw[AnnotationCategory],
+	}
+	if s := raw[AnnotationMinIGVersion]; s != "" {
+		v, err := ParseVersion(s)
+		if err != nil {
+			return a, fmt.Errorf("annotation %s: %w", AnnotationMinIGVersion, err)
+		}
+		a.MinIGVersion = v
+	}
+	if s := raw[AnnotationRecommendedParams]; s != "" {
+		if err := json.Unmarshal([]byte(s), &a.RecommendedParams); err != nil {
+			return a, fmt.Errorf("annotation %s: %w", AnnotationRecommendedParams, err)
+		}
+	}
+	return a, nil
+}
+
+// CheckVersion returns an error wrapping ErrIGTooOld if the image requires
+// a newer ig than v.
+func (a ImageAnnotations) CheckVersion(v Version) error {
+	if a.MinIGVersion != (Version{}) && !v.AtLeast(a.MinIGVersion) {
+		return fmt.Errorf("%w: ig %s, gadget requires %s", ErrIGTooOld, v, a.MinIGVersion)
+	}
+	return nil
+}
+
+// Apply returns flags with the recommended params the flags don't set
+// already appended, in name order.
+func (a ImageAnnotations) Apply(flags []string) []string {
+	names := make([]string, 0, len(a.RecommendedParams))
+	for name := range a.RecommendedParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := append([]string(nil), flags...)
+	for _, name := range names {
+		if !hasFlag(flags, name) {
+			out = append(out, "--"+name+"="+a.RecommendedParams[name])
+		}
+	}
+	return out
+}
+
+// hasFlag reports whether flags set the flag name, as "--name" or
+// "--name=value".
+func hasFlag(flags []string, name string) bool {
+	for _, f := range flags {
+		if f == "--"+name || strings.HasPrefix(f, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotations returns the OCI annotations of image, which must be in the
+// local store, as reported by ig image inspect.
+func (ig *IG) Annotations(ctx context.Context, image string) (ImageAnnotations, error) {
+	info, err := ig.InspectImage(ctx, image)
+	if info == nil {
+		return ImageAnnotations{}, err
+	}
+	return info.Annotations, err
+}
+
+// WithRecommendedParams makes runs and sessions apply the recommended params
+// of their image, unless the flags set them, and refuse images requiring a
+// newer ig. Images that can't be inspected, e.g. because they aren't pulled
+// yet, run as they are.
+func WithRecommendedParams() Option {
+	return func(ig *IG) {
+		ig.annotations = &annotationCache{images: map[string]ImageAnnotations{}}
+	}
+}
+
+// annotationCache caches the annotations of images for
+// WithRecommendedParams.
+type annotationCache struct {
+	mu     sync.Mutex
+	images map[string]ImageAnnotations
+}
+
+// recommend returns flags with the recommended params of image.
+func (ig *IG) recommend(ctx context.Context, image string, flags []string) ([]string, error) {
+	if ig.annotations == nil {
+		return flags, nil
+	}
+
+	ig.annotations.mu.Lock()
+	a, ok := ig.annotations.images[image]
+	ig.annotations.mu.Unlock()
+	if !ok {
+		var err error
+		if a, err = ig.Annotations(ctx, image); err != nil {
+			return flags, nil
+		}
+		ig.annotations.mu.Lock()
+		ig.annotations.images[image] = a
+		ig.annotations.mu.Unlock()
+	}
+
+	if err := a.CheckVersion(ig.Version()); err != nil {
+		return nil, fmt.Errorf("running %s: %w", image, err)
+	}
+	return a.Apply(flags), nil
+}
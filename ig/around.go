@@ -0,0 +1,52 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RunAroundSettle is how long RunAround keeps tracing after the workload
+// returned, for the gadget to report its last events.
+var RunAroundSettle = time.Second
+
+// RunAround covers the most common shape of integration tests in a single
+// call: it starts the gadget, waits for it to be ready, runs workload, lets
+// the gadget settle and stops it, returning every event it printed. The
+// events are returned along with any error, of the gadget or the workload.
+func (ig *IG) RunAround(ctx context.Context, image string, workload func(ctx context.Context) error, flags ...string) ([]Event, error) {
+	s, err := ig.Start(ctx, image, flags...)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for ev := range s.Events() {
+			events = append(events, ev)
+		}
+	}()
+
+	if err := s.WaitReady(ctx); err != nil {
+		stopErr := s.Stop()
+		<-collected
+		return events, errors.Join(err, stopErr)
+	}
+
+	workErr := workload(ctx)
+	if workErr != nil {
+		workErr = fmt.Errorf("running workload: %w", workErr)
+	} else {
+		select {
+		case <-time.After(RunAroundSettle):
+		case <-ctx.Done():
+		case <-s.Done():
+		}
+	}
+
+	stopErr := s.Stop()
+	<-collected
+	return events, errors.Join(workErr, stopErr)
+}
@@ -0,0 +1,113 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OperationKind is a privileged operation of the library.
+type OperationKind string
+
+const (
+	// OpRun runs a gadget, with Run or any of the Start functions.
+	OpRun OperationKind = "run"
+	// OpDetach runs a gadget detached, as a run with "--detach".
+	OpDetach OperationKind = "detach"
+	// OpDeleteInstance deletes a detached instance.
+	OpDeleteInstance OperationKind = "delete-instance"
+	// OpPush pushes an image to a registry.
+	OpPush OperationKind = "push"
+	// OpRemoveImage removes an image from the local store.
+	OpRemoveImage OperationKind = "remove-image"
+	// OpTag tags an image of the local store; the Image of the operation is
+	// the new reference.
+	OpTag OperationKind = "tag"
+	// OpBuild builds an image, or updates the metadata of a gadget project.
+	OpBuild OperationKind = "build"
+)
+
+// Operation describes a privileged operation for an Authorizer.
+type Operation struct {
+	Kind OperationKind
+	// Image is the gadget image, for runs and image operations.
+	Image string
+	// Instance is the ID or name of the instance, for OpDeleteInstance.
+	Instance string
+	// Flags are the flags passed to ig.
+	Flags []string
+	// Token is the token of the context of the operation, see WithToken.
+	Token string
+	// RunID identifies the operation, to correlate audit records with its
+	// errors and artifacts.
+	RunID string
+}
+
+// An Authorizer decides whether an operation may proceed, e.g. from the
+// roles granted to the token of the operation, so services embedding the
+// library for several users can enforce RBAC on gadget usage.
+type Authorizer interface {
+	// Authorize returns nil to allow op, an error to deny it.
+	Authorize(ctx context.Context, op Operation) error
+}
+
+// AuthorizerFunc adapts a function to an Authorizer.
+type AuthorizerFunc func(ctx context.Context, op Operation) error
+
+// Authorize calls f.
+func (f AuthorizerFunc) Authorize(ctx context.Context, op Operation) error {
+	return f(ctx, op)
+}
+
+// ErrUnauthorized wraps the errors of Authorizers denying operations.
+var ErrUnauthorized = errors.New("operation not authorized")
+
+// WithAuthorizer consults a before every privileged operation.
+func WithAuthorizer(a Authorizer) Option {
+	return func(ig *IG) {
+		ig.authorizer = a
+	}
+}
+
+type tokenKey struct{}
+
+// WithToken returns a context carrying the token of the user on whose
+// behalf operations run, passed to the Authorizer in Operation.Token.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext returns the token set by WithToken.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok
+}
+
+// authorize refuses mutating operations of read-only IGs, then consults the
+// authorizer of ig, if any, about op.
+func (ig *IG) authorize(ctx context.Context, op Operation) error {
+	if ig.readOnly && op.Kind.Mutating() {
+		return fmt.Errorf("%w: %s not allowed", ErrReadOnly, op.Kind)
+	}
+	if ig.authorizer == nil {
+		return nil
+	}
+	op.Token, _ = TokenFromContext(ctx)
+	op.RunID, _ = RunIDFromContext(ctx)
+	if err := ig.authorizer.Authorize(ctx, op); err != nil {
+		return fmt.Errorf("%w: %s (run %s): %w", ErrUnauthorized, op.Kind, op.RunID, err)
+	}
+	return nil
+}
+
+// authorizeRun authorizes running image with flags, detached or not.
+func (ig *IG) authorizeRun(ctx context.Context, image string, flags []string) error {
+	kind := OpRun
+	for _, f := range flags {
+		if f == "--detach" || (strings.HasPrefix(f, "--detach=") && f != "--detach=false") {
+			kind = OpDetach
+		}
+	}
+	return ig.authorize(ctx, Operation{Kind: kind, Image: image, Flags: flags})
+}
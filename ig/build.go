@@ -0,0 +1,109 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Defaults of ig image build for gadget projects.
+const (
+	DefaultBuildFile    = "build.yaml"
+	DefaultMetadataFile = "gadget.yaml"
+)
+
+// BuildOptions configures Build.
+type BuildOptions struct {
+	// File is the build file, relative to the project directory.
+	// Defaults to DefaultBuildFile.
+	File string
+	// Tag names the built image.
+	Tag string
+	// UpdateMetadata creates the metadata file of the gadget from its eBPF
+	// objects, or adds what they define and it lacks.
+	UpdateMetadata bool
+	// ValidateMetadata fails the build if the metadata file doesn't match
+	// the eBPF objects.
+	ValidateMetadata bool
+	// Flags are extra ig image build flags, such as "--builder-image".
+	Flags []string
+}
+
+// Build builds the gadget project in dir with ig image build.
+func (ig *IG) Build(ctx context.Context, dir string, opts BuildOptions) error {
+	ctx, _, err := ensureRunID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ig.authorize(ctx, Operation{Kind: OpBuild, Image: opts.Tag, Flags: opts.Flags}); err != nil {
+		return err
+	}
+	args := []string{"image", "build"}
+	if opts.File != "" {
+		args = append(args, "--file", opts.File)
+	}
+	if opts.Tag != "" {
+		args = append(args, "--tag", opts.Tag)
+	}
+	if opts.UpdateMetadata {
+		args = append(args, "--update-metadata")
+	}
+	if opts.ValidateMetadata {
+		args = append(args, "--validate-metadata")
+	}
+	args = append(append(args, opts.Flags...), dir)
+	if _, err := ig.exec(ctx, args...); err != nil {
+		return fmt.Errorf("building %s: %w", dir, err)
+	}
+	return nil
+}
+
+// UpdateMetadata builds the gadget project in dir with UpdateMetadata set,
+// creating or refreshing its metadata file, and returns the path of that
+// file, for build tools of gadget authors.
+func (ig *IG) UpdateMetadata(ctx context.Context, dir string, opts BuildOptions) (string, error) {
+	path, err := MetadataPath(dir, opts.File)
+	if err != nil {
+		return "", err
+	}
+	opts.UpdateMetadata = true
+	if err := ig.Build(ctx, dir, opts); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("building %s: no metadata generated: %w", dir, err)
+	}
+	return path, nil
+}
+
+var buildMetadataRe = regexp.MustCompile(`(?m)^metadata:[ \t]*["']?([^"'#\s]+)`)
+
+// MetadataPath returns the path of the metadata file of the gadget project
+// in dir, as named by its build file (DefaultBuildFile if file is empty),
+// DefaultMetadataFile if the build file doesn't name one or doesn't exist.
+func MetadataPath(dir, file string) (string, error) {
+	if file == "" {
+		file = DefaultBuildFile
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	name := DefaultMetadataFile
+	b, err := os.ReadFile(file)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return "", fmt.Errorf("reading build file: %w", err)
+	default:
+		if m := buildMetadataRe.FindSubmatch(b); m != nil {
+			name = string(m[1])
+		}
+	}
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	return filepath.Join(dir, name), nil
+}
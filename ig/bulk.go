@@ -0,0 +1,63 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// RemoveOptions configures RemoveAll.
+type RemoveOptions struct {
+	// Filter selects the images to remove, by reference, e.g.
+	// "ghcr.io/inspektor-gadget/gadget/trace_exec:latest". Every image if
+	// nil.
+	Filter func(image string) bool
+	// DryRun only reports the images that would be removed.
+	DryRun bool
+}
+
+// RemoveResult is the outcome of removing one image of RemoveAll.
+type RemoveResult struct {
+	Image string
+	// Removed is false for dry runs and failures.
+	Removed bool
+	Err     error
+}
+
+// RemoveAll removes the local images selected by opts, one at a time as
+// Remove does, and reports the outcome for each, in the order ig lists them.
+// The error joins the failures.
+func (ig *IG) RemoveAll(ctx context.Context, opts RemoveOptions) ([]RemoveResult, error) {
+	images, err := ig.Images(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []RemoveResult
+	var errs []error
+	for _, img := range images {
+		image := img.Ref()
+		if opts.Filter != nil && !opts.Filter(image) {
+			continue
+		}
+		r := RemoveResult{Image: image}
+		if !opts.DryRun {
+			r.Err = ig.Remove(ctx, image)
+			r.Removed = r.Err == nil
+			errs = append(errs, r.Err)
+		}
+		results = append(results, r)
+	}
+	return results, errors.Join(errs...)
+}
+
+// RemoveByPrefix removes the local images whose reference starts with
+// prefix, e.g. "localhost:5000/", as RemoveAll does. opts.Filter, if set,
+// further restricts them.
+func (ig *IG) RemoveByPrefix(ctx context.Context, prefix string, opts RemoveOptions) ([]RemoveResult, error) {
+	filter := opts.Filter
+	opts.Filter = func(image string) bool {
+		return strings.HasPrefix(image, prefix) && (filter == nil || filter(image))
+	}
+	return ig.RemoveAll(ctx, opts)
+}
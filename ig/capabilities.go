@@ -0,0 +1,186 @@
+package ig
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Flag is a command-line flag reported by ig's help.
+type Flag struct {
+	// Name is the long name, without dashes.
+	Name string
+	// Shorthand is the one-letter name, without dash, if any.
+	Shorthand string
+	// Type is the value type shown by the help (e.g. "string", "int"),
+	// empty for boolean flags.
+	Type string
+	// Usage is the flag description.
+	Usage string
+}
+
+// Capabilities describes what an ig binary supports, as introspected from
+// its help output, so callers can feature-detect instead of pinning
+// versions.
+type Capabilities struct {
+	// Version of the introspected binary.
+	Version Version
+	// Commands maps command paths relative to ig ("" for ig itself, "run",
+	// "image pull", ...) to the flags they accept, global flags included.
+	Commands map[string][]Flag
+	// Operators lists the operators whose flags ig run accepts.
+	Operators []string
+}
+
+// knownOperatorFlags maps operators to a flag of ig run only present when the
+// operator is compiled in.
+var knownOperatorFlags = map[string]string{
+	"filter":       "filter",
+	"sort":         "sort",
+	"limiter":      "max-entries",
+	"fields":       "fields",
+	"ebpf":         "map-fetch-interval",
+	"localmanager": "runtimes",
+	"otel-metrics": "otel-metrics-name",
+	"wasm":         "wasm-config",
+	"oci":          "verify-image",
+}
+
+// HasCommand reports whether path ("run", "image inspect", ...) is a command
+// of ig.
+func (c *Capabilities) HasCommand(path string) bool {
+	_, ok := c.Commands[path]
+	return ok
+}
+
+// HasFlag reports whether command accepts flag, given by its long name with
+// or without dashes.
+func (c *Capabilities) HasFlag(command, flag string) bool {
+	flag = strings.TrimLeft(flag, "-")
+	for _, f := range c.Commands[command] {
+		if f.Name == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOperator reports whether ig run accepts the flags of operator.
+func (c *Capabilities) HasOperator(operator string) bool {
+	for _, o := range c.Operators {
+		if o == operator {
+			return true
+		}
+	}
+	return false
+}
+
+type capabilitiesKey struct {
+	path    string
+	version Version
+}
+
+var (
+	capabilitiesMu    sync.Mutex
+	capabilitiesCache = map[capabilitiesKey]*Capabilities{}
+)
+
+// Capabilities introspects the help of ig and its subcommands, two levels
+// deep. The result is cached per binary path and version, so only the first
+// call pays for the handful of ig invocations needed.
+func (ig *IG) Capabilities(ctx context.Context) (*Capabilities, error) {
+	key := capabilitiesKey{path: ig.path, version: ig.Version()}
+
+	capabilitiesMu.Lock()
+	c, ok := capabilitiesCache[key]
+	capabilitiesMu.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	c = &Capabilities{
+		Version:  ig.Version(),
+		Commands: map[string][]Flag{},
+	}
+	if err := ig.introspect(ctx, c, nil, 2); err != nil {
+		return nil, err
+	}
+
+	for op, flag := range knownOperatorFlags {
+		if c.HasFlag("run", flag) {
+			c.Operators = append(c.Operators, op)
+		}
+	}
+	sort.Strings(c.Operators)
+
+	capabilitiesMu.Lock()
+	capabilitiesCache[key] = c
+	capabilitiesMu.Unlock()
+
+	return c, nil
+}
+
+func (ig *IG) introspect(ctx context.Context, c *Capabilities, path []string, depth int) error {
+	out, err := ig.exec(ctx, append(path, "--help")...)
+	if err != nil {
+		return fmt.Errorf("getting help of %q: %w", strings.Join(path, " "), err)
+	}
+
+	subcommands, flags := parseHelp(out.stdout)
+	c.Commands[strings.Join(path, " ")] = flags
+
+	if depth == 0 {
+		return nil
+	}
+	for _, sub := range subcommands {
+		if sub == "help" || sub == "completion" {
+			continue
+		}
+		if err := ig.introspect(ctx, c, append(append([]string(nil), path...), sub), depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagRe matches the flag lines of cobra help, e.g.
+// "  -o, --output string   Output mode" or "      --host   Show data from".
+var flagRe = regexp.MustCompile(`^\s+(?:-(\w), )?--([\w.-]+)(?: ([\w\[\]]+))?\s{2,}(.*)$`)
+
+// parseHelp extracts the subcommands and flags from cobra-generated help.
+func parseHelp(help string) (subcommands []string, flags []Flag) {
+	section := ""
+
+	s := bufio.NewScanner(strings.NewReader(help))
+	for s.Scan() {
+		line := s.Text()
+		if line != "" && !strings.HasPrefix(line, " ") {
+			section = strings.TrimSuffix(strings.TrimSpace(line), ":")
+			continue
+		}
+
+		switch {
+		case section == "Available Commands" || strings.HasSuffix(section, " Commands"):
+			if fields := strings.Fields(line); len(fields) > 0 {
+				subcommands = append(subcommands, fields[0])
+			}
+		case strings.HasSuffix(section, "Flags"):
+			m := flagRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			flags = append(flags, Flag{
+				Shorthand: m[1],
+				Name:      m[2],
+				Type:      m[3],
+				Usage:     strings.TrimSpace(m[4]),
+			})
+		}
+	}
+
+	return subcommands, flags
+}
@@ -0,0 +1,67 @@
+package ig
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoIG is returned by the From helpers for contexts without an IG.
+var ErrNoIG = errors.New("no IG in context")
+
+type igKey struct{}
+
+// NewContext returns a context carrying i, for the From helpers, so deep
+// call stacks of test suites don't have to pass the IG around.
+func NewContext(ctx context.Context, i *IG) context.Context {
+	return context.WithValue(ctx, igKey{}, i)
+}
+
+// FromContext returns the IG set by NewContext.
+func FromContext(ctx context.Context) (*IG, bool) {
+	i, ok := ctx.Value(igKey{}).(*IG)
+	return i, ok && i != nil
+}
+
+func fromContext(ctx context.Context) (*IG, error) {
+	i, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrNoIG
+	}
+	return i, nil
+}
+
+// RunFrom is Run with the IG of ctx.
+func RunFrom(ctx context.Context, image string, flags ...string) (*RunResult, error) {
+	i, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return i.Run(ctx, image, flags...)
+}
+
+// StartFrom is Start with the IG of ctx.
+func StartFrom(ctx context.Context, image string, flags ...string) (*GadgetSession, error) {
+	i, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return i.Start(ctx, image, flags...)
+}
+
+// PullFrom is Pull with the IG of ctx.
+func PullFrom(ctx context.Context, image string, flags ...string) error {
+	i, err := fromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return i.Pull(ctx, image, flags...)
+}
+
+// RunAroundFrom is RunAround with the IG of ctx.
+func RunAroundFrom(ctx context.Context, image string, workload func(ctx context.Context) error, flags ...string) ([]Event, error) {
+	i, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return i.RunAround(ctx, image, workload, flags...)
+}
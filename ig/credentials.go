@@ -0,0 +1,223 @@
+package ig
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Credential authenticates to a registry, with a user name and password or
+// token, or an identity token.
+type Credential struct {
+	Username string
+	Password string
+	// IdentityToken is an OAuth refresh token, as stored by docker login
+	// for some registries, used instead of Username and Password.
+	IdentityToken string
+}
+
+// CredentialSource resolves the credential of registries, by host name,
+// such as "ghcr.io" or "123456789012.dkr.ecr.eu-west-1.amazonaws.com".
+type CredentialSource interface {
+	// Credential returns the credential of registry, false if the source
+	// has none.
+	Credential(ctx context.Context, registry string) (Credential, bool, error)
+}
+
+// StaticCredentials are credentials given by registry host.
+type StaticCredentials map[string]Credential
+
+// Credential implements CredentialSource.
+func (c StaticCredentials) Credential(_ context.Context, registry string) (Credential, bool, error) {
+	for _, host := range registryAliases(registry) {
+		if cred, ok := c[host]; ok {
+			return cred, true, nil
+		}
+	}
+	return Credential{}, false, nil
+}
+
+// CredentialHelper is a docker credential helper, the program
+// docker-credential-<name> (e.g. "ecr-login", "pass", "osxkeychain") in
+// PATH.
+type CredentialHelper string
+
+// Credential implements CredentialSource, running the helper.
+func (h CredentialHelper) Credential(ctx context.Context, registry string) (Credential, bool, error) {
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker-credential-"+string(h), "get")
+	cmd.Stdin = strings.NewReader(registry)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Helpers report missing credentials on stdout or stderr, with a
+		// failure.
+		msg := strings.TrimSpace(out.String() + stderr.String())
+		if strings.Contains(msg, "credentials not found") {
+			return Credential{}, false, nil
+		}
+		if msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return Credential{}, false, fmt.Errorf("credential helper %s: %w", h, err)
+	}
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return Credential{}, false, fmt.Errorf("credential helper %s: decoding: %w", h, err)
+	}
+	// Helpers return identity tokens with this user name.
+	if resp.Username == "<token>" {
+		return Credential{IdentityToken: resp.Secret}, true, nil
+	}
+	return Credential{Username: resp.Username, Password: resp.Secret}, true, nil
+}
+
+// DockerConfig is a docker config file, such as ~/.docker/config.json,
+// resolving registries through its credHelpers, then its auths, then its
+// credsStore, as docker does.
+type DockerConfig string
+
+// DefaultDockerConfig returns the docker config file of the user, from
+// DOCKER_CONFIG or ~/.docker.
+func DefaultDockerConfig() DockerConfig {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".docker")
+	}
+	return DockerConfig(filepath.Join(dir, "config.json"))
+}
+
+type dockerAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// Credential implements CredentialSource.
+func (c DockerConfig) Credential(ctx context.Context, registry string) (Credential, bool, error) {
+	b, err := os.ReadFile(string(c))
+	if errors.Is(err, os.ErrNotExist) {
+		return Credential{}, false, nil
+	}
+	if err != nil {
+		return Credential{}, false, err
+	}
+	var cfg struct {
+		Auths       map[string]dockerAuth `json:"auths"`
+		CredHelpers map[string]string     `json:"credHelpers"`
+		CredsStore  string                `json:"credsStore"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return Credential{}, false, fmt.Errorf("decoding %s: %w", c, err)
+	}
+
+	hosts := registryAliases(registry)
+	for _, host := range hosts {
+		if h, ok := cfg.CredHelpers[host]; ok {
+			return CredentialHelper(h).Credential(ctx, host)
+		}
+	}
+	for _, host := range hosts {
+		a, ok := cfg.Auths[host]
+		if !ok || (a.Auth == "" && a.IdentityToken == "") {
+			continue
+		}
+		if a.IdentityToken != "" {
+			return Credential{IdentityToken: a.IdentityToken}, true, nil
+		}
+		dec, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return Credential{}, false, fmt.Errorf("decoding auth of %s in %s: %w", host, c, err)
+		}
+		user, pass, _ := strings.Cut(string(dec), ":")
+		return Credential{Username: user, Password: pass}, true, nil
+	}
+	if cfg.CredsStore != "" {
+		return CredentialHelper(cfg.CredsStore).Credential(ctx, hosts[0])
+	}
+	return Credential{}, false, nil
+}
+
+// registryAliases returns the host names registry is known by in
+// credential stores, Docker Hub having several.
+func registryAliases(registry string) []string {
+	switch registry {
+	case "registry-1.docker.io", "docker.io", "index.docker.io":
+		return []string{"https://index.docker.io/v1/", "docker.io", "index.docker.io", "registry-1.docker.io"}
+	}
+	return []string{registry, "https://" + registry}
+}
+
+// WithCredentials authenticates image operations (pulls, pushes and runs
+// pulling their image) to the registry of their image with the first of
+// sources having a credential for it, e.g. StaticCredentials for a private
+// Harbor, then CredentialHelper("ecr-login"), then DefaultDockerConfig().
+// Credentials are passed to ig in a temporary --authfile holding only the
+// registry of the operation, unless its flags already set one.
+func WithCredentials(sources ...CredentialSource) Option {
+	return func(ig *IG) {
+		ig.credentials = append(ig.credentials, sources...)
+	}
+}
+
+// credential returns the credential of the registry of image, nil if there
+// is none.
+func (ig *IG) credential(ctx context.Context, image string) (*Credential, error) {
+	registry := parseReference(image).registry
+	for _, src := range ig.credentials {
+		cred, ok, err := src.Credential(ctx, registry)
+		if err != nil {
+			return nil, fmt.Errorf("resolving credentials of %s: %w", registry, err)
+		}
+		if ok {
+			return &cred, nil
+		}
+	}
+	return nil, nil
+}
+
+// withAuth returns flags with an --authfile for the registry of image, and
+// a function removing it. flags are returned as is without credentials.
+func (ig *IG) withAuth(ctx context.Context, image string, flags []string) ([]string, func(), error) {
+	if len(ig.credentials) == 0 || hasFlag(flags, "authfile") {
+		return flags, func() {}, nil
+	}
+	cred, err := ig.credential(ctx, image)
+	if err != nil || cred == nil {
+		return flags, func() {}, err
+	}
+
+	a := dockerAuth{IdentityToken: cred.IdentityToken}
+	if a.IdentityToken == "" {
+		a.Auth = base64.StdEncoding.EncodeToString([]byte(cred.Username + ":" + cred.Password))
+	}
+	auths := map[string]dockerAuth{}
+	for _, host := range registryAliases(parseReference(image).registry) {
+		auths[host] = a
+	}
+	b, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.CreateTemp("", "ig-auth-*.json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("writing authfile: %w", err)
+	}
+	_, err = f.Write(b)
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(f.Name())
+		return nil, nil, fmt.Errorf("writing authfile: %w", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	return append(flags[:len(flags):len(flags)], "--authfile", f.Name()), cleanup, nil
+}
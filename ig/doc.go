@@ -0,0 +1,12 @@
+// Package ig drives the Inspektor Gadget ig binary from Go: managing gadget
+// images and running gadgets, with their output captured for the caller.
+//
+// An IG is created once with New, which locates the binary and probes its
+// version, and is safe for concurrent use. Its methods take a context:
+// once it is done, ig and every process it started are killed.
+// It can travel in a context, with NewContext, to code calling the
+// package-level From helpers such as RunFrom.
+//
+// The API of package ig may change between releases; package ig/v1 offers
+// a stable subset, with a compatibility promise.
+package ig
@@ -0,0 +1,82 @@
+package ig
+
+import (
+	"errors"
+	"regexp"
+)
+
+// Common failures of ig, matched with errors.Is against the errors returned
+// by this package, e.g. errors.Is(err, ig.ErrImageNotFound). Hint returns
+// what the user can do about them.
+var (
+	ErrVerificationFailed = errors.New("image verification failed")
+	ErrImageNotFound      = errors.New("image not found")
+	ErrMissingBTF         = errors.New("kernel BTF information missing")
+	ErrKernelTooOld       = errors.New("kernel too old")
+	ErrPermissionDenied   = errors.New("permission denied")
+)
+
+type failure struct {
+	err  error
+	re   *regexp.Regexp
+	hint string
+}
+
+// failures are tried in order against the stderr of ig; more specific
+// patterns come first.
+var failures = []failure{
+	{
+		err:  ErrVerificationFailed,
+		re:   regexp.MustCompile(`(?i)verif\w* .*(fail|signature)|no signatures? found|invalid signature`),
+		hint: "the image isn't signed by a trusted key: sign it, add its public key with --public-keys, or pass --verify-image=false for untrusted images",
+	},
+	{
+		err:  ErrImageNotFound,
+		re:   regexp.MustCompile(`(?i)manifest unknown|name unknown|failed to resolve|image .*not (found|exist)`),
+		hint: "check the image name and tag, and that the registry is reachable; pull it first with ig image pull if running with --pull=never",
+	},
+	{
+		err:  ErrMissingBTF,
+		re:   regexp.MustCompile(`(?i)btf.*(not found|no such file|missing|not supported)|/sys/kernel/btf/vmlinux`),
+		hint: "the kernel doesn't expose BTF: use a kernel built with CONFIG_DEBUG_INFO_BTF=y, or provide BTF for it (e.g. from BTFHub)",
+	},
+	{
+		err:  ErrKernelTooOld,
+		re:   regexp.MustCompile(`(?i)kernel (version )?(is )?too old|requires (a )?kernel|not supported by (the|this) kernel|unknown func bpf_|program of this type isn't supported`),
+		hint: "the gadget uses eBPF features this kernel lacks: upgrade the kernel or use a gadget version supporting it",
+	},
+	{
+		err:  ErrPermissionDenied,
+		re:   regexp.MustCompile(`(?i)permission denied|operation not permitted|must be (run as )?root`),
+		hint: "ig needs root, or the CAP_SYS_ADMIN, CAP_BPF and CAP_PERFMON capabilities, and may be blocked by seccomp or LSM policies",
+	},
+}
+
+func (e *CommandError) classify() *failure {
+	for i := range failures {
+		if failures[i].re.MatchString(e.Stderr) {
+			return &failures[i]
+		}
+	}
+	return nil
+}
+
+// Is reports whether the failure of ig is target, one of the Err variables
+// of this package.
+func (e *CommandError) Is(target error) bool {
+	f := e.classify()
+	return f != nil && f.err == target
+}
+
+// Hint returns a remediation hint for err if it is a recognized ig failure,
+// or "" otherwise.
+func Hint(err error) string {
+	var cerr *CommandError
+	if !errors.As(err, &cerr) {
+		return ""
+	}
+	if f := cerr.classify(); f != nil {
+		return f.hint
+	}
+	return ""
+}
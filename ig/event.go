@@ -0,0 +1,93 @@
+package ig
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Event is one record emitted by a running gadget.
+type Event struct {
+	// Gadget is the image of the gadget that emitted the event, as passed
+	// to Start.
+	Gadget string
+	// Raw is the line printed by ig.
+	Raw string
+	// Fields is the decoded JSON event. It is nil for lines that aren't
+	// JSON objects.
+	Fields map[string]any
+	// Received is when the library read the event.
+	Received time.Time
+	// Timestamp is when the gadget saw the event, parsed from its
+	// "timestamp" field. It is zero if the event has none.
+	Timestamp time.Time
+	// Seq numbers events in the order the library read them, across every
+	// session of the process, so events of different gadgets can be ordered
+	// even when their timestamps tie.
+	Seq uint64
+}
+
+var eventSeq atomic.Uint64
+
+func nextSeq() uint64 {
+	return eventSeq.Add(1)
+}
+
+// Time returns Timestamp if the event has one, and Received otherwise.
+func (e Event) Time() time.Time {
+	if !e.Timestamp.IsZero() {
+		return e.Timestamp
+	}
+	return e.Received
+}
+
+// parseTimestamp parses the timestamp of an event, either RFC 3339 as
+// printed by recent ig versions or nanoseconds since the epoch as printed by
+// older ones.
+func parseTimestamp(v any) (time.Time, bool) {
+	switch ts := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		return t, err == nil
+	case float64:
+		if ts <= 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(0, int64(ts)), true
+	}
+	return time.Time{}, false
+}
+
+// Field returns the value at the dot-separated path in the event, e.g.
+// "proc.pid" or "k8s.namespace".
+func (e Event) Field(path string) (any, bool) {
+	var cur any = e.Fields
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Number converts a numeric field value to a float64, whether decoded from
+// JSON or set from Go, e.g. an int in an expectation, so values compare
+// alike whatever their type. Strings aren't numbers.
+func Number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
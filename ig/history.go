@@ -0,0 +1,209 @@
+package ig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHistorySize is how many runs a History keeps if its Size is zero.
+const DefaultHistorySize = 100
+
+// History is an on-disk record of recent runs, one JSON file per run, so a
+// CLI or service can show the recent gadget runs of a node without external
+// storage. Histories can be read without an IG, by another process.
+type History struct {
+	// Dir holds the records. DefaultHistoryDir if empty.
+	Dir string
+	// Size is how many records are kept, the oldest being removed first.
+	// DefaultHistorySize if zero.
+	Size int
+
+	mu sync.Mutex
+}
+
+// DefaultHistoryDir returns the directory of histories without Dir, in the
+// user cache directory.
+func DefaultHistoryDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ig-testing-framework", "history")
+}
+
+// WithHistory records every Run and session in h.
+func WithHistory(h *History) Option {
+	return func(ig *IG) {
+		ig.history = h
+	}
+}
+
+// RunRecord describes a past run.
+type RunRecord struct {
+	RunID string   `json:"runId"`
+	Image string   `json:"image"`
+	Flags []string `json:"flags"`
+	// Digest is the digest of the image, if known (see IG.ImageDigest).
+	Digest    string    `json:"digest,omitempty"`
+	IGVersion string    `json:"igVersion"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	// Events counts the events of sessions; runs don't decode their
+	// output.
+	Events int `json:"events"`
+	// OutputBytes counts the bytes printed on stdout.
+	OutputBytes int64 `json:"outputBytes"`
+	// Error is the error the run ended with, if any.
+	Error string `json:"error,omitempty"`
+	// Artifacts are the files the run left: its working directory, if kept,
+	// and its transcript.
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// Duration returns how long the run took.
+func (r RunRecord) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+func (h *History) dir() string {
+	if h.Dir != "" {
+		return h.Dir
+	}
+	return DefaultHistoryDir()
+}
+
+func (h *History) fileName(r RunRecord) string {
+	return r.Started.UTC().Format("20060102T150405.000000000") + "-" + r.RunID + ".json"
+}
+
+// Record adds r to the history, removing the oldest records past Size.
+func (h *History) Record(r RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	dir := h.dir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, h.fileName(r)), b, 0o644); err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+
+	size := h.Size
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	names, err := h.names()
+	if err != nil {
+		return err
+	}
+	for len(names) > size {
+		os.Remove(filepath.Join(dir, names[0]))
+		names = names[1:]
+	}
+	return nil
+}
+
+// names returns the record files, oldest first.
+func (h *History) names() ([]string, error) {
+	entries, err := os.ReadDir(h.dir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// List returns the last n runs, newest first, or every run if n is zero.
+// Unreadable records are skipped.
+func (h *History) List(n int) ([]RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names, err := h.names()
+	if err != nil {
+		return nil, err
+	}
+	var records []RunRecord
+	for i := len(names) - 1; i >= 0 && (n <= 0 || len(records) < n); i-- {
+		r, err := h.read(names[i])
+		if err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Describe returns the record of the run with the given ID, or a prefix of
+// it as long as it is unambiguous.
+func (h *History) Describe(runID string) (RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names, err := h.names()
+	if err != nil {
+		return RunRecord{}, err
+	}
+	var match string
+	for _, name := range names {
+		// Names are "<timestamp>-<run ID>.json".
+		_, id, _ := strings.Cut(strings.TrimSuffix(name, ".json"), "-")
+		if !strings.HasPrefix(id, runID) || runID == "" {
+			continue
+		}
+		if match != "" {
+			return RunRecord{}, fmt.Errorf("run ID %q is ambiguous", runID)
+		}
+		match = name
+	}
+	if match == "" {
+		return RunRecord{}, fmt.Errorf("run %s: %w", runID, os.ErrNotExist)
+	}
+	return h.read(match)
+}
+
+func (h *History) read(name string) (RunRecord, error) {
+	var r RunRecord
+	b, err := os.ReadFile(filepath.Join(h.dir(), name))
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return r, nil
+}
+
+// newRunRecord starts the record of a run.
+func (ig *IG) newRunRecord(runID, image string, flags []string, started time.Time) RunRecord {
+	digest, _ := ig.ImageDigest(image)
+	return RunRecord{
+		RunID:     runID,
+		Image:     image,
+		Flags:     flags,
+		Digest:    digest,
+		IGVersion: ig.Version().String(),
+		Started:   started,
+	}
+}
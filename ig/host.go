@@ -0,0 +1,93 @@
+package ig
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostFingerprint describes the environment gadgets run in, which usually
+// explains why they behave differently between hosts.
+type HostFingerprint struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// Kernel is the kernel release, e.g. "6.5.0-1025-azure".
+	Kernel string `json:"kernel,omitempty"`
+	// Distro is the PRETTY_NAME of os-release, e.g. "Ubuntu 22.04.4 LTS".
+	Distro string `json:"distro,omitempty"`
+	// CgroupVersion is 1 or 2, 0 if unknown.
+	CgroupVersion int `json:"cgroupVersion,omitempty"`
+	// BTF reports whether the kernel exposes its BTF.
+	BTF bool `json:"btf"`
+	// Runtimes map the container runtimes found in PATH to the first line
+	// of their --version output.
+	Runtimes map[string]string `json:"runtimes,omitempty"`
+}
+
+// fingerprintRuntimes are the container runtimes HostFingerprint looks for.
+var fingerprintRuntimes = []string{"docker", "podman", "nerdctl", "containerd", "crio", "runc", "crun"}
+
+var fingerprint = sync.OnceValue(collectFingerprint)
+
+// Fingerprint returns the fingerprint of the host, collected on first use.
+func Fingerprint() HostFingerprint {
+	return fingerprint()
+}
+
+func collectFingerprint() HostFingerprint {
+	fp := HostFingerprint{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		fp.Kernel = strings.TrimSpace(string(b))
+	}
+	fp.Distro = osRelease("PRETTY_NAME")
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		fp.CgroupVersion = 2
+	} else if _, err := os.Stat("/sys/fs/cgroup"); err == nil {
+		fp.CgroupVersion = 1
+	}
+	_, err := os.Stat("/sys/kernel/btf/vmlinux")
+	fp.BTF = err == nil
+
+	for _, name := range fingerprintRuntimes {
+		if _, err := exec.LookPath(name); err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		out, err := exec.CommandContext(ctx, name, "--version").Output()
+		cancel()
+		if err != nil {
+			continue
+		}
+		if fp.Runtimes == nil {
+			fp.Runtimes = map[string]string{}
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+		fp.Runtimes[name] = line
+	}
+	return fp
+}
+
+// osRelease returns the value of key in os-release, or "".
+func osRelease(key string) string {
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if v, ok := strings.CutPrefix(sc.Text(), key+"="); ok {
+				return strings.Trim(v, `"'`)
+			}
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package ig
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"github.com/pawarpranav83/ig-testing-framework/internal/capture"
+)
+
+// IG runs a given ig binary.
+type IG struct {
+	path      string
+	env       []string
+	maxOutput int
+	reprobe   bool
+	bin       *binary
+	// keepArtifacts keeps the working directories of runs on Close.
+	keepArtifacts bool
+	authorizer    Authorizer
+	readOnly      bool
+	quota         *quota
+	pulls         *pullCache
+	annotations   *annotationCache
+	// transcriptDir is where sessions write their transcript, if set.
+	transcriptDir string
+	history       *History
+	// runners run the operation classes with a Runner, if set.
+	runners map[OperationClass]*runner
+	logger  *slog.Logger
+	// runFlags are passed to every ig run, see WithRunFlags.
+	runFlags []string
+	// versionFile persists probed versions, see WithVersionCache.
+	versionFile string
+	// credentials authenticate image operations, see WithCredentials.
+	credentials []CredentialSource
+	// sanitize is what is fixed in the output of ig, see WithSanitize.
+	sanitize SanitizeMode
+	// failOnWarning fails runs that printed warnings, see
+	// WithFailOnWarning.
+	failOnWarning bool
+}
+
+// DefaultMaxOutput is the output cap of IGs created without WithMaxOutput.
+var DefaultMaxOutput = 64 << 20
+
+// Option configures an IG.
+type Option func(*IG)
+
+// WithPath sets the ig binary to run instead of looking up "ig" in PATH.
+func WithPath(path string) Option {
+	return func(ig *IG) {
+		ig.path = path
+	}
+}
+
+// WithEnv adds "KEY=value" environment variables to every ig invocation, on
+// top of the inherited environment.
+func WithEnv(env ...string) Option {
+	return func(ig *IG) {
+		ig.env = append(ig.env, env...)
+	}
+}
+
+// WithMaxOutput caps how many bytes of stdout and of stderr are kept per ig
+// invocation, each; the rest is counted and replaced by a truncation marker,
+// so a runaway gadget can't exhaust the memory of the process buffering its
+// output. A negative n means no limit.
+func WithMaxOutput(n int) Option {
+	return func(ig *IG) {
+		ig.maxOutput = n
+	}
+}
+
+// WithKeepArtifacts keeps the working directory of every run (see
+// RunResult.Dir) when the result is closed, to inspect what a run left
+// behind.
+func WithKeepArtifacts() Option {
+	return func(ig *IG) {
+		ig.keepArtifacts = true
+	}
+}
+
+// New locates the ig binary and probes its version.
+func New(opts ...Option) (*IG, error) {
+	ig := &IG{path: "ig", maxOutput: DefaultMaxOutput, bin: &binary{}, sanitize: SanitizeAll}
+	for _, opt := range opts {
+		opt(ig)
+	}
+
+	path, err := exec.LookPath(ig.path)
+	if err != nil {
+		return nil, fmt.Errorf("looking up ig binary: %w", err)
+	}
+	ig.path = path
+	if err := ig.resolveRunners(); err != nil {
+		return nil, err
+	}
+
+	v, err := ig.probeVersion(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("probing ig version: %w", err)
+	}
+	ig.bin.version = v
+
+	return ig, nil
+}
+
+// Path returns the absolute path of the ig binary.
+func (ig *IG) Path() string {
+	return ig.path
+}
+
+// Version returns the version of the ig binary, as probed by New, or
+// since, if the binary changed and the IG was created with WithReprobe.
+func (ig *IG) Version() Version {
+	ig.bin.mu.Lock()
+	defer ig.bin.mu.Unlock()
+
+	return ig.bin.version
+}
+
+// CommandError is returned when ig exits with an error.
+type CommandError struct {
+	// RunID identifies the operation that ran ig, see WithRunID.
+	RunID string
+	// Args are the arguments ig was run with.
+	Args []string
+	// ExitCode is the exit code of ig, or -1 if it was killed by a signal.
+	ExitCode int
+	// Stderr is what ig printed on stderr.
+	Stderr string
+	// Err is the error returned by os/exec.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("ig %s: %s", strings.Join(e.Args, " "), e.Err)
+	if e.RunID != "" {
+		msg = fmt.Sprintf("ig %s (run %s): %s", strings.Join(e.Args, " "), e.RunID, e.Err)
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+type execResult struct {
+	stdout string
+	stderr string
+	// stdoutTruncated and stderrTruncated count the bytes discarded past
+	// the output cap.
+	stdoutTruncated int64
+	stderrTruncated int64
+	// exitCode is the exit code of ig, -1 if it was killed by a signal or
+	// didn't start.
+	exitCode int
+}
+
+// exec runs ig with args, capturing its output, after checking the binary
+// didn't change version.
+func (ig *IG) exec(ctx context.Context, args ...string) (execResult, error) {
+	return ig.execIn(ctx, "", args...)
+}
+
+// execIn is exec with ig running in dir, or in the current directory if dir
+// is empty.
+func (ig *IG) execIn(ctx context.Context, dir string, args ...string) (execResult, error) {
+	if err := ig.checkBinary(ctx); err != nil {
+		return execResult{}, err
+	}
+	return ig.run(ctx, dir, args...)
+}
+
+// run runs ig with args in dir, capturing its output.
+func (ig *IG) run(ctx context.Context, dir string, args ...string) (execResult, error) {
+	ctx, runID, err := ensureRunID(ctx)
+	if err != nil {
+		return execResult{}, err
+	}
+	stdout, stderr := capture.New(ig.maxOutput), capture.New(ig.maxOutput)
+
+	cmd := ig.command(ctx, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	log := ig.Logger().With("run", runID)
+	log.Debug("running ig", "args", args)
+	err = startTracked(cmd)
+	if err == nil {
+		err = waitTracked(cmd)
+	}
+	res := execResult{
+		stdout:          Sanitize(stdout.String(), ig.sanitize),
+		stderr:          Sanitize(stderr.String(), ig.sanitize),
+		stdoutTruncated: stdout.Truncated(),
+		stderrTruncated: stderr.Truncated(),
+		exitCode:        cmd.ProcessState.ExitCode(),
+	}
+	if err != nil {
+		log.Warn("ig failed", "args", args, "err", err)
+		return res, &CommandError{
+			RunID:    runID,
+			Args:     args,
+			ExitCode: res.exitCode,
+			Stderr:   res.stderr,
+			Err:      err,
+		}
+	}
+
+	return res, nil
+}
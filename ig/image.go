@@ -0,0 +1,108 @@
+package ig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Pull pulls a gadget image, passing flags (e.g. "--insecure-registries") to
+// ig image pull. With WithPullCache, images already pulled at the digest
+// their tag points to are skipped.
+func (ig *IG) Pull(ctx context.Context, image string, flags ...string) error {
+	var digest string
+	if ig.pulls != nil {
+		cred, err := ig.credential(ctx, image)
+		if err != nil {
+			return fmt.Errorf("pulling %s: %w", image, err)
+		}
+		var fresh bool
+		if digest, fresh = ig.pulls.fresh(ctx, image, flags, cred); fresh {
+			return nil
+		}
+	}
+	flags, cleanup, err := ig.withAuth(ctx, image, flags)
+	if err != nil {
+		return fmt.Errorf("pulling %s: %w", image, err)
+	}
+	defer cleanup()
+
+	args := append([]string{"image", "pull", image}, flags...)
+	if _, err := ig.exec(ctx, args...); err != nil {
+		return fmt.Errorf("pulling %s: %w", image, err)
+	}
+	if digest != "" {
+		if err := ig.pulls.record(image, digest); err != nil {
+			return fmt.Errorf("recording pull of %s: %w", image, err)
+		}
+	}
+	return nil
+}
+
+// Push pushes a gadget image, passing flags to ig image push.
+func (ig *IG) Push(ctx context.Context, image string, flags ...string) error {
+	ctx, _, err := ensureRunID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ig.authorize(ctx, Operation{Kind: OpPush, Image: image, Flags: flags}); err != nil {
+		return err
+	}
+	flags, cleanup, err := ig.withAuth(ctx, image, flags)
+	if err != nil {
+		return fmt.Errorf("pushing %s: %w", image, err)
+	}
+	defer cleanup()
+	args := append([]string{"image", "push", image}, flags...)
+	if _, err := ig.exec(ctx, args...); err != nil {
+		return fmt.Errorf("pushing %s: %w", image, err)
+	}
+	return nil
+}
+
+// Remove removes a gadget image from the local store.
+func (ig *IG) Remove(ctx context.Context, image string) error {
+	ctx, _, err := ensureRunID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ig.authorize(ctx, Operation{Kind: OpRemoveImage, Image: image}); err != nil {
+		return err
+	}
+	if _, err := ig.exec(ctx, "image", "remove", image); err != nil {
+		return fmt.Errorf("removing %s: %w", image, err)
+	}
+	if ig.pulls != nil {
+		return ig.pulls.forget(image)
+	}
+	return nil
+}
+
+// Tag tags the image src of the local store as dst, e.g. to push a pulled
+// gadget to a local registry.
+func (ig *IG) Tag(ctx context.Context, src, dst string) error {
+	ctx, _, err := ensureRunID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ig.authorize(ctx, Operation{Kind: OpTag, Image: dst}); err != nil {
+		return err
+	}
+	if _, err := ig.exec(ctx, "image", "tag", src, dst); err != nil {
+		return fmt.Errorf("tagging %s as %s: %w", src, dst, err)
+	}
+	return nil
+}
+
+// GadgetName returns the bare gadget name of image, without registry,
+// repository or tag: "ghcr.io/inspektor-gadget/gadget/trace_exec:latest" and
+// "trace_exec" both return "trace_exec".
+func GadgetName(image string) string {
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	if i := strings.IndexAny(image, ":@"); i >= 0 {
+		image = image[:i]
+	}
+	return image
+}
@@ -0,0 +1,146 @@
+package ig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Image is a local image, as listed by ig image list.
+type Image struct {
+	Repository string `json:"repository"`
+	// Tag is empty for untagged images.
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	// Created is zero if ig didn't report it. Relative times printed by the
+	// table output of some versions, e.g. "2 days ago", are approximated.
+	Created time.Time `json:"created"`
+}
+
+// Ref returns the reference of the image: repository:tag, or
+// repository@digest for untagged images.
+func (i Image) Ref() string {
+	switch {
+	case i.Tag != "":
+		return i.Repository + ":" + i.Tag
+	case i.Digest != "":
+		return i.Repository + "@" + i.Digest
+	default:
+		return i.Repository
+	}
+}
+
+// Images returns the local images, in the order ig lists them. It decodes
+// ig image list -o json, and parses the table of ig image list for
+// versions without JSON output.
+func (ig *IG) Images(ctx context.Context) ([]Image, error) {
+	out, err := ig.exec(ctx, "image", "list", "-o", "json")
+	if err == nil {
+		if images, jerr := decodeImages(out.stdout); jerr == nil {
+			return images, nil
+		}
+	}
+	table, terr := ig.exec(ctx, "image", "list")
+	if terr != nil {
+		if err != nil {
+			terr = err
+		}
+		return nil, fmt.Errorf("listing images: %w", terr)
+	}
+	return parseImageTable(table.stdout, time.Now()), nil
+}
+
+func decodeImages(out string) ([]Image, error) {
+	var raw []struct {
+		Repository string `json:"repository"`
+		Tag        string `json:"tag"`
+		Digest     string `json:"digest"`
+		Created    string `json:"created"`
+	}
+	if s := strings.TrimSpace(out); s != "" && s != "null" {
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return nil, fmt.Errorf("decoding images: %w", err)
+		}
+	}
+
+	images := make([]Image, 0, len(raw))
+	for _, r := range raw {
+		images = append(images, Image{
+			Repository: r.Repository,
+			Tag:        imageTag(r.Tag),
+			Digest:     r.Digest,
+			Created:    parseCreated(r.Created, time.Now()),
+		})
+	}
+	return images, nil
+}
+
+// parseImageTable parses the table of ig image list:
+//
+//	REPOSITORY                        TAG     DIGEST        CREATED
+//	ghcr.io/inspektor-gadget/gadget   latest  3b5e5ad3ec4f  2 days ago
+func parseImageTable(out string, now time.Time) []Image {
+	var images []Image
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 || strings.EqualFold(f[0], "REPOSITORY") {
+			continue
+		}
+		img := Image{Repository: f[0]}
+		if len(f) > 1 {
+			img.Tag = imageTag(f[1])
+		}
+		if len(f) > 2 {
+			img.Digest = f[2]
+		}
+		if len(f) > 3 {
+			img.Created = parseCreated(strings.Join(f[3:], " "), now)
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+func imageTag(tag string) string {
+	if tag == "<none>" {
+		return ""
+	}
+	return tag
+}
+
+// relativeTime matches the relative times of the table of ig image list.
+var relativeTime = regexp.MustCompile(`^(\d+|an?|about an?) (second|minute|hour|day|week|month|year)s? ago$`)
+
+var timeUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// parseCreated parses a creation time, absolute or relative to now, zero
+// if it isn't one.
+func parseCreated(s string, now time.Time) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05 -0700 MST"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	m := relativeTime.FindStringSubmatch(strings.ToLower(s))
+	if m == nil {
+		return time.Time{}
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		n = 1
+	}
+	return now.Add(-time.Duration(n) * timeUnits[m[2]])
+}
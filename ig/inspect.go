@@ -0,0 +1,233 @@
+package ig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImageInfo is the metadata of a gadget image, as reported by ig image
+// inspect.
+type ImageInfo struct {
+	Image       string
+	Annotations ImageAnnotations
+	DataSources []DataSourceInfo
+	Params      []ParamInfo
+	// Architectures are the architectures the image was built for, sorted,
+	// if ig reports them.
+	Architectures []string
+	// Metadata is the gadget metadata file (gadget.yaml), if ig reports
+	// it.
+	Metadata []byte
+
+	// annotations are the raw annotations, parsed into Annotations by
+	// InspectImage.
+	annotations map[string]string
+}
+
+// DataSourceInfo is a data source of a gadget.
+type DataSourceInfo struct {
+	Name string
+	// Fields are in the order of the gadget.
+	Fields []FieldInfo
+}
+
+// FieldInfo is a field of the events of a data source.
+type FieldInfo struct {
+	// Path is the dot-separated path of the field, e.g. "proc.comm".
+	Path string
+	// Kind is the kind of the field in the gadget service API, e.g. "Uint32".
+	Kind string
+	// Type is its JSON type, one of the Type constants.
+	Type        string
+	Description string
+	Annotations map[string]string
+	// Empty marks containers of other fields, without a value of their own.
+	Empty bool
+}
+
+// ParamInfo is a param of a gadget.
+type ParamInfo struct {
+	Key string
+	// Prefix is prepended to Key to get the flag of ig run, e.g.
+	// "operator.oci.ebpf.".
+	Prefix         string
+	Description    string
+	DefaultValue   string
+	TypeHint       string
+	PossibleValues []string
+}
+
+// Flag returns the flag setting the param, without dashes.
+func (p ParamInfo) Flag() string {
+	return p.Prefix + p.Key
+}
+
+// kindNames are the names of the field kinds of the gadget service API, by
+// number.
+var kindNames = []string{"Invalid", "Bool", "Int8", "Int16", "Int32", "Int64", "Uint8", "Uint16", "Uint32", "Uint64", "Float32", "Float64", "String", "CString", "Bytes"}
+
+// kindName returns the name of a kind given by name or by number.
+func kindName(kind json.RawMessage) string {
+	s := strings.Trim(string(kind), `"`)
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(kindNames) {
+		return kindNames[n]
+	}
+	return s
+}
+
+// inspectOutput is the output of ig image inspect -o json, as in the
+// gadget information of the gadget service API.
+type inspectOutput struct {
+	DataSources []struct {
+		Name   string `json:"name"`
+		Fields []struct {
+			FullName    string            `json:"fullName"`
+			Kind        json.RawMessage   `json:"kind"`
+			Annotations map[string]string `json:"annotations"`
+			Flags       uint32            `json:"flags"`
+		} `json:"fields"`
+	} `json:"dataSources"`
+	// Metadata is the metadata file, base64-encoded as the bytes of the
+	// gadget service API.
+	Metadata    string            `json:"metadata"`
+	Params      []inspectParam    `json:"params"`
+	Annotations map[string]string `json:"annotations"`
+	Manifest    struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"manifest"`
+	// Architectures are listed by some versions; others show the image
+	// index.
+	Architectures []string `json:"architectures"`
+	Index         struct {
+		Manifests []struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	} `json:"index"`
+}
+
+// inspectParam is a param of ig image inspect -o json.
+type inspectParam struct {
+	Key          string   `json:"key"`
+	Prefix       string   `json:"prefix"`
+	Description  string   `json:"description"`
+	DefaultValue string   `json:"defaultValue"`
+	TypeHint     string   `json:"typeHint"`
+	Possible     []string `json:"possibleValues"`
+}
+
+// InspectImage returns the metadata of image, which must be in the local
+// store: its annotations, data sources with their fields, params and
+// architectures, to introspect a gadget before running it.
+func (ig *IG) InspectImage(ctx context.Context, image string) (*ImageInfo, error) {
+	info, err := ig.inspect(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	info.Annotations, err = ParseAnnotations(info.annotations)
+	if err != nil {
+		return info, fmt.Errorf("image %s: %w", image, err)
+	}
+	return info, nil
+}
+
+// inspect returns the metadata of image as InspectImage does, without
+// parsing its annotations, so images with invalid annotations can still be
+// introspected.
+func (ig *IG) inspect(ctx context.Context, image string) (*ImageInfo, error) {
+	out, err := ig.exec(ctx, "image", "inspect", image, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("inspecting %s: %w", image, err)
+	}
+	var raw inspectOutput
+	if err := json.Unmarshal([]byte(out.stdout), &raw); err != nil {
+		return nil, fmt.Errorf("decoding inspection of %s: %w", image, err)
+	}
+
+	info := &ImageInfo{Image: image}
+	for _, ds := range raw.DataSources {
+		d := DataSourceInfo{Name: ds.Name}
+		for _, f := range ds.Fields {
+			kind := kindName(f.Kind)
+			typ, ok := kindTypes[kind]
+			if !ok {
+				typ = TypeString
+			}
+			d.Fields = append(d.Fields, FieldInfo{
+				Path:        f.FullName,
+				Kind:        kind,
+				Type:        typ,
+				Description: f.Annotations["description"],
+				Annotations: f.Annotations,
+				Empty:       f.Flags&fieldFlagEmpty != 0,
+			})
+		}
+		info.DataSources = append(info.DataSources, d)
+	}
+	for _, p := range raw.Params {
+		info.Params = append(info.Params, ParamInfo{
+			Key:            p.Key,
+			Prefix:         p.Prefix,
+			Description:    p.Description,
+			DefaultValue:   p.DefaultValue,
+			TypeHint:       p.TypeHint,
+			PossibleValues: p.Possible,
+		})
+	}
+
+	archs := map[string]bool{}
+	for _, a := range raw.Architectures {
+		archs[a] = true
+	}
+	for _, m := range raw.Index.Manifests {
+		if a := m.Platform.Architecture; a != "" && a != "unknown" {
+			archs[a] = true
+		}
+	}
+	for a := range archs {
+		info.Architectures = append(info.Architectures, a)
+	}
+	sort.Strings(info.Architectures)
+
+	if md, err := base64.StdEncoding.DecodeString(raw.Metadata); err == nil && len(md) > 0 {
+		info.Metadata = md
+	}
+
+	info.annotations = raw.Annotations
+	if info.annotations == nil {
+		info.annotations = raw.Manifest.Annotations
+	}
+	if info.annotations == nil {
+		info.annotations = map[string]string{}
+	}
+	return info, nil
+}
+
+// DataSource returns the data source name of the image, false if it has
+// none.
+func (i *ImageInfo) DataSource(name string) (DataSourceInfo, bool) {
+	for _, ds := range i.DataSources {
+		if ds.Name == name {
+			return ds, true
+		}
+	}
+	return DataSourceInfo{}, false
+}
+
+// Param returns the param of the image set by flag, with or without dashes
+// and prefix, false if it has none.
+func (i *ImageInfo) Param(flag string) (ParamInfo, bool) {
+	flag = strings.TrimLeft(flag, "-")
+	for _, p := range i.Params {
+		if p.Flag() == flag || p.Key == flag {
+			return p, true
+		}
+	}
+	return ParamInfo{}, false
+}
@@ -0,0 +1,121 @@
+package ig
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// inspectIG returns an IG whose image inspect prints the file inspection.
+func inspectIG(t *testing.T, inspection string) *IG {
+	t.Helper()
+	path, err := filepath.Abs(inspection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scriptIG(t, "cat '"+path+"'\n")
+}
+
+func TestInspectImage(t *testing.T) {
+	ctx := context.Background()
+	i := inspectIG(t, "testdata/inspect.json")
+
+	info, err := i.InspectImage(ctx, "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Annotations.Title != "trace exec" || info.Annotations.MinIGVersion.String() != "v0.25.0" {
+		t.Errorf("Annotations = %+v", info.Annotations)
+	}
+	if want := []string{"amd64", "arm64"}; !reflect.DeepEqual(info.Architectures, want) {
+		t.Errorf("Architectures = %q, want %q", info.Architectures, want)
+	}
+	ds, ok := info.DataSource("exec")
+	if !ok || len(ds.Fields) != 4 {
+		t.Fatalf("DataSource(exec) = %+v, %t", ds, ok)
+	}
+	if f := ds.Fields[0]; f.Path != "proc" || !f.Empty || f.Kind != "Invalid" {
+		t.Errorf("field proc = %+v", f)
+	}
+	if f := ds.Fields[1]; f.Kind != "Uint32" || f.Type != TypeInteger || f.Description != "Process ID" {
+		t.Errorf("field proc.pid = %+v", f)
+	}
+	if p, ok := info.Param("--operator.oci.ebpf.paths"); !ok || p.DefaultValue != "false" {
+		t.Errorf("Param(paths) = %+v, %t", p, ok)
+	}
+
+	a, err := i.Annotations(ctx, "trace_exec")
+	if err != nil || !reflect.DeepEqual(a, info.Annotations) {
+		t.Errorf("Annotations = %+v, %v; want %+v", a, err, info.Annotations)
+	}
+
+	schemas, err := i.Schemas(ctx, "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Schema{{Gadget: "trace_exec", DataSource: "exec", Fields: []FieldSchema{
+		{Path: "args", Type: TypeString},
+		{Path: "proc.comm", Type: TypeString, Description: "Command"},
+		{Path: "proc.pid", Type: TypeInteger, Description: "Process ID"},
+	}}}
+	if !reflect.DeepEqual(schemas, want) {
+		t.Errorf("Schemas = %+v, want %+v", schemas, want)
+	}
+
+	findings, err := i.Validate(ctx, "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	wantFindings := []string{
+		"warning: datasources.exec.fields.args: no description",
+		`error: datasources.exec.fields.proc.comm.annotations.columns.width: "0" must be a positive integer`,
+	}
+	if !reflect.DeepEqual(got, wantFindings) {
+		t.Errorf("Validate =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(wantFindings, "\n"))
+	}
+
+	params, err := i.WasmParams(ctx, "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 1 || params[0].Key != "threshold" || params[0].TypeHint != "uint32" {
+		t.Errorf("WasmParams = %+v", params)
+	}
+}
+
+func TestInspectImageInvalidAnnotations(t *testing.T) {
+	ctx := context.Background()
+	i := scriptIG(t, `echo '{"dataSources":[{"name":"ds","fields":[{"fullName":"a","kind":"Bool"}]}],"annotations":{"io.inspektor-gadget.min-ig-version":"soon"}}'`+"\n")
+
+	info, err := i.InspectImage(ctx, "x")
+	if err == nil || info == nil {
+		t.Fatalf("InspectImage = %v, %v; want the info and an error", info, err)
+	}
+	if _, err := i.Annotations(ctx, "x"); err == nil {
+		t.Error("Annotations succeeded")
+	}
+	// Introspection doesn't depend on the annotations.
+	schemas, err := i.Schemas(ctx, "x")
+	if err != nil || len(schemas) != 1 || schemas[0].Fields[0].Type != TypeBoolean {
+		t.Errorf("Schemas = %+v, %v", schemas, err)
+	}
+}
+
+func TestInspectImageErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := scriptIG(t, "echo '{'\n").InspectImage(ctx, "x"); err == nil || !strings.Contains(err.Error(), "decoding inspection of x") {
+		t.Errorf("InspectImage of invalid JSON: %v", err)
+	}
+	if _, err := scriptIG(t, "echo 'Error: image not found' >&2; exit 1\n").InspectImage(ctx, "x"); err == nil || !strings.Contains(err.Error(), "inspecting x") {
+		t.Errorf("InspectImage of a missing image: %v", err)
+	}
+	if _, err := scriptIG(t, "echo '{}'\n").Schemas(ctx, "x"); err == nil || !strings.Contains(err.Error(), "no data sources") {
+		t.Errorf("Schemas without data sources: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package ig
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ReleaseURL is the URL of the release archive of ig, formatted with the
+// version ("v0.30.0"), the operating system and the architecture, as
+// published on GitHub. Point it at a mirror for air-gapped CI.
+var ReleaseURL = "https://github.com/inspektor-gadget/inspektor-gadget/releases/download/%[1]s/ig-%[2]s-%[3]s-%[1]s.tar.gz"
+
+// Install downloads the ig binary of version for the host into
+// dir/<version>/ig, unless it is already there, and returns its path for
+// WithPath. If dir is empty, binaries are cached in the user cache
+// directory.
+func Install(ctx context.Context, version, dir string) (string, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("installing ig %s: %w", v, err)
+		}
+		dir = filepath.Join(cache, "ig-testing-framework", "ig")
+	}
+	path := filepath.Join(dir, v.String(), "ig")
+	if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+		return path, nil
+	}
+
+	if err := download(ctx, fmt.Sprintf(ReleaseURL, v, runtime.GOOS, runtime.GOARCH), path); err != nil {
+		return "", fmt.Errorf("installing ig %s: %w", v, err)
+	}
+	return path, nil
+}
+
+// download extracts the ig binary of the release archive at url to path,
+// atomically.
+func download(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", url, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("no ig binary in %s", url)
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", url, err)
+		}
+		if h.Typeflag == tar.TypeReg && filepath.Base(h.Name) == "ig" {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".ig-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return fmt.Errorf("extracting ig: %w", err)
+	}
+	if err := f.Chmod(0o755); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
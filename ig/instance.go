@@ -0,0 +1,154 @@
+package ig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Instance is a gadget instance running detached ("ig run --detach").
+type Instance struct {
+	ID    string
+	Name  string
+	Image string
+	// Tags are the "key=value" tags given with --tags.
+	Tags    []string
+	Created time.Time
+}
+
+// HasTag reports whether the instance has tag, given as "key=value".
+func (i Instance) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+type instanceJSON struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Tags         []string `json:"tags"`
+	GadgetConfig struct {
+		ImageName string `json:"imageName"`
+	} `json:"gadgetConfig"`
+	TimeCreated int64 `json:"timeCreated"`
+}
+
+// Instances lists the detached gadget instances.
+func (ig *IG) Instances(ctx context.Context) ([]Instance, error) {
+	out, err := ig.exec(ctx, "list", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("listing instances: %w", err)
+	}
+
+	var raw []instanceJSON
+	if s := strings.TrimSpace(out.stdout); s != "" && s != "null" {
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return nil, fmt.Errorf("decoding instances: %w", err)
+		}
+	}
+
+	instances := make([]Instance, len(raw))
+	for i, r := range raw {
+		instances[i] = Instance{
+			ID:      r.ID,
+			Name:    r.Name,
+			Image:   r.GadgetConfig.ImageName,
+			Tags:    r.Tags,
+			Created: time.Unix(r.TimeCreated, 0),
+		}
+	}
+	return instances, nil
+}
+
+// DeleteInstance stops and removes a detached instance, by ID or name.
+func (ig *IG) DeleteInstance(ctx context.Context, id string) error {
+	ctx, _, err := ensureRunID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ig.authorize(ctx, Operation{Kind: OpDeleteInstance, Instance: id}); err != nil {
+		return err
+	}
+	if _, err := ig.exec(ctx, "delete", id); err != nil {
+		return fmt.Errorf("deleting instance %s: %w", id, err)
+	}
+	return nil
+}
+
+// JanitorOptions selects the instances CollectInstances deletes.
+type JanitorOptions struct {
+	// NamePrefix restricts collection to instances whose name starts with
+	// it, e.g. the prefix a controller names its instances with.
+	NamePrefix string
+	// Tags restricts collection to instances having all of these
+	// "key=value" tags.
+	Tags []string
+	// TTL is the age past which a matching instance is deleted.
+	TTL time.Duration
+}
+
+func (o JanitorOptions) matches(i Instance) bool {
+	if !strings.HasPrefix(i.Name, o.NamePrefix) {
+		return false
+	}
+	for _, t := range o.Tags {
+		if !i.HasTag(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// CollectInstances deletes the detached instances matching opts that are
+// older than opts.TTL, such as those left behind by a crashed controller,
+// and returns the deleted ones. It keeps going past failed deletions and
+// returns their errors joined.
+func (ig *IG) CollectInstances(ctx context.Context, opts JanitorOptions) ([]Instance, error) {
+	if opts.NamePrefix == "" && len(opts.Tags) == 0 {
+		return nil, errors.New("collecting instances: a name prefix or tags are required")
+	}
+
+	instances, err := ig.Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []Instance
+	var errs []error
+	for _, inst := range instances {
+		if !opts.matches(inst) || time.Since(inst.Created) < opts.TTL {
+			continue
+		}
+		if err := ig.DeleteInstance(ctx, inst.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted = append(deleted, inst)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// RunJanitor calls CollectInstances every interval until ctx is done,
+// passing each round's result to report if not nil.
+func (ig *IG) RunJanitor(ctx context.Context, opts JanitorOptions, interval time.Duration, report func(deleted []Instance, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := ig.CollectInstances(ctx, opts)
+		if report != nil {
+			report(deleted, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package ig
+
+import (
+	"io"
+	"log/slog"
+)
+
+// discardLogger is the logger of IGs created without WithLogger.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// WithLogger makes the IG log to l: every ig invocation at debug level and
+// failed ones at warn level, with their run ID.
+func WithLogger(l *slog.Logger) Option {
+	return func(ig *IG) {
+		ig.logger = l
+	}
+}
+
+// Logger returns the logger of the IG, discarding everything if it was
+// created without WithLogger.
+func (ig *IG) Logger() *slog.Logger {
+	if ig.logger == nil {
+		return discardLogger
+	}
+	return ig.logger
+}
@@ -0,0 +1,52 @@
+package ig
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Completeness tells whether a capture holds every event the gadget traced,
+// e.g. before using it as audit evidence.
+type Completeness struct {
+	// Lost counts the events ig reported lost on stderr, overwritten in the
+	// kernel buffers before ig read them.
+	Lost uint64 `json:"lost"`
+	// Dropped counts the output lines of sessions read but not forwarded:
+	// lines past the maximum event size and lines read past a quota.
+	Dropped int64 `json:"dropped"`
+	// Truncated reports output discarded past the output cap of the IG
+	// (see WithMaxOutput): events, or reports of lost events.
+	Truncated bool `json:"truncated"`
+}
+
+// Complete reports whether nothing was lost, dropped or truncated.
+func (c Completeness) Complete() bool {
+	return c.Lost == 0 && c.Dropped == 0 && !c.Truncated
+}
+
+// lostReport matches the reports of lost events of ig and of gadgets, e.g.
+// "lost 12 samples" from perf buffers or "dropped 3 events".
+var lostReport = regexp.MustCompile(`(?i)\b(?:lost|dropped) (\d+) (?:samples?|events?|records?)\b`)
+
+// LostEvents returns the number of events ig reported lost on stderr.
+func LostEvents(stderr string) uint64 {
+	var lost uint64
+	for _, r := range ParseStderr(stderr) {
+		for _, m := range lostReport.FindAllStringSubmatch(r.Message, -1) {
+			if n, err := strconv.ParseUint(m[1], 10, 64); err == nil {
+				lost += n
+			}
+		}
+	}
+	return lost
+}
+
+// Completeness returns whether the session forwarded every event the gadget
+// traced so far.
+func (s *GadgetSession) Completeness() Completeness {
+	return Completeness{
+		Lost:      LostEvents(s.Stderr()),
+		Dropped:   s.dropped.Load(),
+		Truncated: s.stderr.Truncated() > 0,
+	}
+}
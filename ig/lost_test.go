@@ -0,0 +1,88 @@
+package ig
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLostEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		want   uint64
+	}{
+		{name: "none", stderr: "", want: 0},
+		{name: "terminal", stderr: "WARN[0001] lost 12 samples", want: 12},
+		{name: "logfmt", stderr: `level=warning msg="dropped 3 events"`, want: 3},
+		{name: "records", stderr: "lost 4 records", want: 4},
+		{name: "singular", stderr: "lost 1 sample\ndropped 1 event", want: 2},
+		{name: "summed across lines", stderr: "WARN[0001] lost 12 samples\nINFO[0001] ok\nWARN[0002] lost 30 samples\n", want: 42},
+		{name: "summed within a line", stderr: "lost 2 samples, dropped 1 events", want: 3},
+		{name: "case", stderr: "Lost 5 Samples\nDROPPED 6 EVENTS", want: 11},
+		{name: "lost connection", stderr: "WARN[0001] lost connection to the runtime", want: 0},
+		{name: "lost without count", stderr: "lost samples", want: 0},
+		{name: "other unit", stderr: "lost 3 connections\ndropped 2 packets", want: 0},
+		{name: "part of a word", stderr: "unlost 3 samples\nlost 3 samplesets", want: 0},
+		{name: "overflow", stderr: "lost 99999999999999999999 samples\nlost 1 samples", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LostEvents(tt.stderr); got != tt.want {
+				t.Errorf("LostEvents(%q) = %d, want %d", tt.stderr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteness(t *testing.T) {
+	script := `echo 'WARN[0001] lost 7 samples' >&2
+echo '{"comm":"cat"}'
+`
+	res, err := scriptIG(t, script).Run(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if want := (Completeness{Lost: 7}); res.Completeness != want || res.Completeness.Complete() {
+		t.Errorf("run Completeness = %+v, want %+v, incomplete", res.Completeness, want)
+	}
+
+	res, err = scriptIG(t, `echo '{"comm":"cat"}'`, WithMaxOutput(4)).Run(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if !res.Completeness.Truncated || res.Completeness.Complete() {
+		t.Errorf("truncated run Completeness = %+v", res.Completeness)
+	}
+
+	s, err := scriptIG(t, script).Start(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range s.Events() {
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Completeness{Lost: 7}); s.Completeness() != want {
+		t.Errorf("session Completeness = %+v, want %+v", s.Completeness(), want)
+	}
+
+	// A line past the maximum event size drops it and the lines after it.
+	long := `head -c 2000000 /dev/zero | tr '\0' a; echo; echo '{"comm":"cat"}'`
+	s, err = scriptIG(t, long).Start(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range s.Events() {
+	}
+	s.Wait()
+	if got := s.Completeness(); got.Dropped != 2 || got.Complete() {
+		t.Errorf("session Completeness = %+v, want 2 dropped", got)
+	}
+
+	if !(Completeness{}).Complete() {
+		t.Error("zero Completeness is incomplete")
+	}
+}
@@ -0,0 +1,154 @@
+package ig
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// MergeSorted merges streams of events into one in timestamp order (see
+// Event.Time), ties broken by Seq. Each stream is assumed to be in order
+// already, as the events of one gadget mostly are: an event is released once
+// every open stream has moved past it, or after it waited window, so a
+// silent stream delays the others by at most window. Events more than window
+// late still come out of order.
+func MergeSorted(window time.Duration, ins ...<-chan Event) <-chan Event {
+	type input struct {
+		i   int
+		ev  Event
+		end bool
+	}
+	merged := make(chan input, 64)
+	for i, in := range ins {
+		go func(i int, in <-chan Event) {
+			for ev := range in {
+				merged <- input{i: i, ev: ev}
+			}
+			merged <- input{i: i, end: true}
+		}(i, in)
+	}
+
+	out := make(chan Event, 64)
+	go func() {
+		defer close(out)
+
+		open := len(ins)
+		last := make([]time.Time, len(ins))
+		closed := make([]bool, len(ins))
+		var pending EventQueue
+
+		// watermark is the time every open stream reached.
+		watermark := func() (time.Time, bool) {
+			var w time.Time
+			for i, t := range last {
+				if closed[i] {
+					continue
+				}
+				if t.IsZero() {
+					return time.Time{}, false
+				}
+				if w.IsZero() || t.Before(w) {
+					w = t
+				}
+			}
+			return w, !w.IsZero()
+		}
+		release := func(now time.Time) {
+			w, ok := watermark()
+			for pending.Len() > 0 {
+				head := pending.Peek()
+				if !(ok && !head.Time().After(w)) && now.Sub(head.Received) < window {
+					return
+				}
+				out <- pending.Pop()
+			}
+		}
+
+		tick := window / 4
+		if tick < time.Millisecond {
+			tick = time.Millisecond
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for open > 0 {
+			select {
+			case in := <-merged:
+				if in.end {
+					closed[in.i] = true
+					open--
+				} else {
+					if t := in.ev.Time(); t.After(last[in.i]) {
+						last[in.i] = t
+					}
+					pending.Push(in.ev)
+				}
+				release(time.Now())
+			case now := <-ticker.C:
+				release(now)
+			}
+		}
+		for pending.Len() > 0 {
+			out <- pending.Pop()
+		}
+	}()
+	return out
+}
+
+// StartSortedSet is StartSet with the events of the gadgets merged by
+// MergeSorted, so consumers see a single timeline.
+func (ig *IG) StartSortedSet(ctx context.Context, window time.Duration, specs ...GadgetSpec) (*GadgetSet, error) {
+	set, err := ig.startSessions(ctx, specs)
+	if err != nil {
+		return nil, err
+	}
+	ins := make([]<-chan Event, len(set.sessions))
+	for i, s := range set.sessions {
+		ins[i] = s.Events()
+	}
+	set.events = MergeSorted(window, ins...)
+	return set, nil
+}
+
+// EventQueue holds events in timestamp order (see Event.Time), ties broken
+// by Seq, the order MergeSorted and stream.Reorder release them in. The zero
+// value is an empty queue.
+type EventQueue struct {
+	h eventHeap
+}
+
+// Len returns the number of queued events.
+func (q *EventQueue) Len() int { return q.h.Len() }
+
+// Push queues ev.
+func (q *EventQueue) Push(ev Event) { heap.Push(&q.h, ev) }
+
+// Peek returns the first event without removing it. The queue must not be
+// empty.
+func (q *EventQueue) Peek() Event { return q.h[0] }
+
+// Pop removes and returns the first event. The queue must not be empty.
+func (q *EventQueue) Pop() Event { return heap.Pop(&q.h).(Event) }
+
+type eventHeap []Event
+
+func (h eventHeap) Len() int { return len(h) }
+
+func (h eventHeap) Less(i, j int) bool {
+	ti, tj := h[i].Time(), h[j].Time()
+	if !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return h[i].Seq < h[j].Seq
+}
+
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *eventHeap) Push(x any) { *h = append(*h, x.(Event)) }
+
+func (h *eventHeap) Pop() any {
+	old := *h
+	ev := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return ev
+}
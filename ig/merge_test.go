@@ -0,0 +1,34 @@
+package ig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventQueue(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	var q EventQueue
+	for _, ev := range []Event{
+		{Seq: 3, Timestamp: base.Add(time.Second)},
+		{Seq: 2, Timestamp: base},
+		{Seq: 1, Timestamp: base},
+		// Without a timestamp, events are ordered by when they were read.
+		{Seq: 4, Received: base.Add(500 * time.Millisecond)},
+	} {
+		q.Push(ev)
+	}
+
+	if got := q.Peek().Seq; got != 1 {
+		t.Errorf("Peek: got event %d, want 1", got)
+	}
+	var seqs []uint64
+	for q.Len() > 0 {
+		seqs = append(seqs, q.Pop().Seq)
+	}
+	want := []uint64{1, 2, 4, 3}
+	for i := range want {
+		if i >= len(seqs) || seqs[i] != want[i] {
+			t.Fatalf("got events %v, want %v", seqs, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ig
+
+import (
+	"context"
+	"fmt"
+)
+
+// Output is a channel created by the library that StartWithOutput connects
+// the stdout of ig to, so consumers in other processes read the events
+// straight from ig.
+type Output struct {
+	kind string
+	path string
+}
+
+// FIFOOutput is a named pipe created at path. Consumers open it for reading;
+// ig blocks once the pipe buffer is full and nobody reads.
+func FIFOOutput(path string) Output {
+	return Output{kind: "fifo", path: path}
+}
+
+// SocketOutput is a unix socket listening at path. StartWithOutput waits for
+// a consumer to connect, bounded by its context, before starting ig.
+func SocketOutput(path string) Output {
+	return Output{kind: "socket", path: path}
+}
+
+func (o Output) String() string {
+	return o.kind + " " + o.path
+}
+
+// StartWithOutput runs a gadget in the background like Start, but writes its
+// JSON events to out instead of Events, which is closed right away: the
+// events never go through the memory of this process. The path of out is
+// removed once ig exits. Only supported on unix.
+func (ig *IG) StartWithOutput(ctx context.Context, image string, out Output, flags ...string) (*GadgetSession, error) {
+	s, err := ig.newSession(ctx, image, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	f, cleanup, err := out.open(ctx)
+	if err != nil {
+		s.abort()
+		return nil, fmt.Errorf("starting %s: opening %s: %w", image, out, err)
+	}
+	s.output = f
+	s.cleanup = cleanup
+
+	if err := s.launch(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package ig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+)
+
+func (o Output) open(ctx context.Context) (*os.File, func(), error) {
+	return nil, nil, errors.New("outputs are not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,60 @@
+//go:build unix
+
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// open creates the output and returns the file ig writes to, and a function
+// removing the output.
+func (o Output) open(ctx context.Context) (*os.File, func(), error) {
+	remove := func() { os.Remove(o.path) }
+
+	switch o.kind {
+	case "fifo":
+		if err := syscall.Mkfifo(o.path, 0o600); err != nil {
+			return nil, nil, err
+		}
+		// Opening read-write doesn't wait for a reader, unlike write-only.
+		f, err := os.OpenFile(o.path, os.O_RDWR, 0)
+		if err != nil {
+			remove()
+			return nil, nil, err
+		}
+		return f, remove, nil
+
+	case "socket":
+		l, err := net.ListenUnix("unix", &net.UnixAddr{Name: o.path, Net: "unix"})
+		if err != nil {
+			return nil, nil, err
+		}
+		defer l.Close()
+		l.SetUnlinkOnClose(false)
+
+		stop := context.AfterFunc(ctx, func() { l.Close() })
+		conn, err := l.AcceptUnix()
+		stop()
+		if err != nil {
+			remove()
+			if ctx.Err() != nil {
+				return nil, nil, fmt.Errorf("waiting for a consumer: %w", ctx.Err())
+			}
+			return nil, nil, err
+		}
+		defer conn.Close()
+
+		f, err := conn.File()
+		if err != nil {
+			remove()
+			return nil, nil, err
+		}
+		return f, remove, nil
+	}
+	return nil, nil, errors.New("unknown output")
+}
@@ -0,0 +1,75 @@
+package ig
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// OperationClass groups ig invocations by the privileges they need.
+type OperationClass string
+
+const (
+	// ClassRun runs and attaches to gadgets and manages their instances,
+	// which needs root or the eBPF capabilities.
+	ClassRun OperationClass = "run"
+	// ClassImage lists, inspects, pulls, builds, pushes and removes images,
+	// and probes the version of ig, none of which needs privileges.
+	ClassImage OperationClass = "image"
+)
+
+// classOf returns the class of the ig invocation with args.
+func classOf(args []string) OperationClass {
+	if len(args) > 0 && (args[0] == "image" || args[0] == "version") {
+		return ClassImage
+	}
+	return ClassRun
+}
+
+// Runner configures how ig runs for a class of operations, so services only
+// hold privileges for what needs them.
+type Runner struct {
+	// Prefix is prepended to the command line of ig, e.g. "sudo" "-n" to
+	// elevate runs from an unprivileged service. Process IDs, as returned by
+	// GadgetSession.PID, are then those of the first command of Prefix.
+	Prefix []string
+	// User, if not empty, runs ig as this user, by name or "uid[:gid]",
+	// e.g. for a service running as root to pull images as nobody. Only
+	// supported on unix, and by processes allowed to change their user.
+	// The user needs access to the image store of ig.
+	User string
+	// Env are "KEY=value" variables added for this class, e.g. HOME for the
+	// registry credentials of User.
+	Env []string
+}
+
+// runner is a Runner with its user resolved.
+type runner struct {
+	Runner
+	attr *syscall.SysProcAttr
+}
+
+// WithRunner runs the operations of class with r instead of running ig
+// directly as the current user.
+func WithRunner(class OperationClass, r Runner) Option {
+	return func(ig *IG) {
+		if ig.runners == nil {
+			ig.runners = map[OperationClass]*runner{}
+		}
+		ig.runners[class] = &runner{Runner: r}
+	}
+}
+
+// resolveRunners resolves the users of the runners of ig not resolved yet.
+func (ig *IG) resolveRunners() error {
+	for class, r := range ig.runners {
+		if r.User == "" || r.attr != nil {
+			continue
+		}
+		attr, err := userAttr(r.User)
+		if err != nil {
+			return fmt.Errorf("%s runner: %w", class, err)
+		}
+		r.attr = attr
+	}
+	return nil
+}
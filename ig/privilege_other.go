@@ -0,0 +1,13 @@
+//go:build !unix
+
+package ig
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+)
+
+func userAttr(name string) (*syscall.SysProcAttr, error) {
+	return nil, errors.New("running as another user is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package ig
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// userAttr returns the process attributes running processes as name, a user
+// name or "uid[:gid]".
+func userAttr(name string) (*syscall.SysProcAttr, error) {
+	var cred syscall.Credential
+	if uidStr, gidStr, ok := strings.Cut(name, ":"); ok || isNumber(name) {
+		uid, err := strconv.ParseUint(uidStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user %q", name)
+		}
+		cred.Uid, cred.Gid = uint32(uid), uint32(uid)
+		if ok {
+			gid, err := strconv.ParseUint(gidStr, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid user %q", name)
+			}
+			cred.Gid = uint32(gid)
+		}
+		// Without a name, no supplementary groups are known: drop them.
+		cred.Groups = []uint32{}
+		return &syscall.SysProcAttr{Credential: &cred}, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+	gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+	cred.Uid, cred.Gid = uint32(uid), uint32(gid)
+	cred.Groups = []uint32{}
+	if groups, err := u.GroupIds(); err == nil {
+		for _, g := range groups {
+			if id, err := strconv.ParseUint(g, 10, 32); err == nil {
+				cred.Groups = append(cred.Groups, uint32(id))
+			}
+		}
+	}
+	return &syscall.SysProcAttr{Credential: &cred}, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package ig
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup bounds the wait for the output of cmd once its context is
+// done. Process groups are a Unix notion: only ig itself is killed.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = WaitDelay
+}
+
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,190 @@
+package ig
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Types of provenance documents.
+const (
+	InTotoStatementType = "https://in-toto.io/Statement/v1"
+	SLSAProvenanceType  = "https://slsa.dev/provenance/v1"
+	// ProvenanceBuildType identifies gadget runs in provenance documents.
+	ProvenanceBuildType = "https://github.com/pawarpranav83/ig-testing-framework/run/v1"
+	// DSSEPayloadType is the payload type of signed statements.
+	DSSEPayloadType = "application/vnd.in-toto+json"
+)
+
+// Provenance records how a gadget run came about, for audit trails of
+// debugging actions in production.
+type Provenance struct {
+	// RunID identifies the run, as the invocation ID of the statement.
+	RunID string
+	Image string
+	// Digest is the sha256 digest of the image, hex-encoded. It is only
+	// known for images pinned by digest ("image@sha256:..."); set it
+	// otherwise.
+	Digest    string
+	Flags     []string
+	IGVersion Version
+	Host      string
+	Started   time.Time
+	Finished  time.Time
+	// Outputs are the files the run produced, hashed into the subjects of
+	// the statement.
+	Outputs []string
+}
+
+// Provenance returns the provenance of the run, with the files of Dir and
+// the given ones, such as sink files, as outputs. It must be called before
+// Close.
+func (r *RunResult) Provenance(outputs ...string) (*Provenance, error) {
+	p := r.prov
+	p.Flags = append([]string(nil), p.Flags...)
+	if r.Dir != "" {
+		err := filepath.WalkDir(r.Dir, func(path string, d os.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				p.Outputs = append(p.Outputs, path)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing outputs: %w", err)
+		}
+	}
+	p.Outputs = append(p.Outputs, outputs...)
+	return &p, nil
+}
+
+// newProvenance starts the provenance of a run of image.
+func (ig *IG) newProvenance(runID, image string, flags []string) Provenance {
+	host, _ := os.Hostname()
+	p := Provenance{
+		RunID:     runID,
+		Image:     image,
+		Flags:     flags,
+		IGVersion: ig.Version(),
+		Host:      host,
+		Started:   time.Now(),
+	}
+	if _, d, ok := strings.Cut(image, "@sha256:"); ok {
+		p.Digest = d
+	}
+	return p
+}
+
+// Statement returns the provenance as an in-toto statement with a SLSA
+// provenance predicate, the outputs being its subjects. The document only
+// depends on the recorded values and output contents, so it is reproducible.
+func (p *Provenance) Statement() ([]byte, error) {
+	type digestSet map[string]string
+	type subject struct {
+		Name   string    `json:"name"`
+		Digest digestSet `json:"digest"`
+	}
+	subjects := []subject{}
+	outputs := append([]string(nil), p.Outputs...)
+	sort.Strings(outputs)
+	for _, path := range outputs {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil, fmt.Errorf("hashing output: %w", err)
+		}
+		subjects = append(subjects, subject{Name: filepath.Base(path), Digest: digestSet{"sha256": sum}})
+	}
+
+	dep := map[string]any{"uri": p.Image}
+	if p.Digest != "" {
+		dep["digest"] = digestSet{"sha256": p.Digest}
+	}
+	flags := p.Flags
+	if flags == nil {
+		flags = []string{}
+	}
+	return json.MarshalIndent(map[string]any{
+		"_type":         InTotoStatementType,
+		"subject":       subjects,
+		"predicateType": SLSAProvenanceType,
+		"predicate": map[string]any{
+			"buildDefinition": map[string]any{
+				"buildType": ProvenanceBuildType,
+				"externalParameters": map[string]any{
+					"image": p.Image,
+					"flags": flags,
+				},
+				"internalParameters": map[string]any{
+					"igVersion": p.IGVersion.String(),
+					"host":      p.Host,
+				},
+				"resolvedDependencies": []any{dep},
+			},
+			"runDetails": map[string]any{
+				"builder": map[string]any{"id": "https://github.com/inspektor-gadget/inspektor-gadget/releases/tag/" + p.IGVersion.String()},
+				"metadata": map[string]any{
+					"invocationId": p.RunID,
+					"startedOn":    p.Started.UTC().Format(time.RFC3339Nano),
+					"finishedOn":   p.Finished.UTC().Format(time.RFC3339Nano),
+				},
+			},
+		},
+	}, "", "  ")
+}
+
+// Sign returns the statement of the provenance in a DSSE envelope signed
+// with key, identified by keyID. Ed25519 keys sign the payload itself,
+// others its SHA-256 digest.
+func (p *Provenance) Sign(key crypto.Signer, keyID string) ([]byte, error) {
+	payload, err := p.Statement()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pre-authentication encoding of DSSE.
+	msg := fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(DSSEPayloadType), DSSEPayloadType, len(payload), payload)
+	var opts crypto.SignerOpts = crypto.SHA256
+	if _, ok := key.Public().(ed25519.PublicKey); ok {
+		opts = crypto.Hash(0)
+	} else {
+		sum := sha256.Sum256(msg)
+		msg = sum[:]
+	}
+	sig, err := key.Sign(rand.Reader, msg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("signing provenance: %w", err)
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"payloadType": DSSEPayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures": []map[string]string{{
+			"keyid": keyID,
+			"sig":   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, "", "  ")
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
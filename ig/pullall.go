@@ -0,0 +1,76 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PullProgress reports the outcome of pulling one image of PullAll.
+type PullProgress struct {
+	Image string
+	// Err is the error of the pull, nil once the image is pulled.
+	Err error
+	// Took is how long the pull took.
+	Took time.Duration
+	// Done and Total count the finished pulls and every pull of PullAll.
+	Done, Total int
+}
+
+// PullAll pulls images like Pull, at most concurrency at a time (4 if zero
+// or less), e.g. to prefetch every gadget of a test suite in its setup.
+// Duplicate images are pulled once. It returns every failure, joined in the
+// order of images.
+// progress, if not nil, is called after each pull, from a single goroutine
+// at a time.
+func (ig *IG) PullAll(ctx context.Context, images []string, concurrency int, progress func(PullProgress)) error {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	seen := map[string]bool{}
+	var unique []string
+	for _, image := range images {
+		if !seen[image] {
+			seen[image] = true
+			unique = append(unique, image)
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		errs = make([]error, len(unique))
+		done int
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, image := range unique {
+		wg.Add(1)
+		go func(i int, image string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("pulling %s: %w", image, ctx.Err())
+				return
+			}
+			start := time.Now()
+			err := ig.Pull(ctx, image)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			done++
+			errs[i] = err
+			if progress != nil {
+				progress(PullProgress{Image: image, Err: err, Took: time.Since(start), Done: done, Total: len(unique)})
+			}
+		}(i, image)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
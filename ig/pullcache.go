@@ -0,0 +1,131 @@
+package ig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithPullCache records the digests of pulled images in the state file at
+// path, so Pull skips images already pulled, across processes, unless their
+// tag moved in the registry since. Images removed behind the back of the
+// library are still considered pulled; ig pulls them again when running
+// them, by default. If path is empty, a file in the user cache directory is
+// used.
+func WithPullCache(path string) Option {
+	return func(ig *IG) {
+		if path == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				dir = os.TempDir()
+			}
+			path = filepath.Join(dir, "ig-testing-framework", "pulls.json")
+		}
+		ig.pulls = &pullCache{path: path, client: http.DefaultClient}
+	}
+}
+
+// pullCache is the state file of WithPullCache, mapping images to the
+// digest they were pulled at.
+type pullCache struct {
+	path   string
+	client *http.Client
+	mu     sync.Mutex
+}
+
+type pulledImage struct {
+	Digest string    `json:"digest"`
+	Pulled time.Time `json:"pulled"`
+}
+
+func (c *pullCache) load() map[string]pulledImage {
+	images := map[string]pulledImage{}
+	if b, err := os.ReadFile(c.path); err == nil {
+		// A corrupt file only costs redundant pulls.
+		json.Unmarshal(b, &images)
+	}
+	return images
+}
+
+// update applies fn to the state file, replacing it atomically.
+func (c *pullCache) update(fn func(map[string]pulledImage)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	images := c.load()
+	fn(images)
+	b, err := json.MarshalIndent(images, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".pulls-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if err = errors.Join(err, tmp.Close()); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// fresh returns the current digest of image and whether it was already
+// pulled at that digest. The digest is empty if it can't be resolved, e.g.
+// for private registries, in which case the image must be pulled.
+func (c *pullCache) fresh(ctx context.Context, image string, flags []string, cred *Credential) (string, bool) {
+	for _, f := range flags {
+		// Insecure registries are reached over HTTP, which resolution
+		// doesn't do.
+		if strings.HasPrefix(f, "--insecure-registries") {
+			return "", false
+		}
+	}
+	digest, err := resolveDigest(ctx, c.client, image, cred)
+	if err != nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return digest, c.load()[image].Digest == digest
+}
+
+func (c *pullCache) record(image, digest string) error {
+	return c.update(func(images map[string]pulledImage) {
+		images[image] = pulledImage{Digest: digest, Pulled: time.Now()}
+	})
+}
+
+func (c *pullCache) forget(image string) error {
+	return c.update(func(images map[string]pulledImage) {
+		delete(images, image)
+	})
+}
+
+// ImageDigest returns the digest of image, such as "sha256:...", if it is
+// pinned by digest or, with WithPullCache, was pulled through the library.
+func (ig *IG) ImageDigest(image string) (string, bool) {
+	if d := parseReference(image).digest; d != "" {
+		return d, true
+	}
+	if ig.pulls == nil {
+		return "", false
+	}
+
+	ig.pulls.mu.Lock()
+	defer ig.pulls.mu.Unlock()
+
+	p, ok := ig.pulls.load()[image]
+	return p.Digest, ok && p.Digest != ""
+}
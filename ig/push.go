@@ -0,0 +1,83 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/retry"
+)
+
+// PushRetry configures PushWithRetry.
+type PushRetry struct {
+	// Attempts is the maximum number of pushes, 5 if zero.
+	Attempts int
+	// InitialBackoff is the delay before the second push, 1s if zero. It
+	// doubles after each failure, up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between pushes, 30s if zero.
+	MaxBackoff time.Duration
+	// Progress, if not nil, is called after each push attempt.
+	Progress func(PushProgress)
+}
+
+// PushProgress reports the outcome of one push attempt.
+type PushProgress struct {
+	Image   string
+	Attempt int
+	// Err is the error of the attempt, nil once the image is pushed.
+	Err error
+	// Retry is the delay before the next attempt, zero if there is none.
+	Retry time.Duration
+}
+
+// permanentPushRe matches registry errors that retrying won't fix.
+var permanentPushRe = regexp.MustCompile(`(?i)unauthorized|denied|forbidden|not found|invalid reference|manifest invalid`)
+
+// PushWithRetry pushes a gadget image like Push, retrying failed pushes with
+// exponential backoff. ig checks which blobs the registry already has before
+// uploading, so a retry only uploads the layers a failed attempt didn't
+// complete. Authentication and reference errors are not retried.
+func (ig *IG) PushWithRetry(ctx context.Context, image string, opts PushRetry, flags ...string) error {
+	b := retry.Backoff{
+		Attempts: opts.Attempts,
+		Initial:  opts.InitialBackoff,
+		Max:      opts.MaxBackoff,
+	}
+	if b.Attempts == 0 {
+		b.Attempts = 5
+	}
+	if b.Initial == 0 {
+		b.Initial = time.Second
+	}
+	if b.Max == 0 {
+		b.Max = 30 * time.Second
+	}
+
+	report := func(p PushProgress) {
+		if opts.Progress != nil {
+			opts.Progress(p)
+		}
+	}
+
+	attempts := 0
+	err := retry.Do(ctx, b, func(attempt int) error {
+		attempts = attempt
+		err := ig.Push(ctx, image, flags...)
+		var cerr *CommandError
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrReadOnly) || errors.As(err, &cerr) && permanentPushRe.MatchString(cerr.Stderr) {
+			return retry.Permanent(err)
+		}
+		return err
+	}, func(attempt int, err error, delay time.Duration) {
+		report(PushProgress{Image: image, Attempt: attempt, Err: err, Retry: delay})
+	})
+	report(PushProgress{Image: image, Attempt: attempts, Err: err})
+
+	if err != nil && attempts > 1 {
+		return fmt.Errorf("after %d attempts: %w", attempts, err)
+	}
+	return err
+}
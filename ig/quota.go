@@ -0,0 +1,133 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned, wrapped, by runs refused or stopped by the
+// Limits of an IG.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Limits bound the gadget runs of an IG, protecting shared nodes from abuse.
+// Runs count from Run or Start until ig exits. Zero values mean no limit.
+type Limits struct {
+	// MaxRuns bounds the concurrent runs.
+	MaxRuns int
+	// MaxRunsPerImage bounds the concurrent runs of each gadget, whatever
+	// the registry or tag of its image.
+	MaxRunsPerImage int
+	// MaxRunsPerTenant bounds the concurrent runs of each tenant (see
+	// WithTenant).
+	MaxRunsPerTenant int
+	// MaxCaptureBytes bounds the gadget output read on behalf of each
+	// tenant over the lifetime of the IG. Sessions of a tenant going past it
+	// are stopped, and its later runs refused; Run counts its output once
+	// ig exited. Output written to an Output isn't read by the library and
+	// doesn't count.
+	MaxCaptureBytes int64
+}
+
+// WithLimits enforces l on the runs of the IG.
+func WithLimits(l Limits) Option {
+	return func(ig *IG) {
+		ig.quota = &quota{
+			limits:    l,
+			perImage:  map[string]int{},
+			perTenant: map[string]int{},
+			bytes:     map[string]int64{},
+		}
+	}
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose runs count against the quotas of
+// tenant. Runs without a tenant share the quotas of the empty tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+type quota struct {
+	limits Limits
+
+	mu        sync.Mutex
+	runs      int
+	perImage  map[string]int
+	perTenant map[string]int
+	bytes     map[string]int64
+}
+
+// acquire reserves a run of image for tenant, returning the function
+// releasing it. A nil quota allows everything.
+func (q *quota) acquire(tenant, image string) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	gadget := GadgetName(image)
+	l := q.limits
+	switch {
+	case l.MaxRuns > 0 && q.runs >= l.MaxRuns:
+		return nil, fmt.Errorf("%w: %d concurrent runs", ErrQuotaExceeded, l.MaxRuns)
+	case l.MaxRunsPerImage > 0 && q.perImage[gadget] >= l.MaxRunsPerImage:
+		return nil, fmt.Errorf("%w: %d concurrent runs of %s", ErrQuotaExceeded, l.MaxRunsPerImage, gadget)
+	case l.MaxRunsPerTenant > 0 && q.perTenant[tenant] >= l.MaxRunsPerTenant:
+		return nil, fmt.Errorf("%w: %d concurrent runs of tenant %q", ErrQuotaExceeded, l.MaxRunsPerTenant, tenant)
+	case l.MaxCaptureBytes > 0 && q.bytes[tenant] >= l.MaxCaptureBytes:
+		return nil, fmt.Errorf("%w: %d capture bytes of tenant %q", ErrQuotaExceeded, l.MaxCaptureBytes, tenant)
+	}
+	q.runs++
+	q.perImage[gadget]++
+	q.perTenant[tenant]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+
+			q.runs--
+			q.perImage[gadget]--
+			q.perTenant[tenant]--
+		})
+	}, nil
+}
+
+// capture counts n bytes of output read for tenant, returning an error once
+// tenant is past its capture bytes.
+func (q *quota) capture(tenant string, n int) error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.bytes[tenant] += int64(n)
+	if limit := q.limits.MaxCaptureBytes; limit > 0 && q.bytes[tenant] > limit {
+		return fmt.Errorf("%w: %d capture bytes of tenant %q", ErrQuotaExceeded, limit, tenant)
+	}
+	return nil
+}
+
+// acquireRun reserves a run of image for the tenant of ctx.
+func (ig *IG) acquireRun(ctx context.Context, image string) (release func(), tenant string, err error) {
+	tenant, _ = TenantFromContext(ctx)
+	release, err = ig.quota.acquire(tenant, image)
+	if err != nil {
+		return nil, "", fmt.Errorf("running %s: %w", image, err)
+	}
+	return release, tenant, nil
+}
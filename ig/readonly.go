@@ -0,0 +1,26 @@
+package ig
+
+import "errors"
+
+// ErrReadOnly is returned for mutating operations of IGs created with
+// ReadOnly.
+var ErrReadOnly = errors.New("IG is read-only")
+
+// ReadOnly refuses every operation modifying images or instances: pushes,
+// removals, tags, builds, detached runs and instance deletions, so dashboards
+// embedding the library can't modify them by accident. Attached runs, and
+// the pulls they need, are still allowed.
+func ReadOnly() Option {
+	return func(ig *IG) {
+		ig.readOnly = true
+	}
+}
+
+// Mutating reports whether operations of kind modify images or instances.
+func (k OperationKind) Mutating() bool {
+	switch k {
+	case OpDetach, OpDeleteInstance, OpPush, OpRemoveImage, OpTag, OpBuild:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package ig
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// ReadyPattern matches the status lines ig prints on stderr once a gadget
+// is attached and tracing. Sessions are also ready once they print their
+// first event, whichever comes first.
+var ReadyPattern = regexp.MustCompile(`(?i)\btracing\b`)
+
+// readiness is closed once a session is ready.
+type readiness struct {
+	ch   chan struct{}
+	once sync.Once
+}
+
+func (r *readiness) set() {
+	r.once.Do(func() { close(r.ch) })
+}
+
+// readyWriter sets r once the stderr of ig, written to it, matches
+// ReadyPattern.
+type readyWriter struct {
+	r *readiness
+	// tail keeps the end of the previous writes, for lines split across
+	// writes.
+	tail []byte
+}
+
+func (w *readyWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.r.ch:
+		return len(p), nil
+	default:
+	}
+
+	w.tail = append(w.tail, p...)
+	if ReadyPattern.Match(w.tail) {
+		w.r.set()
+	}
+	if len(w.tail) > 256 {
+		w.tail = append(w.tail[:0], w.tail[len(w.tail)-256:]...)
+	}
+	return len(p), nil
+}
+
+// WaitReady waits until the gadget is attached and tracing, so workloads
+// don't race against its startup. It fails if the gadget exits first or ctx
+// is done.
+func (s *GadgetSession) WaitReady(ctx context.Context) error {
+	select {
+	case <-s.ready.ch:
+		return nil
+	default:
+	}
+
+	select {
+	case <-s.ready.ch:
+		return nil
+	case <-s.done:
+		select {
+		case <-s.ready.ch:
+			return nil
+		default:
+		}
+		if err := s.Wait(); err != nil {
+			return fmt.Errorf("%s exited before being ready: %w", s.image, err)
+		}
+		return fmt.Errorf("%s exited before being ready", s.image)
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for %s to be ready: %w", s.image, ctx.Err())
+	}
+}
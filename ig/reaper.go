@@ -0,0 +1,99 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// launcherEnv marks the ig processes started by this package with the PID
+// of the process that started them, so CleanupOrphans can recognize them
+// once their launcher is gone.
+const launcherEnv = "IG_FRAMEWORK_LAUNCHER"
+
+// WaitDelay is how long an ig invocation killed because its context is done
+// waits for its output to be closed, e.g. by processes that survived it,
+// before giving up on the rest of the output.
+var WaitDelay = 5 * time.Second
+
+// command returns an exec.Cmd running ig with args and the environment of
+// ig, with the runner of the class of args, if any. ig runs in a process
+// group of its own, killed when ctx is done.
+func (ig *IG) command(ctx context.Context, args ...string) *exec.Cmd {
+	r := ig.runners[classOf(args)]
+	var cmd *exec.Cmd
+	if r != nil && len(r.Prefix) > 0 {
+		cmd = exec.CommandContext(ctx, r.Prefix[0], append(append(r.Prefix[1:len(r.Prefix):len(r.Prefix)], ig.path), args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, ig.path, args...)
+	}
+	cmd.Env = append(os.Environ(), launcherEnv+"="+strconv.Itoa(os.Getpid()))
+	cmd.Env = append(cmd.Env, ig.env...)
+	if r != nil {
+		cmd.Env = append(cmd.Env, r.Env...)
+		if r.attr != nil {
+			attr := *r.attr
+			cmd.SysProcAttr = &attr
+		}
+	}
+	setProcessGroup(cmd)
+	return cmd
+}
+
+// running holds the PIDs of the ig processes started and not yet waited for,
+// which the reaper must leave to os/exec.
+var running sync.Map
+
+func startTracked(cmd *exec.Cmd) error {
+	// Hold the lock the reaper takes, so it can't reap the process between
+	// its start and its registration.
+	reapMu.Lock()
+	defer reapMu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	running.Store(cmd.Process.Pid, struct{}{})
+	return nil
+}
+
+func waitTracked(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	running.Delete(cmd.Process.Pid)
+	return err
+}
+
+var reapMu sync.Mutex
+
+var errReaperUnsupported = errors.New("process reaping is not supported on " + runtime.GOOS)
+
+// OrphanGrace is how long CleanupOrphans lets orphaned ig processes exit
+// before killing them.
+var OrphanGrace = 5 * time.Second
+
+// InitAgent prepares a long-running agent process: it cleans up the ig
+// processes orphaned by a previous instance and, when running as PID 1 of a
+// container, starts the zombie reaper (see StartReaper). stop stops the
+// reaper, if started.
+func InitAgent() (stop func(), err error) {
+	stop = func() {}
+	if _, err := CleanupOrphans(); errors.Is(err, errReaperUnsupported) {
+		return stop, nil
+	} else if err != nil {
+		return stop, fmt.Errorf("initializing agent: %w", err)
+	}
+	if os.Getpid() == 1 {
+		s, err := StartReaper()
+		if err != nil {
+			return stop, fmt.Errorf("initializing agent: %w", err)
+		}
+		stop = s
+	}
+	return stop, nil
+}
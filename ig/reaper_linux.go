@@ -0,0 +1,150 @@
+//go:build linux
+
+package ig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StartReaper starts reaping the zombie children of the process, for agents
+// running as PID 1 of a container, which inherit every orphan of the
+// container. Children started by this package are left to it. Call stop to
+// stop reaping.
+//
+// Children the program starts itself with os/exec race with the reaper,
+// which may collect them before their Wait, so only use it in programs
+// running processes through this package.
+func StartReaper() (stop func(), err error) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGCHLD)
+
+	done := make(chan struct{})
+	go func() {
+		// The periodic pass catches SIGCHLDs coalesced while reaping.
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigs:
+			case <-ticker.C:
+			}
+			reapZombies()
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}, nil
+}
+
+func reapZombies() {
+	reapMu.Lock()
+	defer reapMu.Unlock()
+
+	self := os.Getpid()
+	for _, p := range listProcesses() {
+		if p.ppid != self || p.state != "Z" {
+			continue
+		}
+		if _, ok := running.Load(p.pid); ok {
+			continue
+		}
+		var ws syscall.WaitStatus
+		syscall.Wait4(p.pid, &ws, syscall.WNOHANG, nil)
+	}
+}
+
+// CleanupOrphans terminates the ig processes started by this package whose
+// launcher is gone, e.g. after a crash of the agent, and returns their PIDs.
+// They get OrphanGrace to exit after SIGTERM before being killed.
+func CleanupOrphans() ([]int, error) {
+	var orphans []int
+	for _, p := range listProcesses() {
+		launcher, ok := launcherOf(p.pid)
+		if !ok || p.ppid == launcher {
+			continue
+		}
+		if syscall.Kill(p.pid, syscall.SIGTERM) == nil {
+			orphans = append(orphans, p.pid)
+		}
+	}
+
+	deadline := time.Now().Add(OrphanGrace)
+	var errs []error
+	for _, pid := range orphans {
+		for syscall.Kill(pid, 0) == nil && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			errs = append(errs, fmt.Errorf("killing orphaned ig %d: %w", pid, err))
+		}
+	}
+	return orphans, errors.Join(errs...)
+}
+
+// launcherOf returns the launcher PID recorded in the environment of pid,
+// if it was started by this package.
+func launcherOf(pid int) (int, bool) {
+	env, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/environ")
+	if err != nil {
+		return 0, false
+	}
+	prefix := []byte(launcherEnv + "=")
+	for _, kv := range bytes.Split(env, []byte{0}) {
+		if v, ok := bytes.CutPrefix(kv, prefix); ok {
+			launcher, err := strconv.Atoi(string(v))
+			return launcher, err == nil
+		}
+	}
+	return 0, false
+}
+
+type process struct {
+	pid, ppid int
+	state     string
+}
+
+func listProcesses() []process {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var procs []process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// "pid (comm) state ppid ...", comm may contain spaces.
+		end := bytes.LastIndexByte(stat, ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[end+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		procs = append(procs, process{pid: pid, ppid: ppid, state: fields[0]})
+	}
+	return procs
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ig
+
+// StartReaper is only supported on Linux.
+func StartReaper() (stop func(), err error) {
+	return nil, errReaperUnsupported
+}
+
+// CleanupOrphans is only supported on Linux.
+func CleanupOrphans() ([]int, error) {
+	return nil, errReaperUnsupported
+}
@@ -0,0 +1,177 @@
+package ig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultRegistry is where ig looks up gadgets given by bare name, such as
+// "trace_exec".
+const DefaultRegistry = "ghcr.io/inspektor-gadget/gadget"
+
+// reference is a parsed image reference.
+type reference struct {
+	registry   string
+	repository string
+	tag        string
+	// digest is set for references pinned by digest ("sha256:...").
+	digest string
+}
+
+// parseReference parses image the way ig does, bare names resolving to
+// DefaultRegistry and missing tags to "latest".
+func parseReference(image string) reference {
+	var r reference
+	if name, d, ok := strings.Cut(image, "@"); ok {
+		image, r.digest = name, d
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, r.tag = image[:i], image[i+1:]
+	}
+	if r.tag == "" {
+		r.tag = "latest"
+	}
+	if !strings.Contains(image, "/") {
+		image = DefaultRegistry + "/" + image
+	}
+	first, rest, _ := strings.Cut(image, "/")
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		r.registry, r.repository = first, rest
+	} else {
+		r.registry, r.repository = "registry-1.docker.io", image
+	}
+	if r.registry == "docker.io" {
+		r.registry = "registry-1.docker.io"
+	}
+	return r
+}
+
+var manifestTypes = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// resolveDigest asks the registry of image for the digest its tag points
+// to, authenticating with cred, if not nil, or anonymous bearer tokens when
+// the registry requires them.
+func resolveDigest(ctx context.Context, client *http.Client, image string, cred *Credential) (string, error) {
+	ref := parseReference(image)
+	if ref.digest != "" {
+		return ref.digest, nil
+	}
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.registry, ref.repository, ref.tag)
+
+	head := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", manifestTypes)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	resp, err := head("")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := authorize(ctx, client, resp.Header.Get("WWW-Authenticate"), cred)
+		if err != nil {
+			return "", fmt.Errorf("authenticating to %s: %w", ref.registry, err)
+		}
+		if resp, err = head(authorization); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resolving %s: %s", image, resp.Status)
+	}
+	d := resp.Header.Get("Docker-Content-Digest")
+	if d == "" {
+		return "", fmt.Errorf("resolving %s: no digest in response", image)
+	}
+	return d, nil
+}
+
+var challengeRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize returns the Authorization header answering the challenge of a
+// registry: basic authentication with cred, or a bearer token, anonymous if
+// cred is nil.
+func authorize(ctx context.Context, client *http.Client, challenge string, cred *Credential) (string, error) {
+	scheme, _, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") && cred != nil && cred.Username != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password)), nil
+	}
+	token, err := bearerToken(ctx, client, challenge, cred)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// bearerToken gets a token for the Bearer challenge of a registry, with the
+// user name and password of cred, if not nil.
+func bearerToken(ctx context.Context, client *http.Client, challenge string, cred *Credential) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported challenge %q", challenge)
+	}
+	p := map[string]string{}
+	for _, m := range challengeRe.FindAllStringSubmatch(params, -1) {
+		p[m[1]] = m[2]
+	}
+	if p["realm"] == "" {
+		return "", fmt.Errorf("no realm in challenge %q", challenge)
+	}
+
+	q := url.Values{}
+	for _, k := range []string{"service", "scope"} {
+		if p[k] != "" {
+			q.Set(k, p[k])
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if cred != nil && cred.Username != "" {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting token: %s", resp.Status)
+	}
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("decoding token: %w", err)
+	}
+	if t.Token == "" {
+		t.Token = t.AccessToken
+	}
+	return t.Token, nil
+}
@@ -0,0 +1,130 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RunResult is the captured output of a gadget run.
+type RunResult struct {
+	// RunID identifies the run, see WithRunID.
+	RunID  string
+	Stdout string
+	Stderr string
+	// StdoutTruncated and StderrTruncated count the bytes discarded past
+	// the output cap of the IG (see WithMaxOutput). Truncated output ends
+	// with a marker.
+	StdoutTruncated int64
+	StderrTruncated int64
+	// ExitCode is the exit code of ig, -1 if it was killed by a signal, e.g.
+	// on cancellation, or didn't start.
+	ExitCode int
+	// Duration is how long ig ran.
+	Duration time.Duration
+	// Dir is the temporary working directory ig ran in, holding whatever
+	// files the run generated. It is removed by Close.
+	Dir string
+	// Host is the fingerprint of the host the gadget ran on.
+	Host HostFingerprint
+	// Warnings are the warnings ig printed on stderr, see ParseStderr.
+	Warnings []StderrRecord
+	// Completeness tells whether Stdout holds every event the gadget
+	// traced.
+	Completeness Completeness
+
+	keep bool
+	prov Provenance
+}
+
+// Close removes the working directory of the run, unless the IG was created
+// with WithKeepArtifacts.
+func (r *RunResult) Close() error {
+	if r.keep || r.Dir == "" {
+		return nil
+	}
+	return os.RemoveAll(r.Dir)
+}
+
+// Run runs a gadget until it exits, passing flags to ig run. Tracing gadgets
+// run until interrupted, so callers bound them with "--timeout" or a context
+// deadline. The result is returned even when ig fails, with whatever the
+// gadget printed and its exit code; callers Close it to remove its working
+// directory.
+func (ig *IG) Run(ctx context.Context, image string, flags ...string) (*RunResult, error) {
+	ctx, runID, err := ensureRunID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flags, err = ig.recommend(ctx, image, ig.withRunFlags(flags))
+	if err != nil {
+		return nil, err
+	}
+	if err := ig.authorizeRun(ctx, image, flags); err != nil {
+		return nil, err
+	}
+	flags, cleanup, err := ig.withAuth(ctx, image, flags)
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", image, err)
+	}
+	defer cleanup()
+	release, tenant, err := ig.acquireRun(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	dir, err := os.MkdirTemp("", "ig-run-"+GadgetName(image)+"-"+runID+"-")
+	if err != nil {
+		return nil, fmt.Errorf("running %s: creating working directory: %w", image, err)
+	}
+
+	prov := ig.newProvenance(runID, image, flags)
+	args := append([]string{"run", image}, flags...)
+	out, err := ig.execIn(ctx, dir, args...)
+	prov.Finished = time.Now()
+	if ig.history != nil {
+		record := ig.newRunRecord(runID, image, flags, prov.Started)
+		record.Finished = prov.Finished
+		record.OutputBytes = int64(len(out.stdout)) + out.stdoutTruncated
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if ig.keepArtifacts {
+			record.Artifacts = []string{dir}
+		}
+		if herr := ig.history.Record(record); herr != nil {
+			err = errors.Join(err, herr)
+		}
+	}
+	// The output is already read: going past the capture bytes only
+	// refuses later runs.
+	ig.quota.capture(tenant, len(out.stdout))
+	res := &RunResult{
+		RunID:           runID,
+		Stdout:          out.stdout,
+		Stderr:          out.stderr,
+		StdoutTruncated: out.stdoutTruncated,
+		StderrTruncated: out.stderrTruncated,
+		ExitCode:        out.exitCode,
+		Duration:        prov.Finished.Sub(prov.Started),
+		Dir:             dir,
+		Host:            Fingerprint(),
+		Warnings:        warnings(ParseStderr(out.stderr)),
+		keep:            ig.keepArtifacts,
+		prov:            prov,
+	}
+	res.Completeness = Completeness{
+		Lost:      LostEvents(out.stderr),
+		Truncated: out.stdoutTruncated > 0 || out.stderrTruncated > 0,
+	}
+	if err == nil && ig.failOnWarning && len(res.Warnings) > 0 {
+		err = warningErr(res.Warnings)
+	}
+	if err != nil {
+		return res, fmt.Errorf("running %s: %w", image, err)
+	}
+	return res, nil
+}
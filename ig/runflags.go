@@ -0,0 +1,265 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output modes of ig run.
+const (
+	OutputJSON       = "json"
+	OutputJSONPretty = "jsonpretty"
+	OutputColumns    = "columns"
+	OutputYAML       = "yaml"
+)
+
+// Pull policies of ig run.
+const (
+	PullAlways  = "always"
+	PullMissing = "missing"
+	PullNever   = "never"
+)
+
+// RunFlags are the stable flags of ig run as typed options, for use with
+// RunWith or StartWith, or with Run and Start through Args:
+//
+//	flags := ig.RunFlags{Timeout: 5 * time.Second, Filter: []string{"proc.comm==curl"}}
+//	res, err := i.RunWith(ctx, "trace_exec", flags)
+//
+// Zero values leave the flag out, so ig applies its default. Conflicting
+// options, e.g. Host with ContainerName, are rejected. Check verifies the
+// flags against the capabilities of a given ig binary.
+type RunFlags struct {
+	// Output is the output mode (--output), one of the Output constants.
+	// Leave it empty with Start, which sets it.
+	Output string
+	// Fields restricts the fields shown (--fields).
+	Fields []string
+
+	// Filter keeps the events matching every expression (--filter), e.g.
+	// "proc.comm==curl".
+	Filter []string
+	// PID restricts tracing to the process pid, as a filter on proc.pid.
+	// Its children are not traced; use MountNamespace or Cgroup for a
+	// process tree.
+	PID int
+	// MountNamespace restricts tracing to the processes of a mount
+	// namespace, as a filter on proc.mntns_id. See MountNamespace.
+	MountNamespace uint64
+	// Cgroup restricts tracing to the mount namespace of the processes of
+	// a cgroup, given by its path, absolute or relative to /sys/fs/cgroup.
+	// See CgroupMountNamespace.
+	Cgroup string
+
+	// Timeout stops the gadget after this long (--timeout). ig takes whole
+	// seconds, so it is rounded up.
+	Timeout time.Duration
+	// Host traces the whole host rather than containers only (--host).
+	Host bool
+	// ContainerName restricts tracing to a container (--containername).
+	ContainerName string
+	// Runtimes are the container runtimes to follow (--runtimes).
+	Runtimes []string
+
+	// Sort orders the events of snapshot gadgets (--sort), e.g. "-pid".
+	Sort []string
+	// MaxEntries caps the number of entries per interval of snapshot and
+	// top gadgets (--max-entries).
+	MaxEntries int
+	// Pull is the image pull policy (--pull), one of the Pull constants.
+	Pull string
+	// VerifyImage, if not nil, enables or disables the signature
+	// verification of the image (--verify-image).
+	VerifyImage *bool
+
+	// Params are gadget parameters, by flag name without dashes, e.g.
+	// "operator.oci.ebpf.paths": "true". They can't set a flag of RunFlags.
+	Params map[string]string
+}
+
+type runFlag struct {
+	name     string
+	category string
+	value    string
+	// inline flags are passed as --name=value rather than as two
+	// arguments: bool flags, whose value would otherwise be taken for an
+	// argument, and gadget params, whose values may start with a dash.
+	inline bool
+}
+
+func (f RunFlags) flags() ([]runFlag, error) {
+	var flags []runFlag
+	var errs []error
+	add := func(category, name, value string) {
+		flags = append(flags, runFlag{name: name, category: category, value: value})
+	}
+
+	// Output
+	switch f.Output {
+	case "", OutputJSON, OutputJSONPretty, OutputColumns, OutputYAML:
+		if f.Output != "" {
+			add("output", "output", f.Output)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid output mode %q", f.Output))
+	}
+	if len(f.Fields) > 0 {
+		add("output", "fields", strings.Join(f.Fields, ","))
+	}
+
+	// Filtering
+	targets, err := f.targetFilters()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if filter := append(f.Filter[:len(f.Filter):len(f.Filter)], targets...); len(filter) > 0 {
+		add("filtering", "filter", strings.Join(filter, ","))
+	}
+
+	// Runtime
+	switch {
+	case f.Timeout < 0:
+		errs = append(errs, fmt.Errorf("invalid timeout %s", f.Timeout))
+	case f.Timeout > 0:
+		secs := (f.Timeout + time.Second - 1) / time.Second
+		add("runtime", "timeout", strconv.FormatInt(int64(secs), 10))
+	}
+	if f.Host {
+		flags = append(flags, runFlag{name: "host", category: "runtime", value: "true", inline: true})
+	}
+	if f.ContainerName != "" {
+		add("runtime", "containername", f.ContainerName)
+	}
+	if f.Host && f.ContainerName != "" {
+		errs = append(errs, errors.New("host and container name are mutually exclusive"))
+	}
+	if f.Host && (f.MountNamespace != 0 || f.Cgroup != "") {
+		errs = append(errs, errors.New("host and mount namespace or cgroup are mutually exclusive"))
+	}
+	if len(f.Runtimes) > 0 {
+		add("runtime", "runtimes", strings.Join(f.Runtimes, ","))
+	}
+
+	// Operators
+	if len(f.Sort) > 0 {
+		add("operators", "sort", strings.Join(f.Sort, ","))
+	}
+	switch {
+	case f.MaxEntries < 0:
+		errs = append(errs, fmt.Errorf("invalid max entries %d", f.MaxEntries))
+	case f.MaxEntries > 0:
+		add("operators", "max-entries", strconv.Itoa(f.MaxEntries))
+	}
+	switch f.Pull {
+	case "", PullAlways, PullMissing, PullNever:
+		if f.Pull != "" {
+			add("operators", "pull", f.Pull)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid pull policy %q", f.Pull))
+	}
+	if f.VerifyImage != nil {
+		flags = append(flags, runFlag{name: "verify-image", category: "operators", value: strconv.FormatBool(*f.VerifyImage), inline: true})
+	}
+
+	// Gadget parameters
+	typed := map[string]bool{}
+	for _, fl := range flags {
+		typed[fl.name] = true
+	}
+	keys := make([]string, 0, len(f.Params))
+	for k := range f.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.TrimLeft(k, "-")
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Errorf("invalid param %q", k))
+		case typed[name] || runFlagNames[name]:
+			errs = append(errs, fmt.Errorf("param %q duplicates a flag of RunFlags", k))
+		default:
+			flags = append(flags, runFlag{name: name, category: "gadget", value: f.Params[k], inline: true})
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid run flags: %w", err)
+	}
+	return flags, nil
+}
+
+// runFlagNames are the ig run flags set by the fields of RunFlags.
+var runFlagNames = map[string]bool{
+	"output": true, "fields": true, "filter": true, "timeout": true,
+	"host": true, "containername": true, "runtimes": true, "sort": true,
+	"max-entries": true, "pull": true, "verify-image": true,
+}
+
+// RunWith runs a gadget as Run does, with typed flags.
+func (ig *IG) RunWith(ctx context.Context, image string, f RunFlags) (*RunResult, error) {
+	args, err := f.Args()
+	if err != nil {
+		return nil, err
+	}
+	return ig.Run(ctx, image, args...)
+}
+
+// StartWith starts a gadget as Start does, with typed flags. f.Output must
+// be left empty.
+func (ig *IG) StartWith(ctx context.Context, image string, f RunFlags) (*GadgetSession, error) {
+	if f.Output != "" {
+		return nil, fmt.Errorf("invalid run flags: output mode %q set for a session", f.Output)
+	}
+	args, err := f.Args()
+	if err != nil {
+		return nil, err
+	}
+	return ig.Start(ctx, image, args...)
+}
+
+// Args validates the flags and returns them as arguments of ig run.
+func (f RunFlags) Args() ([]string, error) {
+	flags, err := f.flags()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, fl := range flags {
+		if fl.inline {
+			args = append(args, "--"+fl.name+"="+fl.value)
+			continue
+		}
+		args = append(args, "--"+fl.name, fl.value)
+	}
+	return args, nil
+}
+
+// Check returns an error listing the flags set in f that ig run doesn't
+// accept in the binary c was introspected from, e.g. operator flags of an
+// operator that isn't compiled in.
+func (f RunFlags) Check(c *Capabilities) error {
+	flags, err := f.flags()
+	if err != nil {
+		return err
+	}
+
+	var unsupported []string
+	for _, fl := range flags {
+		// Gadget parameters depend on the image, not on the binary.
+		if fl.category != "gadget" && !c.HasFlag("run", fl.name) {
+			unsupported = append(unsupported, fmt.Sprintf("--%s (%s)", fl.name, fl.category))
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("ig %s run doesn't support %s", c.Version, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
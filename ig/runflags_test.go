@@ -0,0 +1,178 @@
+package ig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunFlagsArgs(t *testing.T) {
+	yes, no := true, false
+	pid := os.Getpid()
+	tests := []struct {
+		name  string
+		flags RunFlags
+		want  []string
+	}{
+		{name: "zero", flags: RunFlags{}, want: nil},
+		{
+			name:  "output",
+			flags: RunFlags{Output: OutputJSON, Fields: []string{"proc.comm", "proc.pid"}},
+			want:  []string{"--output", "json", "--fields", "proc.comm,proc.pid"},
+		},
+		{
+			name:  "filters",
+			flags: RunFlags{Filter: []string{"proc.comm==curl", "dst.port>=1024"}},
+			want:  []string{"--filter", "proc.comm==curl,dst.port>=1024"},
+		},
+		{
+			name:  "targets",
+			flags: RunFlags{Filter: []string{"proc.comm==curl"}, PID: pid, MountNamespace: 4026531840},
+			want:  []string{"--filter", fmt.Sprintf("proc.comm==curl,proc.pid==%d,proc.mntns_id==4026531840", pid)},
+		},
+		{name: "timeout", flags: RunFlags{Timeout: 5 * time.Second}, want: []string{"--timeout", "5"}},
+		{name: "timeout rounded up", flags: RunFlags{Timeout: 1500 * time.Millisecond}, want: []string{"--timeout", "2"}},
+		{name: "sub-second timeout", flags: RunFlags{Timeout: time.Millisecond}, want: []string{"--timeout", "1"}},
+		{name: "host", flags: RunFlags{Host: true, Runtimes: []string{"docker", "containerd"}}, want: []string{"--host=true", "--runtimes", "docker,containerd"}},
+		{name: "container", flags: RunFlags{ContainerName: "web"}, want: []string{"--containername", "web"}},
+		{
+			name:  "operators",
+			flags: RunFlags{Sort: []string{"-pid", "comm"}, MaxEntries: 10, Pull: PullNever, VerifyImage: &no},
+			want:  []string{"--sort", "-pid,comm", "--max-entries", "10", "--pull", "never", "--verify-image=false"},
+		},
+		{name: "verify image", flags: RunFlags{VerifyImage: &yes}, want: []string{"--verify-image=true"}},
+		{
+			name: "params sorted and inline",
+			flags: RunFlags{Params: map[string]string{
+				"operator.oci.ebpf.paths": "true",
+				"--iface":                 "-eth0",
+				"map-fetch-interval":      "",
+			}},
+			want: []string{"--iface=-eth0", "--map-fetch-interval=", "--operator.oci.ebpf.paths=true"},
+		},
+		{
+			name: "every category",
+			flags: RunFlags{
+				Output:   OutputColumns,
+				Filter:   []string{"proc.uid==0"},
+				Timeout:  time.Minute,
+				Host:     true,
+				Sort:     []string{"pid"},
+				Pull:     PullMissing,
+				Params:   map[string]string{"operator.oci.ebpf.paths": "true"},
+				Runtimes: []string{"cri-o"},
+			},
+			want: []string{
+				"--output", "columns",
+				"--filter", "proc.uid==0",
+				"--timeout", "60",
+				"--host=true",
+				"--runtimes", "cri-o",
+				"--sort", "pid",
+				"--pull", "missing",
+				"--operator.oci.ebpf.paths=true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.flags.Args()
+			if err != nil {
+				t.Fatalf("Args: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFlagsArgsDoesNotModifyFilter(t *testing.T) {
+	filter := make([]string, 1, 4)
+	filter[0] = "proc.comm==curl"
+	f := RunFlags{Filter: filter, MountNamespace: 1}
+	if _, err := f.Args(); err != nil {
+		t.Fatal(err)
+	}
+	if got := filter[:2]; got[1] != "" {
+		t.Errorf("Args wrote to the Filter backing array: %q", got)
+	}
+}
+
+func TestRunFlagsArgsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags RunFlags
+		errs  []string
+	}{
+		{name: "output", flags: RunFlags{Output: "xml"}, errs: []string{`invalid output mode "xml"`}},
+		{name: "timeout", flags: RunFlags{Timeout: -time.Second}, errs: []string{"invalid timeout -1s"}},
+		{name: "max entries", flags: RunFlags{MaxEntries: -1}, errs: []string{"invalid max entries -1"}},
+		{name: "pull", flags: RunFlags{Pull: "sometimes"}, errs: []string{`invalid pull policy "sometimes"`}},
+		{name: "pid", flags: RunFlags{PID: -1}, errs: []string{"invalid pid -1"}},
+		{name: "missing process", flags: RunFlags{PID: 1 << 30}, errs: []string{fmt.Sprintf("no process %d", 1<<30)}},
+		{name: "host and container", flags: RunFlags{Host: true, ContainerName: "web"}, errs: []string{"host and container name are mutually exclusive"}},
+		{name: "host and mount namespace", flags: RunFlags{Host: true, MountNamespace: 1}, errs: []string{"host and mount namespace or cgroup are mutually exclusive"}},
+		{name: "empty param", flags: RunFlags{Params: map[string]string{"--": "x"}}, errs: []string{`invalid param "--"`}},
+		{name: "param of a typed flag", flags: RunFlags{Params: map[string]string{"--timeout": "5"}}, errs: []string{`param "--timeout" duplicates a flag of RunFlags`}},
+		{name: "param of a set flag", flags: RunFlags{Host: true, Params: map[string]string{"host": "false"}}, errs: []string{`param "host" duplicates a flag of RunFlags`}},
+		{
+			name:  "every error",
+			flags: RunFlags{Output: "xml", Pull: "sometimes", Host: true, ContainerName: "web"},
+			errs:  []string{`invalid output mode "xml"`, `invalid pull policy "sometimes"`, "host and container name are mutually exclusive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tt.flags.Args()
+			if err == nil {
+				t.Fatalf("Args succeeded: %q", args)
+			}
+			if !strings.HasPrefix(err.Error(), "invalid run flags: ") {
+				t.Errorf("Args: %v, want an invalid run flags error", err)
+			}
+			for _, e := range tt.errs {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("Args: %v, want an error containing %q", err, e)
+				}
+			}
+		})
+	}
+}
+
+func TestRunFlagsCheck(t *testing.T) {
+	c := &Capabilities{
+		Version: Version{Major: 0, Minor: 30},
+		Commands: map[string][]Flag{
+			"run": {{Name: "output"}, {Name: "timeout"}, {Name: "host"}, {Name: "filter"}},
+		},
+	}
+	tests := []struct {
+		name  string
+		flags RunFlags
+		err   string
+	}{
+		{name: "supported", flags: RunFlags{Output: OutputJSON, Timeout: time.Second, Host: true}},
+		{name: "params aren't checked", flags: RunFlags{Params: map[string]string{"operator.oci.ebpf.paths": "true"}}},
+		{
+			name:  "unsupported",
+			flags: RunFlags{Filter: []string{"a==b"}, Sort: []string{"pid"}, VerifyImage: new(bool)},
+			err:   "ig v0.30.0 run doesn't support --sort (operators), --verify-image (operators)",
+		},
+		{name: "invalid", flags: RunFlags{Output: "xml"}, err: `invalid output mode "xml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flags.Check(c)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Check: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Check: %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package ig
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRunID is returned by operations whose context has a run ID set
+// with WithRunID that can't be part of a file name.
+var ErrInvalidRunID = errors.New("invalid run ID")
+
+type runIDKey struct{}
+
+// NewRunID returns a new run identifier, a random (version 4) UUID.
+func NewRunID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// WithRunID returns a context whose operations use id as run ID instead of
+// generating one, e.g. the ID of the request a service runs a gadget for.
+// Run IDs name artifacts, so they may only hold ASCII letters, digits, '.',
+// '_' and '-', and can't be "." or ".."; operations fail with
+// ErrInvalidRunID otherwise.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFromContext returns the run ID set with WithRunID, or by the library
+// for the operation in progress, such as in the context passed to an
+// Authorizer.
+func RunIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(runIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ensureRunID returns ctx with a run ID, and that ID. Every operation gets
+// one, found in its errors, audit records and artifacts.
+func ensureRunID(ctx context.Context) (context.Context, string, error) {
+	if id, ok := RunIDFromContext(ctx); ok {
+		if err := validateRunID(id); err != nil {
+			return ctx, "", err
+		}
+		return ctx, id, nil
+	}
+	id := NewRunID()
+	return WithRunID(ctx, id), id, nil
+}
+
+// validateRunID checks id is safe to use in file names.
+func validateRunID(id string) error {
+	if id == "." || id == ".." {
+		return fmt.Errorf("%w %q", ErrInvalidRunID, id)
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '.', c == '_', c == '-':
+		default:
+			return fmt.Errorf("%w %q: %q is not allowed", ErrInvalidRunID, id, c)
+		}
+	}
+	return nil
+}
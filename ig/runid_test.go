@@ -0,0 +1,44 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateRunID(t *testing.T) {
+	for _, tc := range []struct {
+		id    string
+		valid bool
+	}{
+		{id: NewRunID(), valid: true},
+		{id: "req-42_retry.1", valid: true},
+		{id: "...", valid: true},
+		{id: ".", valid: false},
+		{id: "..", valid: false},
+		{id: "a/b", valid: false},
+		{id: "../escape", valid: false},
+		{id: "run*", valid: false},
+		{id: "with space", valid: false},
+		{id: "é", valid: false},
+	} {
+		err := validateRunID(tc.id)
+		if tc.valid && err != nil {
+			t.Errorf("validateRunID(%q): unexpected error %s", tc.id, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidRunID) {
+			t.Errorf("validateRunID(%q): got %v, want ErrInvalidRunID", tc.id, err)
+		}
+	}
+}
+
+func TestRunRejectsInvalidRunID(t *testing.T) {
+	i := scriptIG(t, "echo '{}'\n")
+	ctx := WithRunID(context.Background(), "../escape")
+	if _, err := i.Run(ctx, "trace_exec"); !errors.Is(err, ErrInvalidRunID) {
+		t.Errorf("Run: got %v, want ErrInvalidRunID", err)
+	}
+	if _, err := i.Start(ctx, "trace_exec"); !errors.Is(err, ErrInvalidRunID) {
+		t.Errorf("Start: got %v, want ErrInvalidRunID", err)
+	}
+}
@@ -0,0 +1,88 @@
+package ig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RunJSON runs a gadget as Run does, with -o json, and returns its events
+// decoded. The events printed are returned along with any error of the run.
+func (ig *IG) RunJSON(ctx context.Context, image string, flags ...string) ([]map[string]any, error) {
+	var events []map[string]any
+	err := ig.RunJSONInto(ctx, image, &events, flags...)
+	return events, err
+}
+
+// RunJSONInto runs a gadget as RunJSON does, appending its events to the
+// slice dst points to, e.g. a *[]TraceExecEvent, each unmarshaled with
+// encoding/json. The arrays printed by snapshot gadgets are flattened, and
+// lines that aren't JSON, as status messages, are skipped. Flags must not
+// set another output mode.
+func (ig *IG) RunJSONInto(ctx context.Context, image string, dst any, flags ...string) error {
+	slice := reflect.ValueOf(dst)
+	if slice.Kind() != reflect.Pointer || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("running %s: decoding events into %T, not a pointer to a slice", image, dst)
+	}
+	if out, ok := outputFlag(flags); ok && out != OutputJSON {
+		return fmt.Errorf("running %s: output mode %q set for JSON events", image, out)
+	}
+
+	res, err := ig.Run(ctx, image, append([]string{"-o", OutputJSON}, flags...)...)
+	if res == nil {
+		return err
+	}
+	defer res.Close()
+	if derr := decodeJSONLines(res.Stdout, slice.Elem()); derr != nil {
+		err = errors.Join(err, fmt.Errorf("decoding events of %s: %w", image, derr))
+	}
+	return err
+}
+
+// decodeJSONLines appends the events of output, one JSON object or array
+// per line, to slice.
+func decodeJSONLines(output string, slice reflect.Value) error {
+	var errs []error
+	for i, line := range strings.Split(output, "\n") {
+		b := bytes.TrimSpace([]byte(line))
+		if len(b) == 0 || (b[0] != '{' && b[0] != '[') {
+			continue
+		}
+		if b[0] == '[' {
+			batch := reflect.New(slice.Type())
+			if err := json.Unmarshal(b, batch.Interface()); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", i+1, err))
+				continue
+			}
+			slice.Set(reflect.AppendSlice(slice, batch.Elem()))
+			continue
+		}
+		ev := reflect.New(slice.Type().Elem())
+		if err := json.Unmarshal(b, ev.Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", i+1, err))
+			continue
+		}
+		slice.Set(reflect.Append(slice, ev.Elem()))
+	}
+	return errors.Join(errs...)
+}
+
+// outputFlag returns the output mode set by flags, if any.
+func outputFlag(flags []string) (string, bool) {
+	out, ok := "", false
+	for i, f := range flags {
+		switch {
+		case (f == "-o" || f == "--output") && i+1 < len(flags):
+			out, ok = flags[i+1], true
+		case strings.HasPrefix(f, "--output="):
+			out, ok = strings.TrimPrefix(f, "--output="), true
+		case strings.HasPrefix(f, "-o="):
+			out, ok = strings.TrimPrefix(f, "-o="), true
+		}
+	}
+	return out, ok
+}
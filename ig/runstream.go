@@ -0,0 +1,25 @@
+package ig
+
+import "context"
+
+// RunStream starts a gadget as Start does and streams the lines it prints
+// on stdout as ig prints them, for long-running gadgets whose output can't
+// wait for Run to return. flags come after "-o json", so they may select
+// another output mode, e.g. "-o", "columns"; blank lines are skipped.
+//
+// The channel is closed once the gadget exited. The session stops the
+// gadget and reports its error; its Events must not be read.
+func (ig *IG) RunStream(ctx context.Context, image string, flags ...string) (<-chan string, *GadgetSession, error) {
+	s, err := ig.Start(ctx, image, flags...)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := make(chan string, cap(s.events))
+	go func() {
+		defer close(lines)
+		for ev := range s.events {
+			lines <- ev.Raw
+		}
+	}()
+	return lines, s, nil
+}
@@ -0,0 +1,116 @@
+package ig
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SanitizeMode selects what Sanitize fixes in the output of ig. Gadgets
+// print what traced processes give them, e.g. binary argv, which breaks
+// JSON decoding and garbles test logs.
+type SanitizeMode uint
+
+const (
+	// SanitizeUTF8 replaces invalid UTF-8 with U+FFFD.
+	SanitizeUTF8 SanitizeMode = 1 << iota
+	// SanitizeANSI strips ANSI escape sequences, such as colors.
+	SanitizeANSI
+	// SanitizeControl escapes control characters other than tabs and line
+	// breaks as \u00XX, which is valid in JSON strings.
+	SanitizeControl
+
+	// SanitizeNone preserves the output as printed.
+	SanitizeNone SanitizeMode = 0
+	// SanitizeAll is the mode of IGs created without WithSanitize.
+	SanitizeAll = SanitizeUTF8 | SanitizeANSI | SanitizeControl
+)
+
+func (m SanitizeMode) String() string {
+	if m == SanitizeNone {
+		return "none"
+	}
+	var names []string
+	for _, n := range []struct {
+		mode SanitizeMode
+		name string
+	}{{SanitizeUTF8, "utf8"}, {SanitizeANSI, "ansi"}, {SanitizeControl, "control"}} {
+		if m&n.mode != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// WithSanitize sets what is sanitized in the output of ig: the stdout and
+// stderr of runs, and the raw lines and stderr of sessions. Events are
+// decoded from sanitized lines. SanitizeNone preserves the bytes as printed.
+func WithSanitize(mode SanitizeMode) Option {
+	return func(ig *IG) {
+		ig.sanitize = mode
+	}
+}
+
+// ansiSequence matches CSI sequences (colors, cursor moves), OSC sequences
+// (terminal titles, hyperlinks) and two-byte escapes.
+const ansiSequence = "\x1b(?:\\[[0-?]*[ -/]*[@-~]|\\][^\x07\x1b]*(?:\x07|\x1b\\\\)|[@-Z\\\\-_])"
+
+var (
+	ansiRegexp       = regexp.MustCompile(ansiSequence)
+	ansiPrefixRegexp = regexp.MustCompile("^" + ansiSequence)
+)
+
+// Sanitize returns s with the problems selected by mode fixed.
+func Sanitize(s string, mode SanitizeMode) string {
+	found := NeedsSanitize(s) & mode
+	if found == 0 {
+		return s
+	}
+	if found&SanitizeANSI != 0 {
+		s = ansiRegexp.ReplaceAllString(s, "")
+	}
+	if found&SanitizeControl != 0 {
+		var b strings.Builder
+		for i := 0; i < len(s); i++ {
+			if c := s[i]; isControl(c) {
+				fmt.Fprintf(&b, `\u%04x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+		s = b.String()
+	}
+	if found&SanitizeUTF8 != 0 {
+		s = strings.ToValidUTF8(s, "\uFFFD")
+	}
+	return s
+}
+
+// NeedsSanitize detects the problems of s that Sanitize fixes, SanitizeNone
+// for clean UTF-8 text. ANSI sequences are reported as control characters
+// too.
+func NeedsSanitize(s string) SanitizeMode {
+	var found SanitizeMode
+	for i := 0; i < len(s); i++ {
+		if !isControl(s[i]) {
+			continue
+		}
+		// The escape starting an ANSI sequence is a control character too,
+		// escaped if sequences are preserved.
+		found |= SanitizeControl
+		if s[i] == 0x1b && ansiPrefixRegexp.MatchString(s[i:]) {
+			found |= SanitizeANSI
+		}
+	}
+	if !utf8.ValidString(s) {
+		found |= SanitizeUTF8
+	}
+	return found
+}
+
+// isControl reports whether c is a C0 control character other than tabs and
+// line breaks.
+func isControl(c byte) bool {
+	return c < 0x20 && c != '\t' && c != '\n' && c != '\r'
+}
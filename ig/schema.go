@@ -0,0 +1,291 @@
+package ig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// JSON types of schema fields.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+)
+
+// FieldSchema describes one field of the events of a data source.
+type FieldSchema struct {
+	// Path is the dot-separated path of the field, e.g. "proc.comm".
+	Path string `json:"path"`
+	// Type is one of the Type constants.
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// Schema describes the events of one data source of a gadget.
+type Schema struct {
+	Gadget     string `json:"gadget"`
+	DataSource string `json:"dataSource"`
+	// Fields are sorted by path. Objects only appear through their leaves.
+	Fields []FieldSchema `json:"fields"`
+}
+
+// fieldFlagEmpty marks container fields without a value of their own, as
+// in the gadget service API.
+const fieldFlagEmpty = 1 << 0
+
+// kindTypes maps the field kinds of the gadget service API, by name and by
+// number, to JSON types.
+var kindTypes = map[string]string{
+	"Bool": TypeBoolean, "1": TypeBoolean,
+	"Int8": TypeInteger, "2": TypeInteger,
+	"Int16": TypeInteger, "3": TypeInteger,
+	"Int32": TypeInteger, "4": TypeInteger,
+	"Int64": TypeInteger, "5": TypeInteger,
+	"Uint8": TypeInteger, "6": TypeInteger,
+	"Uint16": TypeInteger, "7": TypeInteger,
+	"Uint32": TypeInteger, "8": TypeInteger,
+	"Uint64": TypeInteger, "9": TypeInteger,
+	"Float32": TypeNumber, "10": TypeNumber,
+	"Float64": TypeNumber, "11": TypeNumber,
+	"String": TypeString, "12": TypeString,
+	"CString": TypeString, "13": TypeString,
+	"Bytes": TypeString, "14": TypeString,
+}
+
+// Schemas returns the schemas of the data sources of every image, as
+// reported by ig image inspect. ig versions whose inspect output lacks data
+// sources return an error; InferSchema works from sample events instead.
+func (ig *IG) Schemas(ctx context.Context, images ...string) ([]Schema, error) {
+	var schemas []Schema
+	for _, image := range images {
+		info, err := ig.inspect(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		if len(info.DataSources) == 0 {
+			return nil, fmt.Errorf("inspection of %s has no data sources (ig %s)", image, ig.Version())
+		}
+
+		for _, ds := range info.DataSources {
+			s := Schema{Gadget: GadgetName(image), DataSource: ds.Name}
+			for _, f := range ds.Fields {
+				if f.Empty {
+					continue
+				}
+				s.Fields = append(s.Fields, FieldSchema{
+					Path:        f.Path,
+					Type:        f.Type,
+					Description: f.Description,
+				})
+			}
+			sort.Slice(s.Fields, func(i, j int) bool { return s.Fields[i].Path < s.Fields[j].Path })
+			schemas = append(schemas, s)
+		}
+	}
+	return schemas, nil
+}
+
+// InferSchema derives the schema of a data source from sample events, for
+// ig versions whose image inspection doesn't describe fields. Fields seen
+// with different types are typed as strings.
+func InferSchema(gadget, dataSource string, events []Event) Schema {
+	types := map[string]string{}
+	for _, ev := range events {
+		inferFields("", ev.Fields, types)
+	}
+
+	s := Schema{Gadget: gadget, DataSource: dataSource}
+	for path, typ := range types {
+		s.Fields = append(s.Fields, FieldSchema{Path: path, Type: typ})
+	}
+	sort.Slice(s.Fields, func(i, j int) bool { return s.Fields[i].Path < s.Fields[j].Path })
+	return s
+}
+
+func inferFields(prefix string, m map[string]any, types map[string]string) {
+	for k, v := range m {
+		path := prefix + k
+		var typ string
+		switch v := v.(type) {
+		case map[string]any:
+			inferFields(path+".", v, types)
+			continue
+		case nil:
+			continue
+		case bool:
+			typ = TypeBoolean
+		case float64:
+			typ = TypeNumber
+			if v == float64(int64(v)) {
+				typ = TypeInteger
+			}
+		case string:
+			typ = TypeString
+		case []any:
+			typ = TypeArray
+		}
+
+		switch prev, ok := types[path]; {
+		case !ok || prev == typ:
+			types[path] = typ
+		case prev == TypeInteger && typ == TypeNumber, prev == TypeNumber && typ == TypeInteger:
+			types[path] = TypeNumber
+		default:
+			types[path] = TypeString
+		}
+	}
+}
+
+// JSONSchema renders the schema as a JSON Schema (draft 2020-12) document,
+// with nested objects for dotted paths.
+func (s Schema) JSONSchema() ([]byte, error) {
+	root := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "urn:inspektor-gadget:" + s.Gadget + ":" + s.DataSource,
+		"title":   s.Gadget + " " + s.DataSource,
+		"type":    TypeObject,
+	}
+	for _, f := range s.Fields {
+		node := root
+		parts := strings.Split(f.Path, ".")
+		for _, p := range parts[:len(parts)-1] {
+			props := properties(node)
+			child, ok := props[p].(map[string]any)
+			if !ok {
+				child = map[string]any{"type": TypeObject}
+				props[p] = child
+			}
+			node = child
+		}
+		leaf := map[string]any{"type": f.Type}
+		if f.Description != "" {
+			leaf["description"] = f.Description
+		}
+		properties(node)[parts[len(parts)-1]] = leaf
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
+
+func properties(node map[string]any) map[string]any {
+	props, ok := node["properties"].(map[string]any)
+	if !ok {
+		props = map[string]any{}
+		node["properties"] = props
+	}
+	return props
+}
+
+// protoTypes maps JSON types to protobuf scalar types.
+var protoTypes = map[string]string{
+	TypeString:  "string",
+	TypeInteger: "int64",
+	TypeNumber:  "double",
+	TypeBoolean: "bool",
+}
+
+// SchemaNode is a field of a Schema in nested form, as rendered by
+// JSONSchema and Proto.
+type SchemaNode struct {
+	// Name is the last element of the path of the field.
+	Name string
+	// Number is the position of the node among its siblings, from 1. It is
+	// the protobuf field number.
+	Number int
+	// Field is nil for objects.
+	Field *FieldSchema
+	// Children are the fields of an object.
+	Children []*SchemaNode
+}
+
+// Tree returns the fields of the schema nested by path.
+func (s Schema) Tree() []*SchemaNode {
+	var root []*SchemaNode
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		nodes := &root
+		parts := strings.Split(f.Path, ".")
+		for _, p := range parts[:len(parts)-1] {
+			nodes = &schemaChild(nodes, p).Children
+		}
+		*nodes = append(*nodes, &SchemaNode{Name: parts[len(parts)-1], Number: len(*nodes) + 1, Field: f})
+	}
+	return root
+}
+
+// schemaChild returns the object node name of nodes, adding it if needed.
+func schemaChild(nodes *[]*SchemaNode, name string) *SchemaNode {
+	for _, n := range *nodes {
+		if n.Field == nil && n.Name == name {
+			return n
+		}
+	}
+	n := &SchemaNode{Name: name, Number: len(*nodes) + 1}
+	*nodes = append(*nodes, n)
+	return n
+}
+
+// Proto renders the schema as a proto3 file, with a message per nested
+// object. Field numbers follow the order of the fields, so they are only
+// stable as long as the schema doesn't change.
+func (s Schema) Proto(pkg string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "syntax = \"proto3\";\n\npackage %s;\n", pkg)
+	writeProtoMessage(&b, "", protoName(s.Gadget)+protoName(s.DataSource), s.Tree())
+	return b.String()
+}
+
+func writeProtoMessage(b *strings.Builder, indent, name string, nodes []*SchemaNode) {
+	fmt.Fprintf(b, "\n%smessage %s {\n", indent, name)
+	for _, n := range nodes {
+		var typ string
+		switch {
+		case n.Field == nil:
+			typ = protoName(n.Name)
+			writeProtoMessage(b, indent+"  ", typ, n.Children)
+		case n.Field.Type == TypeArray:
+			// Schemas don't type array elements; they are rendered as
+			// strings.
+			typ = "repeated string"
+		default:
+			typ = protoTypes[n.Field.Type]
+		}
+		fmt.Fprintf(b, "%s  %s %s = %d;\n", indent, typ, protoFieldName(n.Name), n.Number)
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// protoName turns a gadget, data source or field name into a message name:
+// "trace_exec" gives "TraceExec".
+func protoName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// protoFieldName turns a field name into a valid proto field name.
+func protoFieldName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,469 @@
+package ig
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/internal/capture"
+	"github.com/pawarpranav83/ig-testing-framework/internal/stats"
+)
+
+// StopGrace is how long Stop waits for an interrupted gadget to exit before
+// killing it.
+var StopGrace = 5 * time.Second
+
+// maxEventSize bounds the length of a single line of gadget output.
+const maxEventSize = 1 << 20
+
+// GadgetSession is a gadget running in the background, streaming its events
+// as ig prints them.
+type GadgetSession struct {
+	runID  string
+	image  string
+	cmd    *exec.Cmd
+	events chan Event
+
+	stderr *capture.Buffer
+	// sanitize is what is fixed in lines and stderr, see WithSanitize.
+	sanitize SanitizeMode
+	// failOnWarning fails the session if ig printed warnings.
+	failOnWarning bool
+	// dropped counts the lines read but not forwarded, see Completeness.
+	dropped atomic.Int64
+
+	mu       sync.Mutex
+	state    State
+	watchers []chan StateChange
+	stopping bool
+
+	transcript    *Transcript
+	transcriptDir string
+
+	// record is the history record of the session, filled in as it runs,
+	// if the IG keeps a history.
+	history *History
+	record  *RunRecord
+
+	// output, if set, receives the stdout of ig in place of Events. It is
+	// closed once ig started, and cleanup runs once ig exited.
+	output  *os.File
+	cleanup func()
+
+	// quota counts the output read for tenant; release frees the run.
+	quota    *quota
+	tenant   string
+	release  func()
+	quotaErr error
+
+	ready readiness
+	// activity is when ig last printed something, in Unix nanoseconds.
+	activity atomic.Int64
+
+	done chan struct{}
+	err  error
+}
+
+// Start runs a gadget in the background with JSON output ("-o json" is added
+// to flags) and streams its events. Consumers must drain Events: ig blocks
+// once it is full. Call Stop or Wait to release the session.
+func (ig *IG) Start(ctx context.Context, image string, flags ...string) (*GadgetSession, error) {
+	s, err := ig.newSession(ctx, image, flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.launch(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// PullAndStart pulls image, passing pullFlags to ig image pull, then starts
+// it like Start. Pulling beforehand keeps the download out of the gadget
+// startup, and shows up as StatePulling in the session lifecycle.
+func (ig *IG) PullAndStart(ctx context.Context, image string, pullFlags []string, flags ...string) (*GadgetSession, error) {
+	ctx, _, err := ensureRunID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ig.newSession(ctx, image, flags)
+	if err != nil {
+		return nil, err
+	}
+	s.setState(StatePulling)
+	if err := ig.Pull(ctx, image, pullFlags...); err != nil {
+		s.abort()
+		return nil, err
+	}
+	if err := s.launch(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newSession prepares the session of a run of image, once authorized and
+// within quotas.
+func (ig *IG) newSession(ctx context.Context, image string, flags []string) (*GadgetSession, error) {
+	ctx, runID, err := ensureRunID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flags, err = ig.recommend(ctx, image, ig.withRunFlags(flags))
+	if err != nil {
+		return nil, err
+	}
+	if err := ig.authorizeRun(ctx, image, flags); err != nil {
+		return nil, err
+	}
+	flags, cleanup, err := ig.withAuth(ctx, image, flags)
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", image, err)
+	}
+	if err := ig.checkBinary(ctx); err != nil {
+		cleanup()
+		return nil, err
+	}
+	release, tenant, err := ig.acquireRun(ctx, image)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	// The authfile is only needed until ig exits.
+	releaseRun := release
+	release = func() {
+		releaseRun()
+		cleanup()
+	}
+
+	args := append([]string{"run", image, "-o", "json"}, flags...)
+
+	cmd := ig.command(ctx, args...)
+
+	s := &GadgetSession{
+		runID:  runID,
+		image:  image,
+		cmd:    cmd,
+		events: make(chan Event, 1024),
+		stderr: capture.New(ig.maxOutput),
+		done:   make(chan struct{}),
+		ready:  readiness{ch: make(chan struct{})},
+
+		quota:   ig.quota,
+		tenant:  tenant,
+		release: release,
+
+		sanitize:      ig.sanitize,
+		failOnWarning: ig.failOnWarning,
+	}
+	cmd.Stderr = s.stderr
+
+	if ig.transcriptDir != "" {
+		s.transcriptDir = ig.transcriptDir
+		s.transcript = &Transcript{
+			RunID:   runID,
+			Version: ig.Version(),
+			Argv:    cmd.Args,
+			Env:     ig.env,
+			Started: time.Now(),
+		}
+		cmd.Stderr = io.MultiWriter(s.stderr, transcriptWriter{s.transcript, TranscriptStderr})
+	}
+	cmd.Stderr = io.MultiWriter(cmd.Stderr, &readyWriter{r: &s.ready}, activityWriter{s})
+
+	if ig.history != nil {
+		record := ig.newRunRecord(runID, image, flags, time.Now())
+		s.history, s.record = ig.history, &record
+	}
+	return s, nil
+}
+
+func (s *GadgetSession) launch() error {
+	s.setState(StateStarting)
+	s.touch()
+
+	if s.output != nil {
+		s.cmd.Stdout = s.output
+		err := startTracked(s.cmd)
+		s.output.Close()
+		if err != nil {
+			s.cleanup()
+			s.abort()
+			return fmt.Errorf("starting %s: %w", s.image, err)
+		}
+		s.setState(StateRunning)
+		stats.SessionsStarted.Add(1)
+		stats.ActiveSessions.Add(1)
+		go s.read(nil, s.cmd.Args[1:])
+		return nil
+	}
+
+	stdout, err := s.cmd.StdoutPipe()
+	if err == nil {
+		err = startTracked(s.cmd)
+	}
+	if err != nil {
+		s.abort()
+		return fmt.Errorf("starting %s: %w", s.image, err)
+	}
+	s.setState(StateRunning)
+	stats.SessionsStarted.Add(1)
+	stats.ActiveSessions.Add(1)
+
+	var out io.Reader = stdout
+	if s.transcript != nil {
+		out = io.TeeReader(stdout, transcriptWriter{s.transcript, TranscriptStdout})
+	}
+	go s.read(out, s.cmd.Args[1:])
+
+	return nil
+}
+
+// abort fails a session that couldn't start.
+func (s *GadgetSession) abort() {
+	s.setState(StateFailed)
+	s.release()
+}
+
+// read forwards events until ig closes its stdout, then reaps it. stdout is
+// nil if ig writes to an Output.
+func (s *GadgetSession) read(stdout io.Reader, args []string) {
+	defer func() {
+		stats.ActiveSessions.Add(-1)
+		s.release()
+		if s.cleanup != nil {
+			s.cleanup()
+		}
+		if s.transcript != nil {
+			s.transcript.record(TranscriptExit, s.cmd.ProcessState.String())
+		}
+		if s.exitErr() != nil {
+			s.setState(StateFailed)
+		} else {
+			s.setState(StateStopped)
+		}
+		if s.transcript != nil {
+			if err := s.writeTranscript(); err != nil {
+				s.err = errors.Join(s.err, err)
+			}
+		}
+		if s.record != nil {
+			if err := s.recordHistory(); err != nil {
+				s.err = errors.Join(s.err, err)
+			}
+		}
+		close(s.done)
+	}()
+
+	var scanErr error
+	if stdout != nil {
+		scanErr = s.scan(stdout)
+	}
+	close(s.events)
+
+	err := waitTracked(s.cmd)
+	if s.quotaErr != nil {
+		s.err = fmt.Errorf("%s (run %s) stopped: %w", s.image, s.runID, s.quotaErr)
+		return
+	}
+	if err != nil {
+		s.err = &CommandError{
+			RunID:    s.runID,
+			Args:     args,
+			ExitCode: s.cmd.ProcessState.ExitCode(),
+			Stderr:   s.Stderr(),
+			Err:      err,
+		}
+		return
+	}
+	if scanErr != nil {
+		s.err = fmt.Errorf("reading output of %s (run %s): %w", s.image, s.runID, scanErr)
+		return
+	}
+	if ws := s.Warnings(); s.failOnWarning && len(ws) > 0 {
+		s.err = fmt.Errorf("%s (run %s): %w", s.image, s.runID, warningErr(ws))
+	}
+}
+
+// recordHistory completes the history record of the exited session and
+// adds it to the history.
+func (s *GadgetSession) recordHistory() error {
+	s.record.Finished = time.Now()
+	if err := s.exitErr(); err != nil {
+		s.record.Error = err.Error()
+	}
+	if s.transcript != nil {
+		s.record.Artifacts = append(s.record.Artifacts, filepath.Join(s.transcriptDir, s.transcript.fileName(s.image)))
+	}
+	return s.history.Record(*s.record)
+}
+
+// exitErr returns the error of the exited gadget, not counting being killed
+// by a signal that Stop sent.
+func (s *GadgetSession) exitErr() error {
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+
+	var cerr *CommandError
+	if stopping && errors.As(s.err, &cerr) && cerr.ExitCode == -1 {
+		return nil
+	}
+	return s.err
+}
+
+func (s *GadgetSession) scan(stdout io.Reader) error {
+	stdout = countingReader{stdout}
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64*1024), maxEventSize)
+
+	for sc.Scan() {
+		s.touch()
+		n := len(sc.Bytes()) + 1
+		if s.record != nil {
+			s.record.OutputBytes += int64(n)
+		}
+		if s.quotaErr == nil {
+			if s.quotaErr = s.quota.capture(s.tenant, n); s.quotaErr != nil {
+				// Keep reading until ig exits, without forwarding.
+				s.signal(os.Interrupt)
+			}
+		}
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		if s.quotaErr != nil {
+			s.dropped.Add(1)
+			continue
+		}
+		s.ready.set()
+
+		line := Sanitize(sc.Text(), s.sanitize)
+		ev := Event{Gadget: s.image, Raw: line, Received: time.Now(), Seq: nextSeq()}
+		var fields map[string]any
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			stats.EventsDecoded.Add(1)
+			if s.record != nil {
+				s.record.Events++
+			}
+			ev.Fields = fields
+			if ts, ok := parseTimestamp(fields["timestamp"]); ok {
+				ev.Timestamp = ts
+			}
+		}
+		s.events <- ev
+	}
+
+	err := sc.Err()
+	if err != nil {
+		// Keep draining so ig doesn't block writing to a full pipe, counting
+		// the lost lines: the rest of the one too long and those after it.
+		lines := lineCounter{partial: true}
+		io.Copy(&lines, stdout)
+		stats.EventsDropped.Add(lines.lines())
+		s.dropped.Add(lines.lines())
+	}
+	return err
+}
+
+// signal sends sig to ig, recording it in the transcript.
+func (s *GadgetSession) signal(sig os.Signal) error {
+	if s.transcript != nil {
+		s.transcript.record(TranscriptSignal, sig.String())
+	}
+	if sig == os.Kill {
+		return killProcessGroup(s.cmd.Process)
+	}
+	return s.cmd.Process.Signal(sig)
+}
+
+// Signal sends sig to ig, e.g. syscall.SIGHUP, without otherwise changing
+// the lifecycle of the session: use Stop to end it. It returns
+// os.ErrProcessDone once ig exited.
+func (s *GadgetSession) Signal(sig os.Signal) error {
+	return s.signal(sig)
+}
+
+// PID returns the process ID of ig.
+func (s *GadgetSession) PID() int {
+	return s.cmd.Process.Pid
+}
+
+// RunID returns the ID of the run, see WithRunID.
+func (s *GadgetSession) RunID() string {
+	return s.runID
+}
+
+// Image returns the image of the gadget.
+func (s *GadgetSession) Image() string {
+	return s.image
+}
+
+// Events returns the events of the gadget. The channel is closed once the
+// gadget exited.
+func (s *GadgetSession) Events() <-chan Event {
+	return s.events
+}
+
+// Stderr returns what ig printed on stderr so far, up to the output cap of
+// the IG.
+func (s *GadgetSession) Stderr() string {
+	return Sanitize(s.stderr.String(), s.sanitize)
+}
+
+// Warnings returns the warnings ig printed on stderr so far, see
+// ParseStderr.
+func (s *GadgetSession) Warnings() []StderrRecord {
+	return warnings(ParseStderr(s.Stderr()))
+}
+
+// Done returns a channel closed once the gadget exited.
+func (s *GadgetSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait waits for the gadget to exit on its own and returns its error.
+func (s *GadgetSession) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Stop interrupts the gadget, as Ctrl-C would, so it can flush its output,
+// and kills it if it is still running after StopGrace. Being terminated by
+// the signal is not an error; failing on its own is.
+func (s *GadgetSession) Stop() error {
+	select {
+	case <-s.done:
+		return s.exitErr()
+	default:
+	}
+
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+	s.setState(StateStopping)
+
+	interrupted := s.signal(os.Interrupt) == nil
+	if interrupted {
+		select {
+		case <-s.done:
+		case <-time.After(StopGrace):
+			s.signal(os.Kill)
+			<-s.done
+		}
+	} else {
+		s.signal(os.Kill)
+		<-s.done
+	}
+
+	return s.exitErr()
+}
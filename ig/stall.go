@@ -0,0 +1,109 @@
+package ig
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StreamStalled notifies that a running gadget printed nothing, neither
+// events on stdout nor status lines on stderr, for a while. A stalled
+// gadget looks the same as a gadget without activity to trace; a stall
+// that outlasts the expected activity of the workload points at ig.
+type StreamStalled struct {
+	Image string
+	RunID string
+	// Since is when the gadget last printed something.
+	Since time.Time
+	// Idle is how long it has been silent when the stall was detected.
+	Idle time.Duration
+}
+
+// activityWriter records the writes of ig to stderr as activity of s.
+type activityWriter struct{ s *GadgetSession }
+
+func (w activityWriter) Write(p []byte) (int, error) {
+	w.s.touch()
+	return len(p), nil
+}
+
+// touch records activity of the session.
+func (s *GadgetSession) touch() {
+	s.activity.Store(time.Now().UnixNano())
+}
+
+// LastActivity returns when ig last printed something, on stdout or
+// stderr, or started if it printed nothing yet.
+func (s *GadgetSession) LastActivity() time.Time {
+	return time.Unix(0, s.activity.Load())
+}
+
+// WaitIdle waits until the gadget printed nothing for idle since it was
+// called, e.g. to stop it once it reported the events of a workload that
+// just returned. It also returns once the gadget exited, and fails if ctx is
+// done first.
+func (s *GadgetSession) WaitIdle(ctx context.Context, idle time.Duration) error {
+	since := time.Now()
+	t := time.NewTimer(idle)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s to be idle: %w", s.image, ctx.Err())
+		case <-t.C:
+		}
+
+		last := s.LastActivity()
+		if last.Before(since) {
+			last = since
+		}
+		remaining := idle - time.Since(last)
+		if remaining <= 0 {
+			return nil
+		}
+		t.Reset(remaining)
+	}
+}
+
+// WatchStalls returns a channel receiving a StreamStalled each time the
+// gadget stays silent for period, once per silence. It is closed once the
+// gadget exited. Notifications are dropped if the channel is full.
+func (s *GadgetSession) WatchStalls(period time.Duration) <-chan StreamStalled {
+	ch := make(chan StreamStalled, 1)
+	tick := period / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	go func() {
+		defer close(ch)
+		t := time.NewTicker(tick)
+		defer t.Stop()
+
+		stalled := false
+		for {
+			select {
+			case <-s.done:
+				return
+			case now := <-t.C:
+				last := s.LastActivity()
+				idle := now.Sub(last)
+				if idle < period {
+					stalled = false
+					continue
+				}
+				if stalled {
+					continue
+				}
+				stalled = true
+				select {
+				case ch <- StreamStalled{Image: s.image, RunID: s.runID, Since: last, Idle: idle}:
+				default:
+				}
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,38 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitIdle(t *testing.T) {
+	// Prints for ~300ms, then stays silent until killed.
+	i := scriptIG(t, `for n in 1 2 3; do echo '{"n":'$n'}'; sleep 0.1; done
+exec sleep 10
+`)
+	s, err := i.Start(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	go func() {
+		for range s.Events() {
+		}
+	}()
+
+	start := time.Now()
+	if err := s.WaitIdle(context.Background(), 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("WaitIdle returned after %s, while the gadget was still printing", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.WaitIdle(ctx, time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitIdle past ctx deadline: got %v, want context.DeadlineExceeded", err)
+	}
+}
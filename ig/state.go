@@ -0,0 +1,99 @@
+package ig
+
+import "time"
+
+// State is a step of the lifecycle of a GadgetSession. States only move
+// forward, and a session ends in StateStopped or StateFailed.
+type State int
+
+const (
+	StateCreated State = iota
+	// StatePulling is only entered by PullAndStart.
+	StatePulling
+	StateStarting
+	StateRunning
+	// StateStopping is entered when Stop is called.
+	StateStopping
+	// StateStopped means ig exited successfully or was stopped.
+	StateStopped
+	// StateFailed means ig failed to pull, start or run.
+	StateFailed
+)
+
+var stateNames = [...]string{
+	StateCreated:  "Created",
+	StatePulling:  "Pulling",
+	StateStarting: "Starting",
+	StateRunning:  "Running",
+	StateStopping: "Stopping",
+	StateStopped:  "Stopped",
+	StateFailed:   "Failed",
+}
+
+func (s State) String() string {
+	if s >= 0 && int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return "Unknown"
+}
+
+// Terminal reports whether s is a final state.
+func (s State) Terminal() bool {
+	return s == StateStopped || s == StateFailed
+}
+
+// StateChange is a transition of a GadgetSession.
+type StateChange struct {
+	From, To State
+	Time     time.Time
+}
+
+// State returns the current state of the session.
+func (s *GadgetSession) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// Watch returns a channel receiving the state changes of the session from
+// now on. It is closed once the session reaches a terminal state, right
+// away if it already did. Watchers never slow the session down: there are
+// fewer transitions than the channel can buffer.
+func (s *GadgetSession) Watch() <-chan StateChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan StateChange, len(stateNames))
+	if s.state.Terminal() {
+		close(ch)
+		return ch
+	}
+	s.watchers = append(s.watchers, ch)
+	return ch
+}
+
+func (s *GadgetSession) setState(to State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := s.state
+	if to <= from || from.Terminal() {
+		return
+	}
+	s.state = to
+
+	change := StateChange{From: from, To: to, Time: time.Now()}
+	if s.transcript != nil {
+		s.transcript.record(TranscriptState, to.String())
+	}
+	for _, w := range s.watchers {
+		w <- change
+	}
+	if to.Terminal() {
+		for _, w := range s.watchers {
+			close(w)
+		}
+		s.watchers = nil
+	}
+}
@@ -0,0 +1,76 @@
+package ig
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pawarpranav83/ig-testing-framework/internal/stats"
+)
+
+// Stats are process-wide counters of the library, to measure the overhead of
+// the wrapper in long-lived deployments.
+type Stats struct {
+	// EventsDecoded is the number of gadget output lines decoded as JSON
+	// events.
+	EventsDecoded int64 `json:"eventsDecoded"`
+	// EventsDropped is the number of events lost by the library: output
+	// past the maximum event size and events dropped by slow subscribers of
+	// a stream.Fanout.
+	EventsDropped int64 `json:"eventsDropped"`
+	// BytesRead is the number of bytes of gadget output read.
+	BytesRead int64 `json:"bytesRead"`
+	// SinkBytes is the number of bytes serialized by sinks.
+	SinkBytes int64 `json:"sinkBytes"`
+	// SessionsStarted is the number of gadget sessions started.
+	SessionsStarted int64 `json:"sessionsStarted"`
+	// ActiveSessions is the number of gadget sessions still running.
+	ActiveSessions int64 `json:"activeSessions"`
+}
+
+// ReadStats returns the current value of the counters.
+func ReadStats() Stats {
+	return Stats{
+		EventsDecoded:   stats.EventsDecoded.Load(),
+		EventsDropped:   stats.EventsDropped.Load(),
+		BytesRead:       stats.BytesRead.Load(),
+		SinkBytes:       stats.SinkBytes.Load(),
+		SessionsStarted: stats.SessionsStarted.Load(),
+		ActiveSessions:  stats.ActiveSessions.Load(),
+	}
+}
+
+// countingReader counts the bytes read from r in stats.BytesRead.
+type countingReader struct {
+	r io.Reader
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	stats.BytesRead.Add(int64(n))
+	return n, err
+}
+
+// lineCounter counts the lines written to it.
+type lineCounter struct {
+	newlines int64
+	// partial is set if the last line isn't terminated, or nothing was
+	// written.
+	partial bool
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		c.newlines += int64(bytes.Count(p, []byte{'\n'}))
+		c.partial = p[len(p)-1] != '\n'
+	}
+	return len(p), nil
+}
+
+// lines returns the number of lines written, counting an unterminated last
+// line, or one line if nothing was written.
+func (c *lineCounter) lines() int64 {
+	if c.partial {
+		return c.newlines + 1
+	}
+	return c.newlines
+}
@@ -0,0 +1,162 @@
+package ig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SeverityInfo marks stderr lines that are neither warnings nor errors,
+// e.g. progress messages.
+const SeverityInfo Severity = "info"
+
+// ErrWarning is returned, wrapped, by runs of IGs created with
+// WithFailOnWarning that printed warnings.
+var ErrWarning = errors.New("ig printed warnings")
+
+// StderrRecord is a line ig printed on stderr, classified by severity.
+type StderrRecord struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Fields are the fields of structured log lines, besides the level and
+	// message, e.g. "time".
+	Fields map[string]string `json:"fields,omitempty"`
+	// Line is the line as printed.
+	Line string `json:"line"`
+}
+
+func (r StderrRecord) String() string {
+	return fmt.Sprintf("%s: %s", r.Severity, r.Message)
+}
+
+// WithFailOnWarning makes runs and sessions that printed warnings on stderr
+// fail with ErrWarning even if ig succeeded, to catch degraded runs, such
+// as ones that dropped events, in tests.
+func WithFailOnWarning() Option {
+	return func(ig *IG) {
+		ig.failOnWarning = true
+	}
+}
+
+// levels map the log levels of ig to severities, by their full name in
+// structured lines and their prefix in terminal lines.
+var levels = map[string]Severity{
+	"trace": SeverityInfo, "TRAC": SeverityInfo,
+	"debug": SeverityInfo, "DEBU": SeverityInfo,
+	"info": SeverityInfo, "INFO": SeverityInfo,
+	"warning": SeverityWarning, "warn": SeverityWarning, "WARN": SeverityWarning,
+	"error": SeverityError, "ERRO": SeverityError,
+	"fatal": SeverityError, "FATA": SeverityError,
+	"panic": SeverityError, "PANI": SeverityError,
+}
+
+var (
+	// terminalLine is a log line of ig on a terminal: "WARN[0001] message".
+	terminalLine = regexp.MustCompile(`^([A-Z]{4})\[[^\]]*\]\s*(.*)$`)
+	// prefixedLine is a line prefixed with its severity, e.g. the errors
+	// of the ig CLI: "Error: unknown flag".
+	prefixedLine = regexp.MustCompile(`(?i)^(error|warning|warn|info):\s*(.*)$`)
+)
+
+// ParseStderr classifies the lines ig printed on stderr. It understands the
+// log lines of ig, structured ("level=warning msg=...") or on a terminal
+// ("WARN[0000] ..."), and lines prefixed with their severity ("Error: ...").
+// Other lines are SeverityInfo. Blank lines are skipped.
+func ParseStderr(stderr string) []StderrRecord {
+	var records []StderrRecord
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		records = append(records, parseStderrLine(line))
+	}
+	return records
+}
+
+func parseStderrLine(line string) StderrRecord {
+	r := StderrRecord{Severity: SeverityInfo, Message: strings.TrimSpace(line), Line: line}
+	if fields, ok := parseLogfmt(line); ok {
+		if sev, ok := levels[strings.ToLower(fields["level"])]; ok {
+			r.Severity = sev
+		}
+		r.Message = fields["msg"]
+		delete(fields, "level")
+		delete(fields, "msg")
+		if len(fields) > 0 {
+			r.Fields = fields
+		}
+		return r
+	}
+	if m := terminalLine.FindStringSubmatch(line); m != nil {
+		if sev, ok := levels[m[1]]; ok {
+			r.Severity = sev
+			r.Message = strings.TrimSpace(m[2])
+		}
+		return r
+	}
+	if m := prefixedLine.FindStringSubmatch(line); m != nil {
+		r.Severity = levels[strings.ToLower(m[1])]
+		r.Message = m[2]
+	}
+	return r
+}
+
+// parseLogfmt parses a structured log line of key=value pairs, with quoted
+// values, reporting whether it is one: it must have a level and a message.
+func parseLogfmt(line string) (map[string]string, bool) {
+	fields := map[string]string{}
+	rest := strings.TrimSpace(line)
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \"") {
+			return nil, false
+		}
+		if strings.HasPrefix(value, `"`) {
+			end := 1
+			for end < len(value) && value[end] != '"' {
+				if value[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(value) {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			fields[key], rest = unquoted, value[end+1:]
+		} else {
+			v, r, _ := strings.Cut(value, " ")
+			fields[key], rest = v, r
+		}
+		rest = strings.TrimLeft(rest, " ")
+	}
+	_, hasLevel := fields["level"]
+	_, hasMsg := fields["msg"]
+	return fields, hasLevel && hasMsg
+}
+
+// warnings returns the warnings of records.
+func warnings(records []StderrRecord) []StderrRecord {
+	var ws []StderrRecord
+	for _, r := range records {
+		if r.Severity == SeverityWarning {
+			ws = append(ws, r)
+		}
+	}
+	return ws
+}
+
+// warningErr returns an error wrapping ErrWarning listing ws.
+func warningErr(ws []StderrRecord) error {
+	msgs := make([]string, len(ws))
+	for i, w := range ws {
+		msgs[i] = w.Message
+	}
+	return fmt.Errorf("%w: %s", ErrWarning, strings.Join(msgs, "; "))
+}
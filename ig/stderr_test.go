@@ -0,0 +1,129 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStderr(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want StderrRecord
+	}{
+		{
+			name: "logfmt warning",
+			line: `time="2024-05-01T10:00:00Z" level=warning msg="lost 3 samples" gadget=trace_exec`,
+			want: StderrRecord{
+				Severity: SeverityWarning,
+				Message:  "lost 3 samples",
+				Fields:   map[string]string{"time": "2024-05-01T10:00:00Z", "gadget": "trace_exec"},
+			},
+		},
+		{
+			name: "logfmt escaped quotes",
+			line: `level=error msg="pulling \"trace_exec\": denied"`,
+			want: StderrRecord{Severity: SeverityError, Message: `pulling "trace_exec": denied`},
+		},
+		{name: "logfmt warn", line: `level=warn msg=x`, want: StderrRecord{Severity: SeverityWarning, Message: "x"}},
+		{name: "logfmt debug", line: `level=debug msg=starting`, want: StderrRecord{Severity: SeverityInfo, Message: "starting"}},
+		{name: "logfmt fatal", line: `level=FATAL msg=boom`, want: StderrRecord{Severity: SeverityError, Message: "boom"}},
+		{name: "logfmt unknown level", line: `level=notice msg=hi`, want: StderrRecord{Severity: SeverityInfo, Message: "hi"}},
+		{name: "terminal warning", line: "WARN[0001] no BTF found, using BTFHub", want: StderrRecord{Severity: SeverityWarning, Message: "no BTF found, using BTFHub"}},
+		{name: "terminal error", line: "ERRO[0000] running gadget: exit status 1", want: StderrRecord{Severity: SeverityError, Message: "running gadget: exit status 1"}},
+		{name: "terminal info", line: "INFO[0000] starting   ", want: StderrRecord{Severity: SeverityInfo, Message: "starting"}},
+		{name: "terminal panic", line: "PANI[0002]", want: StderrRecord{Severity: SeverityError, Message: ""}},
+		{name: "terminal unknown prefix", line: "ABCD[0001] x", want: StderrRecord{Severity: SeverityInfo, Message: "ABCD[0001] x"}},
+		{name: "cli error", line: "Error: unknown flag: --foo", want: StderrRecord{Severity: SeverityError, Message: "unknown flag: --foo"}},
+		{name: "prefixed warning", line: "WARNING: running without BTF", want: StderrRecord{Severity: SeverityWarning, Message: "running without BTF"}},
+		{name: "prefixed warn", line: "warn: x", want: StderrRecord{Severity: SeverityWarning, Message: "x"}},
+		{name: "prefixed info", line: "Info:  pulled", want: StderrRecord{Severity: SeverityInfo, Message: "pulled"}},
+		{name: "no level", line: "  pulling image ghcr.io/inspektor-gadget/gadget/trace_exec", want: StderrRecord{Severity: SeverityInfo, Message: "pulling image ghcr.io/inspektor-gadget/gadget/trace_exec"}},
+		{name: "no level mentioning error", line: "0 errors, 1 warning", want: StderrRecord{Severity: SeverityInfo, Message: "0 errors, 1 warning"}},
+		{name: "logfmt without msg", line: "level=info nomsg", want: StderrRecord{Severity: SeverityInfo, Message: "level=info nomsg"}},
+		{name: "logfmt without level", line: `msg="x" a=b`, want: StderrRecord{Severity: SeverityInfo, Message: `msg="x" a=b`}},
+		{name: "unterminated quote", line: `level=warning msg="x`, want: StderrRecord{Severity: SeverityInfo, Message: `level=warning msg="x`}},
+		{name: "not logfmt", line: "a=b c", want: StderrRecord{Severity: SeverityInfo, Message: "a=b c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseStderr(tt.line + "\r\n")
+			tt.want.Line = strings.TrimRight(tt.line, " ")
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("ParseStderr = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStderrLines(t *testing.T) {
+	stderr := "INFO[0000] starting\n\n   \nWARN[0001] lost 2 samples\nError: exit status 1\n"
+	got := ParseStderr(stderr)
+	var sevs []Severity
+	for _, r := range got {
+		sevs = append(sevs, r.Severity)
+	}
+	if want := []Severity{SeverityInfo, SeverityWarning, SeverityError}; !reflect.DeepEqual(sevs, want) {
+		t.Errorf("severities %v, want %v", sevs, want)
+	}
+	if ws := warnings(got); len(ws) != 1 || ws[0].Message != "lost 2 samples" {
+		t.Errorf("warnings = %v", ws)
+	}
+	if got := ParseStderr(""); got != nil {
+		t.Errorf("ParseStderr(\"\") = %v, want nil", got)
+	}
+}
+
+// scriptIG returns an IG running a shell script as ig, answering version
+// itself.
+func scriptIG(t *testing.T, script string, opts ...Option) *IG {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ig")
+	script = "#!/bin/sh\nif [ \"$1\" = version ]; then echo v0.30.0; exit 0; fi\n" + script
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	i, err := New(append([]Option{WithPath(path)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+const warningScript = `echo 'level=warning msg="lost 3 samples"' >&2
+echo 'INFO[0000] done' >&2
+echo '{"comm":"cat"}'
+`
+
+func TestRunWarnings(t *testing.T) {
+	res, err := scriptIG(t, warningScript).Run(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if len(res.Warnings) != 1 || res.Warnings[0].Message != "lost 3 samples" {
+		t.Errorf("Warnings = %v", res.Warnings)
+	}
+
+	i := scriptIG(t, warningScript, WithFailOnWarning())
+	res, err = i.Run(context.Background(), "trace_exec")
+	if !errors.Is(err, ErrWarning) {
+		t.Errorf("Run with WithFailOnWarning: got %v, want ErrWarning", err)
+	}
+	res.Close()
+
+	s, err := i.Start(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range s.Events() {
+	}
+	if err := s.Wait(); !errors.Is(err, ErrWarning) {
+		t.Errorf("session with WithFailOnWarning: got %v, want ErrWarning", err)
+	}
+}
@@ -0,0 +1,215 @@
+package ig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SuperviseOptions configures Supervise.
+type SuperviseOptions struct {
+	// StallAfter restarts the gadget once it printed nothing for this
+	// long. Stalls are not detected if zero.
+	StallAfter time.Duration
+	// MaxRestarts caps the restarts, 3 if zero. A negative value means no
+	// limit.
+	MaxRestarts int
+	// Backoff is how long to wait before restarting, 1s if zero.
+	Backoff time.Duration
+}
+
+// Supervisor keeps a gadget running: it restarts it when it stalls or
+// fails, streaming the events of every run on one channel.
+type Supervisor struct {
+	image  string
+	events chan Event
+	stalls chan StreamStalled
+	done   chan struct{}
+	stop   chan struct{}
+
+	mu       sync.Mutex
+	cur      *GadgetSession
+	stopped  bool
+	restarts int
+	err      error
+}
+
+// Supervise starts a gadget as Start does, and restarts it whenever it
+// stalls for opts.StallAfter or fails, until ctx is done, Stop is called or
+// it ran out of restarts. A gadget exiting successfully on its own, e.g.
+// on "--timeout", is not restarted.
+func (ig *IG) Supervise(ctx context.Context, image string, opts SuperviseOptions, flags ...string) (*Supervisor, error) {
+	if opts.MaxRestarts == 0 {
+		opts.MaxRestarts = 3
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = time.Second
+	}
+	s, err := ig.Start(ctx, image, flags...)
+	if err != nil {
+		return nil, err
+	}
+	sup := &Supervisor{
+		image:  image,
+		events: make(chan Event, cap(s.events)),
+		stalls: make(chan StreamStalled, 16),
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		cur:    s,
+	}
+	go sup.run(ctx, ig, opts, flags)
+	return sup, nil
+}
+
+func (sup *Supervisor) run(ctx context.Context, ig *IG, opts SuperviseOptions, flags []string) {
+	defer func() {
+		close(sup.events)
+		close(sup.stalls)
+		close(sup.done)
+	}()
+
+	s := sup.cur
+	for {
+		stalled := sup.forward(s, opts.StallAfter)
+		<-s.Done()
+		err := s.exitErr()
+
+		sup.mu.Lock()
+		stopped := sup.stopped
+		sup.mu.Unlock()
+		switch {
+		case stopped || ctx.Err() != nil:
+			return
+		case !stalled && err == nil:
+			return
+		case opts.MaxRestarts >= 0 && sup.Restarts() >= opts.MaxRestarts:
+			if err == nil {
+				err = fmt.Errorf("gadget stalled for %s", opts.StallAfter)
+			}
+			sup.setErr(fmt.Errorf("supervising %s: giving up after %d restarts: %w", sup.image, opts.MaxRestarts, err))
+			return
+		}
+
+		select {
+		case <-time.After(opts.Backoff):
+		case <-ctx.Done():
+			return
+		case <-sup.stop:
+			return
+		}
+		next, err := ig.Start(ctx, sup.image, flags...)
+		if err != nil {
+			sup.setErr(fmt.Errorf("supervising %s: restarting: %w", sup.image, err))
+			return
+		}
+
+		sup.mu.Lock()
+		sup.cur = next
+		sup.restarts++
+		stopped = sup.stopped
+		sup.mu.Unlock()
+		if stopped {
+			next.Stop()
+		}
+		s = next
+	}
+}
+
+// forward forwards the events of s until it exits and reports whether it
+// was stopped for stalling.
+func (sup *Supervisor) forward(s *GadgetSession, stallAfter time.Duration) bool {
+	var stalls <-chan StreamStalled
+	if stallAfter > 0 {
+		stalls = s.WatchStalls(stallAfter)
+	}
+	stalled := false
+	events := s.Events()
+	for events != nil {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			sup.events <- ev
+		case st, ok := <-stalls:
+			if !ok {
+				stalls = nil
+				continue
+			}
+			select {
+			case sup.stalls <- st:
+			default:
+			}
+			stalled = true
+			go s.Stop()
+		}
+	}
+	return stalled
+}
+
+func (sup *Supervisor) setErr(err error) {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	sup.err = err
+}
+
+// Events returns the events of every run of the gadget. The channel is
+// closed once the supervisor is done.
+func (sup *Supervisor) Events() <-chan Event {
+	return sup.events
+}
+
+// Stalls returns the stalls that caused restarts. Notifications are dropped
+// if the channel is full.
+func (sup *Supervisor) Stalls() <-chan StreamStalled {
+	return sup.stalls
+}
+
+// Restarts returns how many times the gadget was restarted.
+func (sup *Supervisor) Restarts() int {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	return sup.restarts
+}
+
+// Session returns the current run of the gadget.
+func (sup *Supervisor) Session() *GadgetSession {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	return sup.cur
+}
+
+// Done returns a channel closed once the supervisor is done.
+func (sup *Supervisor) Done() <-chan struct{} {
+	return sup.done
+}
+
+// Wait waits for the supervisor to be done and returns why it gave up, nil
+// if the gadget was stopped or exited successfully.
+func (sup *Supervisor) Wait() error {
+	<-sup.done
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	return sup.err
+}
+
+// Stop stops the gadget and the supervision.
+func (sup *Supervisor) Stop() error {
+	sup.mu.Lock()
+	if !sup.stopped {
+		sup.stopped = true
+		close(sup.stop)
+	}
+	s := sup.cur
+	sup.mu.Unlock()
+
+	s.Stop()
+	return sup.Wait()
+}
@@ -0,0 +1,92 @@
+package ig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Filter fields used to target processes.
+const (
+	pidField   = "proc.pid"
+	mntnsField = "proc.mntns_id"
+)
+
+// MountNamespace returns the ID of the mount namespace of the process pid,
+// as reported by gadgets in proc.mntns_id.
+func MountNamespace(pid int) (uint64, error) {
+	if pid <= 0 {
+		return 0, fmt.Errorf("invalid pid %d", pid)
+	}
+	link, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+	if err != nil {
+		return 0, fmt.Errorf("reading mount namespace of %d: %w", pid, err)
+	}
+	// The link reads "mnt:[4026531841]".
+	id, ok := strings.CutPrefix(link, "mnt:[")
+	if !ok || !strings.HasSuffix(id, "]") {
+		return 0, fmt.Errorf("unexpected mount namespace %q of %d", link, pid)
+	}
+	return strconv.ParseUint(strings.TrimSuffix(id, "]"), 10, 64)
+}
+
+// CgroupMountNamespace returns the mount namespace of the processes of the
+// cgroup at path, absolute or relative to /sys/fs/cgroup. It fails if the
+// cgroup has no process or shares the mount namespace of the host, as
+// tracing that namespace would trace the whole host.
+func CgroupMountNamespace(path string) (uint64, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cgroupRoot, path)
+	}
+	b, err := os.ReadFile(filepath.Join(path, "cgroup.procs"))
+	if err != nil {
+		return 0, fmt.Errorf("reading processes of cgroup %s: %w", path, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0]))
+	if err != nil {
+		return 0, fmt.Errorf("cgroup %s has no process", path)
+	}
+	id, err := MountNamespace(pid)
+	if err != nil {
+		return 0, err
+	}
+	if host, err := MountNamespace(1); err == nil && host == id {
+		return 0, fmt.Errorf("cgroup %s shares the mount namespace of the host", path)
+	}
+	return id, nil
+}
+
+// targetFilters returns the filter expressions scoping a run to the targets
+// of f.
+func (f RunFlags) targetFilters() ([]string, error) {
+	var filters []string
+	var errs []error
+	if f.PID < 0 {
+		errs = append(errs, fmt.Errorf("invalid pid %d", f.PID))
+	} else if f.PID > 0 {
+		if _, err := os.Stat(fmt.Sprintf("/proc/%d", f.PID)); err != nil {
+			errs = append(errs, fmt.Errorf("no process %d", f.PID))
+		}
+		filters = append(filters, fmt.Sprintf("%s==%d", pidField, f.PID))
+	}
+	if f.MountNamespace != 0 {
+		filters = append(filters, fmt.Sprintf("%s==%d", mntnsField, f.MountNamespace))
+	}
+	if f.Cgroup != "" {
+		id, err := CgroupMountNamespace(f.Cgroup)
+		if err != nil {
+			errs = append(errs, err)
+		} else if f.MountNamespace != 0 && id != f.MountNamespace {
+			errs = append(errs, fmt.Errorf("cgroup %s is not in mount namespace %d", f.Cgroup, f.MountNamespace))
+		} else if f.MountNamespace == 0 {
+			filters = append(filters, fmt.Sprintf("%s==%d", mntnsField, id))
+		}
+	}
+	return filters, errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package ig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WithTranscripts makes every GadgetSession record a transcript of its run
+// and write it to dir once it ends, as "<gadget>-<start time>.json". The
+// transcript bundles everything needed to reproduce or report an issue
+// upstream: see Transcript.
+func WithTranscripts(dir string) Option {
+	return func(ig *IG) {
+		ig.transcriptDir = dir
+	}
+}
+
+// Kinds of TranscriptEntry.
+const (
+	TranscriptStdout = "stdout"
+	TranscriptStderr = "stderr"
+	TranscriptSignal = "signal"
+	TranscriptState  = "state"
+	TranscriptExit   = "exit"
+)
+
+// TranscriptEntry is one thing that happened during a session.
+type TranscriptEntry struct {
+	Time time.Time `json:"time"`
+	// Kind is one of the Transcript constants.
+	Kind string `json:"kind"`
+	// Data is the output chunk as ig wrote it, the signal sent, the new
+	// state or the exit status.
+	Data string `json:"data"`
+}
+
+// Transcript is the record of a session: how ig was invoked and every
+// output chunk, signal, state change and exit, timestamped.
+type Transcript struct {
+	RunID   string  `json:"runId"`
+	Version Version `json:"-"`
+	// Argv is the full command line of ig.
+	Argv []string `json:"argv"`
+	// Env is the environment added with WithEnv. The inherited
+	// environment is left out, as it may hold credentials.
+	Env     []string          `json:"env,omitempty"`
+	Started time.Time         `json:"started"`
+	Entries []TranscriptEntry `json:"entries"`
+
+	mu sync.Mutex
+}
+
+func (t *Transcript) record(kind, data string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Entries = append(t.Entries, TranscriptEntry{Time: time.Now(), Kind: kind, Data: data})
+}
+
+// MarshalJSON renders the transcript with the version of ig as a string.
+func (t *Transcript) MarshalJSON() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type transcript Transcript
+	return json.Marshal(struct {
+		IGVersion string `json:"igVersion"`
+		*transcript
+	}{t.Version.String(), (*transcript)(t)})
+}
+
+// WriteFile writes the transcript as indented JSON to path.
+func (t *Transcript) WriteFile(path string) error {
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding transcript: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("writing transcript: %w", err)
+	}
+	return nil
+}
+
+// fileName returns the name of the transcript of image in a transcript
+// directory.
+func (t *Transcript) fileName(image string) string {
+	return fmt.Sprintf("%s-%s-%s.json", GadgetName(image), t.Started.Format("20060102T150405.000000000"), t.RunID)
+}
+
+// transcriptWriter records what is written to it as entries of kind.
+type transcriptWriter struct {
+	t    *Transcript
+	kind string
+}
+
+func (w transcriptWriter) Write(p []byte) (int, error) {
+	w.t.record(w.kind, string(p))
+	return len(p), nil
+}
+
+// Transcript returns the transcript of the session, nil unless the IG was
+// created WithTranscripts. It is complete once the session ended.
+func (s *GadgetSession) Transcript() *Transcript {
+	return s.transcript
+}
+
+func (s *GadgetSession) writeTranscript() error {
+	path := filepath.Join(s.transcriptDir, s.transcript.fileName(s.image))
+	if err := s.transcript.WriteFile(path); err != nil {
+		return fmt.Errorf("session of %s: %w", s.image, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrVersionChanged is returned, wrapped, by every operation once the ig
+// binary on disk was replaced by another version, e.g. by a package manager
+// upgrade, unless the IG was created with WithReprobe.
+var ErrVersionChanged = errors.New("ig binary changed version")
+
+// WithReprobe makes an IG probe the version of its binary again when the
+// binary on disk changes, and carry on with the new one, instead of failing
+// with ErrVersionChanged.
+func WithReprobe() Option {
+	return func(ig *IG) {
+		ig.reprobe = true
+	}
+}
+
+// binary tracks the ig binary of an IG and the version it was probed as.
+type binary struct {
+	mu      sync.Mutex
+	info    os.FileInfo
+	version Version
+	// changed is the version the binary was replaced by, so later calls
+	// fail without probing again.
+	changed *Version
+}
+
+// probeVersion returns the version of the binary, from the version cache or
+// by running ig version, and records the file it was probed from.
+func (ig *IG) probeVersion(ctx context.Context) (Version, error) {
+	info, err := os.Stat(ig.path)
+	if err != nil {
+		return Version{}, err
+	}
+	key := versionKey(ig.path, info)
+	if v, ok := ig.cachedVersion(key); ok {
+		ig.bin.info = info
+		return v, nil
+	}
+	out, err := ig.run(ctx, "", "version")
+	if err != nil {
+		return Version{}, err
+	}
+	v, err := parseVersionOutput(out.stdout)
+	if err != nil {
+		return Version{}, err
+	}
+	if err := ig.cacheVersion(key, &v); err != nil {
+		return Version{}, fmt.Errorf("caching version: %w", err)
+	}
+	ig.bin.info = info
+	return v, nil
+}
+
+// checkBinary probes the version again if the binary changed on disk since
+// it was last probed.
+func (ig *IG) checkBinary(ctx context.Context) error {
+	ig.bin.mu.Lock()
+	defer ig.bin.mu.Unlock()
+
+	if c := ig.bin.changed; c != nil {
+		return fmt.Errorf("%w: %s is now %s, was %s", ErrVersionChanged, ig.path, c, ig.bin.version)
+	}
+	info, err := os.Stat(ig.path)
+	if err != nil {
+		return fmt.Errorf("checking ig binary: %w", err)
+	}
+	old := ig.bin.info
+	if os.SameFile(old, info) && old.Size() == info.Size() && old.ModTime().Equal(info.ModTime()) {
+		return nil
+	}
+
+	v, err := ig.probeVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("probing changed ig binary: %w", err)
+	}
+	switch {
+	case v == ig.bin.version:
+	case ig.reprobe:
+		ig.bin.version = v
+	default:
+		ig.bin.changed = &v
+		return fmt.Errorf("%w: %s is now %s, was %s", ErrVersionChanged, ig.path, v, ig.bin.version)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package v1
+
+import "sync"
+
+// warned records the deprecated identifiers already reported.
+var warned sync.Map
+
+// deprecated logs, once per process, that name is deprecated in favor of
+// replacement. Deprecated identifiers call it on use.
+func (c *Client) deprecated(name, replacement string) {
+	if _, loaded := warned.LoadOrStore(name, struct{}{}); loaded {
+		return
+	}
+	c.ig.Logger().Warn("deprecated API in use", "api", "v1."+name, "replacement", replacement)
+}
@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeprecatedWarnsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ig")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho v0.30.0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	c, err := New(WithPath(path), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.deprecated("TestOld", "TestNew")
+	c.deprecated("TestOld", "TestNew")
+
+	if n := strings.Count(logs.String(), "deprecated API in use"); n != 1 {
+		t.Fatalf("got %d warnings, want 1:\n%s", n, logs.String())
+	}
+	for _, want := range []string{"api=v1.TestOld", "replacement=TestNew", "level=WARN"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("warning lacks %q:\n%s", want, logs.String())
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Package v1 is the stable API of the library, for production consumers.
+// It covers running gadgets and managing their images, on top of package
+// ig, which keeps evolving.
+//
+// # Compatibility
+//
+// Within v1, programs written against the package keep compiling and
+// behaving as documented: exported identifiers are not removed or renamed,
+// function signatures don't change, struct fields are only added and
+// options keep their defaults. Incompatible changes go to a new major
+// package, ig/v2, next to this one. Client.IG, which exposes package ig, is
+// outside this promise.
+//
+// # Deprecation
+//
+// Identifiers superseded within v1 are marked with a "Deprecated:"
+// paragraph naming their replacement and keep working until v2. Their
+// first use in a process logs a warning to the logger of the Client (see
+// WithLogger), so deprecated calls show up in production logs before the
+// upgrade removes them.
+package v1
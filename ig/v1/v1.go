@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Client runs a given ig binary. It is safe for concurrent use.
+type Client struct {
+	ig *ig.IG
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	ig []ig.Option
+}
+
+// WithPath sets the ig binary to run instead of looking up "ig" in PATH.
+func WithPath(path string) Option {
+	return func(o *options) {
+		o.ig = append(o.ig, ig.WithPath(path))
+	}
+}
+
+// WithEnv adds "KEY=value" environment variables to every ig invocation.
+func WithEnv(env ...string) Option {
+	return func(o *options) {
+		o.ig = append(o.ig, ig.WithEnv(env...))
+	}
+}
+
+// WithLogger makes the client log ig invocations and deprecation warnings
+// to l. Nothing is logged by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.ig = append(o.ig, ig.WithLogger(l))
+	}
+}
+
+// New locates the ig binary and probes its version.
+func New(opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	i, err := ig.New(o.ig...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{ig: i}, nil
+}
+
+// Wrap returns a client running gadgets with i, for programs already
+// configuring an ig.IG.
+func Wrap(i *ig.IG) *Client {
+	return &Client{ig: i}
+}
+
+// IG returns the ig.IG of the client, for features v1 doesn't cover. It is
+// not covered by the compatibility promise of v1.
+func (c *Client) IG() *ig.IG {
+	return c.ig
+}
+
+// Version returns the version of the ig binary, e.g. "v0.30.0".
+func (c *Client) Version() string {
+	return c.ig.Version().String()
+}
+
+// Pull pulls a gadget image, passing flags to ig image pull.
+func (c *Client) Pull(ctx context.Context, image string, flags ...string) error {
+	return c.ig.Pull(ctx, image, flags...)
+}
+
+// Remove removes a gadget image from the local store.
+func (c *Client) Remove(ctx context.Context, image string) error {
+	return c.ig.Remove(ctx, image)
+}
+
+// Result is the output of a gadget run.
+type Result struct {
+	// RunID identifies the run.
+	RunID  string
+	Stdout string
+	Stderr string
+	// ExitCode is the exit code of ig, -1 if it was killed by a signal.
+	ExitCode int
+	// Duration is how long ig ran.
+	Duration time.Duration
+}
+
+// Run runs a gadget until it exits, passing flags to ig run, and returns
+// what it printed, also when it fails.
+func (c *Client) Run(ctx context.Context, image string, flags ...string) (Result, error) {
+	res, err := c.ig.Run(ctx, image, flags...)
+	if res == nil {
+		return Result{}, err
+	}
+	defer res.Close()
+	return Result{
+		RunID:    res.RunID,
+		Stdout:   res.Stdout,
+		Stderr:   res.Stderr,
+		ExitCode: res.ExitCode,
+		Duration: res.Duration,
+	}, err
+}
+
+// Event is one record emitted by a running gadget.
+type Event struct {
+	// Gadget is the image of the gadget that emitted the event.
+	Gadget string
+	// Raw is the line printed by ig.
+	Raw string
+	// Fields is the decoded JSON event, nil for lines that aren't JSON
+	// objects.
+	Fields map[string]any
+	// Time is when the gadget saw the event, or when it was read if the
+	// event has no timestamp.
+	Time time.Time
+}
+
+// Session is a gadget running in the background.
+type Session struct {
+	s      *ig.GadgetSession
+	events chan Event
+}
+
+// Start starts a gadget in the background, passing flags to ig run.
+func (c *Client) Start(ctx context.Context, image string, flags ...string) (*Session, error) {
+	s, err := c.ig.Start(ctx, image, flags...)
+	if err != nil {
+		return nil, err
+	}
+	sess := &Session{s: s, events: make(chan Event, cap(s.Events()))}
+	go func() {
+		defer close(sess.events)
+		for ev := range s.Events() {
+			sess.events <- Event{Gadget: ev.Gadget, Raw: ev.Raw, Fields: ev.Fields, Time: ev.Time()}
+		}
+	}()
+	return sess, nil
+}
+
+// RunID identifies the run of the session.
+func (s *Session) RunID() string {
+	return s.s.RunID()
+}
+
+// Events returns the events of the gadget, closed once it exited.
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
+// Stderr returns what the gadget printed on stderr so far.
+func (s *Session) Stderr() string {
+	return s.s.Stderr()
+}
+
+// Wait waits for the gadget to exit and returns its error, if any.
+func (s *Session) Wait() error {
+	return s.s.Wait()
+}
+
+// Stop interrupts the gadget and waits for it to exit.
+func (s *Session) Stop() error {
+	return s.s.Stop()
+}
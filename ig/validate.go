@@ -0,0 +1,426 @@
+package ig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMetadata is returned by Findings.Err for metadata with errors.
+var ErrInvalidMetadata = errors.New("invalid gadget metadata")
+
+// Severity of a Finding or of a StderrRecord.
+type Severity string
+
+const (
+	// SeverityError marks metadata ig rejects or misinterprets.
+	SeverityError Severity = "error"
+	// SeverityWarning marks incomplete metadata, such as fields without
+	// descriptions.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem of gadget metadata.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Path locates the problem in the metadata, e.g.
+	// "datasources.exec.fields.comm".
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Path == "" {
+		return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Path, f.Message)
+}
+
+// Findings are the result of Validate, sorted by path.
+type Findings []Finding
+
+// Err returns an error wrapping ErrInvalidMetadata listing the findings of
+// SeverityError, or nil if there are none, e.g. to gate publishing.
+func (fs Findings) Err() error {
+	var errs []string
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.Path+": "+f.Message)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidMetadata, strings.Join(errs, "; "))
+}
+
+// WasmExports are the functions the wasm module of a gadget may export for
+// ig to call.
+var WasmExports = []string{"gadgetInit", "gadgetPreStart", "gadgetStart", "gadgetStop"}
+
+// Validate checks the metadata of a gadget for completeness: descriptions
+// of the gadget, its fields and params, column annotations, param types and
+// defaults and, for projects with a built wasm module, its exports.
+// imageOrDir is either a gadget project directory, checked without ig, or
+// an image, checked from ig image inspect.
+func (ig *IG) Validate(ctx context.Context, imageOrDir string) (Findings, error) {
+	if fi, err := os.Stat(imageOrDir); err == nil && fi.IsDir() {
+		return ValidateDir(imageOrDir)
+	}
+
+	info, err := ig.inspect(ctx, imageOrDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.Metadata) > 0 {
+		return ValidateMetadata(info.Metadata)
+	}
+
+	// Without the metadata file, check what ig derived from it.
+	var v validation
+	for _, ds := range info.DataSources {
+		for _, f := range ds.Fields {
+			if f.Empty {
+				continue
+			}
+			annotations := map[string]any{}
+			for k, a := range f.Annotations {
+				annotations[k] = a
+			}
+			v.field("datasources."+ds.Name+".fields."+f.Path, map[string]any{"annotations": annotations})
+		}
+	}
+	for _, p := range info.Params {
+		possible := make([]any, len(p.PossibleValues))
+		for i, s := range p.PossibleValues {
+			possible[i] = s
+		}
+		v.param("params."+p.Flag(), map[string]any{
+			"key":            p.Key,
+			"description":    p.Description,
+			"defaultValue":   p.DefaultValue,
+			"typeHint":       p.TypeHint,
+			"possibleValues": possible,
+		})
+	}
+	return v.findings(), nil
+}
+
+// ValidateDir validates the gadget project in dir, see Validate.
+func ValidateDir(dir string) (Findings, error) {
+	path, err := MetadataPath(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	md, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata: %w", err)
+	}
+	fs, err := ValidateMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+
+	wasm, err := buildWasm(dir)
+	if err != nil || wasm == "" {
+		return fs, err
+	}
+	var v validation
+	v.wasm(wasm)
+	return append(fs, v.findings()...).sorted(), nil
+}
+
+// ValidateMetadata validates the content of a metadata file, see Validate.
+func ValidateMetadata(md []byte) (Findings, error) {
+	doc, err := parseYAML(md)
+	if err != nil {
+		return nil, fmt.Errorf("parsing metadata: %w", err)
+	}
+	root, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("parsing metadata: not a mapping")
+	}
+
+	var v validation
+	if s, _ := root["name"].(string); s == "" {
+		v.add(SeverityError, "name", "missing")
+	}
+	if s, _ := root["description"].(string); s == "" {
+		v.add(SeverityWarning, "description", "missing")
+	}
+
+	datasources, _ := root["datasources"].(map[string]any)
+	if len(datasources) == 0 {
+		v.add(SeverityWarning, "datasources", "no data sources")
+	}
+	for name, ds := range datasources {
+		path := "datasources." + name
+		fields, _ := mapValue(ds)["fields"].(map[string]any)
+		if len(fields) == 0 {
+			v.add(SeverityWarning, path+".fields", "no fields")
+		}
+		for fname, f := range fields {
+			v.field(path+".fields."+fname, mapValue(f))
+		}
+	}
+
+	params, _ := root["params"].(map[string]any)
+	for section, ps := range params {
+		for name, p := range mapValue(ps) {
+			v.param("params."+section+"."+name, mapValue(p))
+		}
+	}
+	return v.findings(), nil
+}
+
+func mapValue(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+type validation struct {
+	fs Findings
+}
+
+func (v *validation) add(sev Severity, path, format string, args ...any) {
+	v.fs = append(v.fs, Finding{Severity: sev, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) findings() Findings {
+	return v.fs.sorted()
+}
+
+func (fs Findings) sorted() Findings {
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].Path < fs[j].Path })
+	return fs
+}
+
+// columnAnnotations are the column annotations of fields taking a value
+// of limited form, with a check returning the problem of a value.
+var columnAnnotations = map[string]func(string) string{
+	"columns.width":    positiveInt,
+	"columns.minwidth": positiveInt,
+	"columns.maxwidth": positiveInt,
+	"columns.hidden":   isBool,
+	"columns.fixed":    isBool,
+	"columns.alignment": func(s string) string {
+		if s != "left" && s != "right" {
+			return "must be left or right"
+		}
+		return ""
+	},
+}
+
+func positiveInt(s string) string {
+	if n, err := strconv.Atoi(s); err != nil || n <= 0 {
+		return "must be a positive integer"
+	}
+	return ""
+}
+
+func isBool(s string) string {
+	if _, err := strconv.ParseBool(s); err != nil {
+		return "must be true or false"
+	}
+	return ""
+}
+
+func (v *validation) field(path string, f map[string]any) {
+	annotations := mapValue(f["annotations"])
+	if s, _ := annotations["description"].(string); s == "" {
+		v.add(SeverityWarning, path, "no description")
+	}
+	for name, check := range columnAnnotations {
+		a, ok := annotations[name]
+		if !ok {
+			continue
+		}
+		if problem := check(fmt.Sprint(a)); problem != "" {
+			v.add(SeverityError, path+".annotations."+name, "%q %s", a, problem)
+		}
+	}
+}
+
+// typeHints check the values of params of each type hint. Params without
+// a type hint are strings.
+var typeHints = map[string]func(string) error{
+	"":       func(string) error { return nil },
+	"string": func(string) error { return nil },
+	"bool":   func(s string) error { _, err := strconv.ParseBool(s); return err },
+	"int":    intOf(64), "int8": intOf(8), "int16": intOf(16), "int32": intOf(32), "int64": intOf(64),
+	"uint": uintOf(64), "uint8": uintOf(8), "uint16": uintOf(16), "uint32": uintOf(32), "uint64": uintOf(64),
+	"float32":  func(s string) error { _, err := strconv.ParseFloat(s, 32); return err },
+	"float64":  func(s string) error { _, err := strconv.ParseFloat(s, 64); return err },
+	"duration": func(s string) error { _, err := time.ParseDuration(s); return err },
+	"ip": func(s string) error {
+		if net.ParseIP(s) == nil {
+			return errors.New("invalid IP address")
+		}
+		return nil
+	},
+	"bytes": func(string) error { return nil },
+}
+
+func intOf(bits int) func(string) error {
+	return func(s string) error { _, err := strconv.ParseInt(s, 10, bits); return err }
+}
+
+func uintOf(bits int) func(string) error {
+	return func(s string) error { _, err := strconv.ParseUint(s, 10, bits); return err }
+}
+
+func (v *validation) param(path string, p map[string]any) {
+	if s, _ := p["key"].(string); s == "" {
+		v.add(SeverityError, path, "no key")
+	}
+	if s, _ := p["description"].(string); s == "" {
+		v.add(SeverityWarning, path, "no description")
+	}
+	hint, _ := p["typeHint"].(string)
+	check, ok := typeHints[hint]
+	if !ok {
+		v.add(SeverityError, path+".typeHint", "unknown type %q", hint)
+		return
+	}
+	def, hasDefault := p["defaultValue"].(string)
+	if hasDefault && def != "" {
+		if err := check(def); err != nil {
+			v.add(SeverityError, path+".defaultValue", "%q is not a valid %s", def, hint)
+		}
+	}
+	possible, _ := p["possibleValues"].([]any)
+	for _, pv := range possible {
+		if err := check(fmt.Sprint(pv)); err != nil {
+			v.add(SeverityError, path+".possibleValues", "%q is not a valid %s", pv, hint)
+		}
+	}
+	if len(possible) > 0 && hasDefault && def != "" {
+		found := false
+		for _, pv := range possible {
+			found = found || fmt.Sprint(pv) == def
+		}
+		if !found {
+			v.add(SeverityError, path+".defaultValue", "%q is not a possible value", def)
+		}
+	}
+}
+
+var buildWasmRe = regexp.MustCompile(`(?m)^wasm:[ \t]*["']?([^"'#\s]+)`)
+
+// buildWasm returns the path of the built wasm module of the project in
+// dir, or "" if it has none or only its source.
+func buildWasm(dir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, DefaultBuildFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading build file: %w", err)
+	}
+	m := buildWasmRe.FindSubmatch(b)
+	if m == nil || !strings.HasSuffix(string(m[1]), ".wasm") {
+		return "", nil
+	}
+	path := string(m[1])
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+	return path, nil
+}
+
+func (v *validation) wasm(path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		v.add(SeverityError, "wasm", "%s", err)
+		return
+	}
+	exports, err := wasmExports(b)
+	if err != nil {
+		v.add(SeverityError, "wasm", "%s: %s", filepath.Base(path), err)
+		return
+	}
+	for _, e := range WasmExports {
+		if exports[e] {
+			return
+		}
+	}
+	v.add(SeverityError, "wasm", "%s exports none of %s", filepath.Base(path), strings.Join(WasmExports, ", "))
+}
+
+// wasmExports returns the names of the functions a wasm module exports.
+func wasmExports(b []byte) (map[string]bool, error) {
+	if !bytes.HasPrefix(b, []byte("\x00asm\x01\x00\x00\x00")) {
+		return nil, errors.New("not a wasm module")
+	}
+	r := bytes.NewReader(b[8:])
+	for r.Len() > 0 {
+		id, _ := r.ReadByte()
+		size, err := readULEB128(r)
+		if err != nil || size > uint64(r.Len()) {
+			return nil, errors.New("truncated section")
+		}
+		section := make([]byte, size)
+		r.Read(section)
+		if id == 7 {
+			return wasmExportSection(bytes.NewReader(section))
+		}
+	}
+	return map[string]bool{}, nil
+}
+
+func wasmExportSection(r *bytes.Reader) (map[string]bool, error) {
+	errTruncated := errors.New("truncated export section")
+	n, err := readULEB128(r)
+	if err != nil {
+		return nil, errTruncated
+	}
+	exports := map[string]bool{}
+	for i := uint64(0); i < n; i++ {
+		l, err := readULEB128(r)
+		if err != nil || l > uint64(r.Len()) {
+			return nil, errTruncated
+		}
+		name := make([]byte, l)
+		r.Read(name)
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, errTruncated
+		}
+		if _, err := readULEB128(r); err != nil {
+			return nil, errTruncated
+		}
+		if kind == 0 {
+			exports[string(name)] = true
+		}
+	}
+	return exports, nil
+}
+
+// readULEB128 reads an unsigned LEB128 integer, as wasm encodes sizes and
+// counts.
+func readULEB128(r *bytes.Reader) (uint64, error) {
+	var n uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+	return 0, errors.New("LEB128 integer overflow")
+}
@@ -0,0 +1,194 @@
+package ig
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want []string
+	}{
+		{name: "trace_exec", md: "testdata/trace_exec.yaml", want: nil},
+		{
+			name: "trace_tcp",
+			md:   "testdata/trace_tcp.yaml",
+			want: []string{"warning: datasources.tcp.fields.error_raw: no description"},
+		},
+		{
+			name: "empty",
+			md:   "",
+			want: []string{
+				"warning: datasources: no data sources",
+				"warning: description: missing",
+				"error: name: missing",
+			},
+		},
+		{
+			name: "invalid annotations and params",
+			md: `name: x
+description: y
+datasources:
+  ds:
+    fields:
+      a:
+        annotations:
+          description: a
+          columns.width: -1
+          columns.alignment: center
+  empty: {}
+params:
+  ebpf:
+    p:
+      key: p
+      description: p
+      typeHint: uint8
+      defaultValue: "300"
+      possibleValues: ["1", "x"]
+    q:
+      typeHint: color
+`,
+			want: []string{
+				`error: datasources.ds.fields.a.annotations.columns.alignment: "center" must be left or right`,
+				`error: datasources.ds.fields.a.annotations.columns.width: "-1" must be a positive integer`,
+				"warning: datasources.empty.fields: no fields",
+				`error: params.ebpf.p.defaultValue: "300" is not a valid uint8`,
+				`error: params.ebpf.p.defaultValue: "300" is not a possible value`,
+				`error: params.ebpf.p.possibleValues: "x" is not a valid uint8`,
+				"error: params.ebpf.q: no key",
+				"warning: params.ebpf.q: no description",
+				`error: params.ebpf.q.typeHint: unknown type "color"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := []byte(tt.md)
+			if strings.HasPrefix(tt.md, "testdata/") {
+				var err error
+				if md, err = os.ReadFile(tt.md); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fs, err := ValidateMetadata(md)
+			if err != nil {
+				t.Fatalf("ValidateMetadata: %v", err)
+			}
+			var got []string
+			for _, f := range fs {
+				got = append(got, f.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateMetadataErrors(t *testing.T) {
+	for _, md := range []string{"- a\n- b", "a: 1\n  b: 2"} {
+		if _, err := ValidateMetadata([]byte(md)); err == nil {
+			t.Errorf("ValidateMetadata(%q) succeeded", md)
+		}
+	}
+}
+
+// wasmModule returns a wasm module with the sections, each an id and its
+// content.
+func wasmModule(sections ...[]byte) []byte {
+	b := []byte("\x00asm\x01\x00\x00\x00")
+	for _, s := range sections {
+		b = append(b, s[0], byte(len(s)-1))
+		b = append(b, s[1:]...)
+	}
+	return b
+}
+
+// exportSection returns an export section of function exports.
+func exportSection(names ...string) []byte {
+	s := []byte{7, byte(len(names))}
+	for _, n := range names {
+		s = append(s, byte(len(n)))
+		s = append(s, n...)
+		s = append(s, 0, 0) // function 0
+	}
+	return s
+}
+
+func TestWasmExports(t *testing.T) {
+	tests := []struct {
+		name   string
+		module []byte
+		want   map[string]bool
+	}{
+		{name: "no sections", module: wasmModule(), want: map[string]bool{}},
+		{name: "exports", module: wasmModule(exportSection("gadgetInit", "gadgetStart")), want: map[string]bool{"gadgetInit": true, "gadgetStart": true}},
+		{
+			name: "after other sections",
+			// A type section with one func type () -> (), then the exports.
+			module: wasmModule([]byte{1, 1, 0x60, 0, 0}, exportSection("gadgetStart")),
+			want:   map[string]bool{"gadgetStart": true},
+		},
+		{
+			name: "memory and global exports",
+			// "mem" is a memory (2), "g" a global (3).
+			module: wasmModule([]byte{7, 3, 3, 'm', 'e', 'm', 2, 0, 1, 'g', 3, 0, 1, 'f', 0, 0x80, 0x01}),
+			want:   map[string]bool{"f": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wasmExports(tt.module)
+			if err != nil {
+				t.Fatalf("wasmExports: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wasmExports = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWasmExportsInvalid(t *testing.T) {
+	valid := wasmModule([]byte{1, 1, 0x60, 0, 0}, exportSection("gadgetInit", "gadgetStart"))
+	tests := []struct {
+		name   string
+		module []byte
+		err    string
+	}{
+		{name: "empty", module: nil, err: "not a wasm module"},
+		{name: "elf", module: []byte("\x7fELF\x02\x01\x01"), err: "not a wasm module"},
+		{name: "wrong version", module: []byte("\x00asm\x02\x00\x00\x00"), err: "not a wasm module"},
+		{name: "section without size", module: append(wasmModule(), 7), err: "truncated section"},
+		{name: "section past end", module: append(wasmModule(), 7, 10, 1), err: "truncated section"},
+		{name: "unterminated size", module: append(wasmModule(), 7, 0x80, 0x80), err: "truncated section"},
+		{name: "overlong size", module: append(wasmModule(), 7, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01), err: "truncated section"},
+		{name: "empty export section", module: wasmModule([]byte{7}), err: "truncated export section"},
+		{name: "count past exports", module: wasmModule([]byte{7, 2, 1, 'f', 0, 0}), err: "truncated export section"},
+		{name: "name past section", module: wasmModule([]byte{7, 1, 9, 'f'}), err: "truncated export section"},
+		{name: "no kind", module: wasmModule([]byte{7, 1, 1, 'f'}), err: "truncated export section"},
+		{name: "no index", module: wasmModule([]byte{7, 1, 1, 'f', 0}), err: "truncated export section"},
+		{name: "huge count", module: wasmModule([]byte{7, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}), err: "truncated export section"},
+	}
+	// Every truncation of a valid module is an error, not a panic.
+	for n := 8; n < len(valid); n++ {
+		if _, err := wasmExports(valid[:n]); err != nil && !strings.Contains(err.Error(), "truncated") {
+			t.Errorf("wasmExports(valid[:%d]): %v", n, err)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := wasmExports(tt.module)
+			if err == nil {
+				t.Fatal("wasmExports succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("wasmExports: %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
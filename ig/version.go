@@ -0,0 +1,122 @@
+package ig
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version of ig, such as v0.30.0.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the pre-release suffix without its dash, e.g. "rc.1".
+	Pre string
+}
+
+var versionRe = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?`)
+
+// ParseVersion parses versions such as "v0.30.0", "0.30" or "v0.31.0-rc.1".
+// Build metadata is ignored.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimSpace(s)
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil || m[0] != strings.SplitN(s, "+", 2)[0] {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	return versionFromMatch(m), nil
+}
+
+// MustParseVersion is like ParseVersion but panics on error. It is meant for
+// version constants.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parseVersionOutput extracts the version from the output of "ig version",
+// which may be decorated depending on the release.
+func parseVersionOutput(out string) (Version, error) {
+	m := versionRe.FindStringSubmatch(out)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version found in %q", strings.TrimSpace(out))
+	}
+	return versionFromMatch(m), nil
+}
+
+func versionFromMatch(m []string) Version {
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	v.Pre = m[4]
+	return v
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older than, equal to
+// or newer than o. Pre-releases are older than the release they precede and
+// compare as SemVer says, so "rc.2" is older than "rc.10".
+func (v Version) Compare(o Version) int {
+	for _, d := range [...]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return comparePre(v.Pre, o.Pre)
+}
+
+// comparePre compares pre-release suffixes identifier by identifier:
+// numeric identifiers as numbers and older than alphanumeric ones, and a
+// suffix older than the longer ones it prefixes.
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, y := as[i], bs[i]
+		xn, xerr := strconv.ParseUint(x, 10, 64)
+		yn, yerr := strconv.ParseUint(y, 10, 64)
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				return cmp.Compare(xn, yn)
+			}
+		case xerr == nil:
+			return -1
+		case yerr == nil:
+			return 1
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// AtLeast reports whether v is o or newer.
+func (v Version) AtLeast(o Version) bool {
+	return v.Compare(o) >= 0
+}
@@ -0,0 +1,37 @@
+package ig
+
+import "testing"
+
+func TestVersionCompare(t *testing.T) {
+	// Each version is older than the next, as in the example of SemVer §11.
+	ordered := []string{
+		"v0.29.9",
+		"v0.30.0-alpha",
+		"v0.30.0-alpha.1",
+		"v0.30.0-alpha.beta",
+		"v0.30.0-beta",
+		"v0.30.0-beta.2",
+		"v0.30.0-beta.11",
+		"v0.30.0-rc.1",
+		"v0.30.0-rc.2",
+		"v0.30.0-rc.10",
+		"v0.30.0",
+		"v0.30.1",
+		"v1.0.0",
+	}
+	for i, a := range ordered {
+		va := MustParseVersion(a)
+		if got := va.Compare(va); got != 0 {
+			t.Errorf("%s.Compare(%s) = %d, want 0", a, a, got)
+		}
+		for _, b := range ordered[i+1:] {
+			vb := MustParseVersion(b)
+			if got := va.Compare(vb); got != -1 {
+				t.Errorf("%s.Compare(%s) = %d, want -1", a, b, got)
+			}
+			if got := vb.Compare(va); got != 1 {
+				t.Errorf("%s.Compare(%s) = %d, want 1", b, a, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package ig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WithVersionCache also keeps the probed versions of ig binaries in the
+// state file at path, so New doesn't run ig version in every process
+// either. If path is empty, a file in the user cache directory is used.
+//
+// Versions are always cached in memory, keyed by binary path, size and
+// modification time: IGs created for an unchanged binary reuse its version.
+func WithVersionCache(path string) Option {
+	return func(ig *IG) {
+		if path == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				dir = os.TempDir()
+			}
+			path = filepath.Join(dir, "ig-testing-framework", "versions.json")
+		}
+		ig.versionFile = path
+	}
+}
+
+// versionKey identifies a binary file as probed.
+func versionKey(path string, info os.FileInfo) string {
+	return fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())
+}
+
+// versions is the in-memory version cache.
+var versions = struct {
+	mu sync.Mutex
+	m  map[string]Version
+}{m: map[string]Version{}}
+
+// cachedVersion returns the cached version of the binary with key.
+func (ig *IG) cachedVersion(key string) (Version, bool) {
+	versions.mu.Lock()
+	defer versions.mu.Unlock()
+
+	if v, ok := versions.m[key]; ok {
+		return v, true
+	}
+	if ig.versionFile == "" {
+		return Version{}, false
+	}
+	s, ok := loadVersionFile(ig.versionFile)[key]
+	if !ok {
+		return Version{}, false
+	}
+	v, err := ParseVersion(s)
+	if err != nil {
+		return Version{}, false
+	}
+	versions.m[key] = v
+	return v, true
+}
+
+// cacheVersion records the version of the binary with key, or forgets it
+// if v is nil.
+func (ig *IG) cacheVersion(key string, v *Version) error {
+	versions.mu.Lock()
+	defer versions.mu.Unlock()
+
+	if v != nil {
+		versions.m[key] = *v
+	} else {
+		delete(versions.m, key)
+	}
+	if ig.versionFile == "" {
+		return nil
+	}
+	m := loadVersionFile(ig.versionFile)
+	if v != nil {
+		m[key] = v.String()
+	} else {
+		delete(m, key)
+	}
+	return writeVersionFile(ig.versionFile, m)
+}
+
+func loadVersionFile(path string) map[string]string {
+	m := map[string]string{}
+	if b, err := os.ReadFile(path); err == nil {
+		// A corrupt file only costs a probe.
+		json.Unmarshal(b, &m)
+	}
+	return m
+}
+
+// writeVersionFile replaces the state file of WithVersionCache atomically.
+func writeVersionFile(path string, m map[string]string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".versions-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if err = errors.Join(err, tmp.Close()); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Refresh probes the version of the binary again, bypassing the version
+// caches, e.g. after replacing the binary in place with the same size and
+// modification time. It also clears ErrVersionChanged, carrying on with the
+// new version, for the IG and every IG derived from it with With.
+func (ig *IG) Refresh(ctx context.Context) error {
+	ig.bin.mu.Lock()
+	defer ig.bin.mu.Unlock()
+
+	info, err := os.Stat(ig.path)
+	if err != nil {
+		return fmt.Errorf("checking ig binary: %w", err)
+	}
+	if err := ig.cacheVersion(versionKey(ig.path, info), nil); err != nil {
+		return fmt.Errorf("clearing version cache: %w", err)
+	}
+	v, err := ig.probeVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("probing ig version: %w", err)
+	}
+	ig.bin.version = v
+	ig.bin.changed = nil
+	return nil
+}
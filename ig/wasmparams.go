@@ -0,0 +1,152 @@
+package ig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidWasmParams is returned, wrapped, for wasm params an image
+// doesn't declare or rejects.
+var ErrInvalidWasmParams = errors.New("invalid wasm params")
+
+// wasmParamPrefix is the prefix ig image inspect reports the params
+// declared by the wasm module of a gadget with.
+const wasmParamPrefix = "operator.oci.wasm."
+
+// WasmParam is a param declared by the wasm module of a gadget.
+type WasmParam struct {
+	Key         string
+	Description string
+	// TypeHint is the type of the values, e.g. "uint32" or "duration",
+	// string if empty.
+	TypeHint       string
+	DefaultValue   string
+	PossibleValues []string
+}
+
+// WasmParams configure the wasm module of a gadget at run time, instead of
+// raw flags:
+//
+//	args, err := i.WasmArgs(ctx, image, ig.WasmParams{
+//		Values: map[string]string{"threshold": "10"},
+//		Files:  map[string]string{"rules": "testdata/rules.json"},
+//	})
+//	...
+//	res, err := i.Run(ctx, image, args...)
+type WasmParams struct {
+	// Values maps param keys to their value.
+	Values map[string]string
+	// Files maps param keys to a file holding their value, e.g. rules or a
+	// policy. A final newline is dropped.
+	Files map[string]string
+}
+
+// WasmParams returns the params the wasm module of image declares, as
+// reported by ig image inspect, sorted by key.
+func (ig *IG) WasmParams(ctx context.Context, image string) ([]WasmParam, error) {
+	info, err := ig.inspect(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []WasmParam
+	for _, p := range info.Params {
+		if p.Prefix != wasmParamPrefix {
+			continue
+		}
+		params = append(params, WasmParam{
+			Key:            p.Key,
+			Description:    p.Description,
+			TypeHint:       p.TypeHint,
+			DefaultValue:   p.DefaultValue,
+			PossibleValues: p.PossibleValues,
+		})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Key < params[j].Key })
+	return params, nil
+}
+
+// WasmArgs validates p against the params the wasm module of image
+// declares and returns them as arguments of ig run.
+func (ig *IG) WasmArgs(ctx context.Context, image string, p WasmParams) ([]string, error) {
+	if len(p.Values) == 0 && len(p.Files) == 0 {
+		return nil, nil
+	}
+	declared, err := ig.WasmParams(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	args, err := p.Args(declared)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", image, err)
+	}
+	return args, nil
+}
+
+// Args validates p against declared params, their type and possible
+// values, and returns them as arguments of ig run, sorted by key.
+func (p WasmParams) Args(declared []WasmParam) ([]string, error) {
+	values := map[string]string{}
+	var errs []error
+	for key, v := range p.Values {
+		values[key] = v
+	}
+	files := make([]string, 0, len(p.Files))
+	for key := range p.Files {
+		files = append(files, key)
+	}
+	sort.Strings(files)
+	for _, key := range files {
+		path := p.Files[key]
+		if _, ok := values[key]; ok {
+			errs = append(errs, fmt.Errorf("param %s given both as value and file", key))
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("param %s: %w", key, err))
+			continue
+		}
+		values[key] = strings.TrimSuffix(string(b), "\n")
+	}
+
+	byKey := map[string]WasmParam{}
+	for _, d := range declared {
+		byKey[d.Key] = d
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		v := values[key]
+		d, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("param %s is not declared by the wasm module", key))
+			continue
+		}
+		if check, ok := typeHints[d.TypeHint]; ok {
+			if err := check(v); err != nil {
+				errs = append(errs, fmt.Errorf("param %s: %q is not a valid %s", key, v, d.TypeHint))
+				continue
+			}
+		}
+		if len(d.PossibleValues) > 0 && !slices.Contains(d.PossibleValues, v) {
+			errs = append(errs, fmt.Errorf("param %s: %q is not one of %s", key, v, strings.Join(d.PossibleValues, ", ")))
+			continue
+		}
+		args = append(args, "--"+key+"="+v)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidWasmParams, err)
+	}
+	return args, nil
+}
@@ -0,0 +1,44 @@
+package ig
+
+import (
+	"maps"
+	"slices"
+)
+
+// WithRunFlags passes flags to every ig run, before the flags of the call,
+// e.g. "--runtimes", "containerd" for a whole service.
+func WithRunFlags(flags ...string) Option {
+	return func(ig *IG) {
+		ig.runFlags = append(ig.runFlags, flags...)
+	}
+}
+
+// With returns a new IG configured as ig with opts applied on top, e.g. to
+// add per-request environment variables or run flags. The new IG shares the
+// binary and probed version of ig, so With doesn't run ig, as well as its
+// quotas, pull cache and history; ig itself is left unchanged, so With is
+// safe for concurrent use. WithPath is ignored: a different binary needs
+// New.
+func (ig *IG) With(opts ...Option) (*IG, error) {
+	d := *ig
+	d.env = slices.Clip(d.env)
+	d.runFlags = slices.Clip(d.runFlags)
+	d.credentials = slices.Clip(d.credentials)
+	d.runners = maps.Clone(d.runners)
+	for _, opt := range opts {
+		opt(&d)
+	}
+	d.path = ig.path
+	if err := d.resolveRunners(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// withRunFlags returns flags preceded by the flags of WithRunFlags.
+func (ig *IG) withRunFlags(flags []string) []string {
+	if len(ig.runFlags) == 0 {
+		return flags
+	}
+	return append(slices.Clip(ig.runFlags), flags...)
+}
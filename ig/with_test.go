@@ -0,0 +1,34 @@
+package ig
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithCredentialsIsolated(t *testing.T) {
+	// Spare capacity in the parent would let derived IGs share it.
+	base := scriptIG(t, "", WithCredentials(StaticCredentials{}, StaticCredentials{}))
+	base.credentials = base.credentials[:1]
+
+	a, err := base.With(WithCredentials(StaticCredentials{"a.example": {Username: "a"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := base.With(WithCredentials(StaticCredentials{"b.example": {Username: "b"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cred, err := a.credential(context.Background(), "a.example/gadget"); err != nil || cred == nil || cred.Username != "a" {
+		t.Errorf("credential of a: got %v, %v, want user a", cred, err)
+	}
+	if cred, err := a.credential(context.Background(), "b.example/gadget"); err != nil || cred != nil {
+		t.Errorf("a resolved the credentials of b: %v, %v", cred, err)
+	}
+	if len(base.credentials) != 1 {
+		t.Errorf("With changed the credentials of its parent: %d sources", len(base.credentials))
+	}
+	if cred, err := b.credential(context.Background(), "b.example/gadget"); err != nil || cred == nil || cred.Username != "b" {
+		t.Errorf("credential of b: got %v, %v, want user b", cred, err)
+	}
+}
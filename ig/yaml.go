@@ -0,0 +1,242 @@
+package ig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document without its indentation.
+type yamlLine struct {
+	indent int
+	text   string
+	num    int
+}
+
+// parseYAML parses the subset of YAML gadget metadata files use: block
+// mappings and sequences, plain, quoted and block scalars, and empty or
+// single-line flow collections. Scalars are left as strings.
+func parseYAML(b []byte) (any, error) {
+	var lines []yamlLine
+	for i, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimRight(stripYAMLComment(l), " \t\r")
+		text := strings.TrimLeft(l, " ")
+		if text == "" || text == "---" {
+			lines = append(lines, yamlLine{indent: -1, num: i + 1})
+			continue
+		}
+		lines = append(lines, yamlLine{indent: len(l) - len(text), text: text, num: i + 1})
+	}
+	p := &yamlParser{lines: lines}
+	p.skipBlank()
+	if p.i == len(p.lines) {
+		return map[string]any{}, nil
+	}
+	v, err := p.block(p.lines[p.i].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); p.i < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.i].num)
+	}
+	return v, nil
+}
+
+// stripYAMLComment removes a trailing comment, outside quotes.
+func stripYAMLComment(l string) string {
+	var quote rune
+	for i, r := range l {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || l[i-1] == ' ' || l[i-1] == '\t'):
+			return l[:i]
+		}
+	}
+	return l
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.i < len(p.lines) && p.lines[p.i].indent < 0 {
+		p.i++
+	}
+}
+
+// block parses the mapping or sequence whose lines are at indent.
+func (p *yamlParser) block(indent int) (any, error) {
+	if l := p.lines[p.i]; l.text == "-" || strings.HasPrefix(l.text, "- ") {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	seq := []any{}
+	for p.skipBlank(); p.i < len(p.lines); p.skipBlank() {
+		l := p.lines[p.i]
+		if l.indent != indent || (l.text != "-" && !strings.HasPrefix(l.text, "- ")) {
+			break
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+		if item == "" {
+			p.i++
+			v, err := p.nested(indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		if _, _, ok := cutYAMLKey(item); ok {
+			// "- key: value" starts a mapping indented past the dash.
+			p.lines[p.i] = yamlLine{indent: indent + len(l.text) - len(item), text: item, num: l.num}
+			v, err := p.mapping(p.lines[p.i].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		p.i++
+		v, err := p.scalar(item, indent)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", l.num, err)
+		}
+		seq = append(seq, v)
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	m := map[string]any{}
+	for p.skipBlank(); p.i < len(p.lines); p.skipBlank() {
+		l := p.lines[p.i]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		key, value, ok := cutYAMLKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key", l.num)
+		}
+		p.i++
+		var (
+			v   any
+			err error
+		)
+		if value == "" {
+			v, err = p.nested(indent)
+		} else {
+			v, err = p.scalar(value, indent)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", l.num, err)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// nested parses the value of a key or item without inline value: a block
+// more indented than indent, a sequence at indent, or nothing.
+func (p *yamlParser) nested(indent int) (any, error) {
+	p.skipBlank()
+	if p.i == len(p.lines) {
+		return nil, nil
+	}
+	l := p.lines[p.i]
+	isSeq := l.text == "-" || strings.HasPrefix(l.text, "- ")
+	if l.indent > indent || (l.indent == indent && isSeq) {
+		return p.block(l.indent)
+	}
+	return nil, nil
+}
+
+// cutYAMLKey splits "key: value", with a quoted or plain key.
+func cutYAMLKey(s string) (key, value string, ok bool) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", "", false
+		}
+		rest := s[end+2:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false
+		}
+		k, err := unquoteYAML(s[:end+2])
+		return k, strings.TrimSpace(rest[1:]), err == nil
+	}
+	if i := strings.Index(s, ": "); i > 0 {
+		return s[:i], strings.TrimSpace(s[i+2:]), true
+	}
+	if strings.HasSuffix(s, ":") && len(s) > 1 {
+		return s[:len(s)-1], "", true
+	}
+	return "", "", false
+}
+
+func (p *yamlParser) scalar(s string, indent int) (any, error) {
+	switch {
+	case s == "|" || s == ">" || strings.HasPrefix(s, "|-") || strings.HasPrefix(s, ">-"):
+		return p.blockScalar(s[0] == '>', strings.HasSuffix(s, "-"), indent), nil
+	case s == "[]":
+		return []any{}, nil
+	case s == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		seq := []any{}
+		for _, item := range strings.Split(s[1:len(s)-1], ",") {
+			v, err := unquoteYAML(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		}
+		return seq, nil
+	}
+	return unquoteYAML(s)
+}
+
+func (p *yamlParser) blockScalar(folded, strip bool, indent int) string {
+	var lines []string
+	for ; p.i < len(p.lines); p.i++ {
+		l := p.lines[p.i]
+		if l.indent >= 0 && l.indent <= indent {
+			break
+		}
+		lines = append(lines, l.text)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	sep := "\n"
+	if folded {
+		sep = " "
+	}
+	s := strings.Join(lines, sep)
+	if !strip && s != "" {
+		s += "\n"
+	}
+	return s
+}
+
+func unquoteYAML(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
@@ -0,0 +1,182 @@
+package ig
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAMLGadgetMetadata(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[string]any
+	}{
+		{
+			file: "testdata/trace_exec.yaml",
+			want: map[string]any{
+				"name":             "trace exec",
+				"description":      "trace process executions",
+				"homepageURL":      "https://inspektor-gadget.io/",
+				"documentationURL": "https://www.inspektor-gadget.io/docs/latest/gadgets/trace_exec",
+				"sourceURL":        "https://github.com/inspektor-gadget/inspektor-gadget/tree/main/gadgets/trace_exec",
+				"datasources": map[string]any{
+					"exec": map[string]any{
+						"fields": map[string]any{
+							"args": map[string]any{"annotations": map[string]any{
+								"columns.width": "40",
+								"description":   "Arguments passed to the new process",
+							}},
+							"error": map[string]any{"annotations": map[string]any{
+								"columns.hidden": "true",
+								"columns.width":  "5",
+								"description":    "Error returned by execve",
+							}},
+							"upper_layer": map[string]any{"annotations": map[string]any{
+								"columns.hidden": "true",
+								"columns.width":  "8",
+								"description":    "True if the executable is in the upper layer of an overlay filesystem",
+							}},
+						},
+					},
+				},
+				"ebpfParams": map[string]any{
+					"ignore_failed": map[string]any{"key": "ignore-failed", "defaultValue": "true", "description": "Ignore failed calls"},
+					"paths":         map[string]any{"key": "paths", "defaultValue": "false", "description": "Show the cwd and exepath of the process"},
+				},
+				"params": map[string]any{
+					"ebpf": map[string]any{
+						"ignore-failed": map[string]any{
+							"key":          "ignore-failed",
+							"defaultValue": "true",
+							"description":  "Ignore failed calls",
+							"typeHint":     "bool",
+						},
+					},
+				},
+			},
+		},
+		{
+			file: "testdata/trace_tcp.yaml",
+			want: map[string]any{
+				"name":        "trace tcp",
+				"description": "Trace TCP connect, accept and close.\nOne event per connection.\n",
+				"datasources": map[string]any{
+					"tcp": map[string]any{
+						"fields": map[string]any{
+							"src": map[string]any{"annotations": map[string]any{"template": "l4endpoint", "description": "Source endpoint"}},
+							"dst": map[string]any{"annotations": map[string]any{"template": "l4endpoint", "description": "Destination endpoint"}},
+							"type": map[string]any{"annotations": map[string]any{
+								"description":       "Type of the event: connect, accept or close",
+								"columns.alignment": "left",
+								"value.one-of":      []any{"connect", "accept", "close"},
+							}},
+							"error_raw": map[string]any{"annotations": map[string]any{"columns.hidden": "true", "description": ""}},
+						},
+					},
+				},
+				"params": map[string]any{
+					"ebpf": map[string]any{
+						"accept-only": map[string]any{
+							"key":          "accept-only",
+							"defaultValue": "false",
+							"typeHint":     "bool",
+							"description":  "Only show accept events",
+						},
+						"family": map[string]any{
+							"key":            "family",
+							"defaultValue":   "4",
+							"typeHint":       "uint8",
+							"possibleValues": []any{"4", "6"},
+							"description":    "Address family",
+						},
+					},
+				},
+				"tags": []any{"tcp", "network"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseYAML(b)
+			if err != nil {
+				t.Fatalf("parseYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want any
+	}{
+		{name: "empty", doc: "", want: map[string]any{}},
+		{name: "comments only", doc: "# a\n\n---\n", want: map[string]any{}},
+		{name: "empty value", doc: "a:\nb: 1", want: map[string]any{"a": nil, "b": "1"}},
+		{name: "quoted key", doc: `"a: b": 1` + "\n'c': 2", want: map[string]any{"a: b": "1", "c": "2"}},
+		{name: "quoted hash", doc: `a: "x # y" # z`, want: map[string]any{"a": "x # y"}},
+		{name: "hash in word", doc: "a: x#y", want: map[string]any{"a": "x#y"}},
+		{name: "escapes", doc: `a: "tab\there"` + "\nb: 'it''s'", want: map[string]any{"a": "tab\there", "b": "it's"}},
+		{name: "flow", doc: "a: []\nb: {}\nc: [x, 'y', \"z\"]", want: map[string]any{"a": []any{}, "b": map[string]any{}, "c": []any{"x", "y", "z"}}},
+		{name: "sequence", doc: "- a\n- b", want: []any{"a", "b"}},
+		{name: "sequence at key indent", doc: "a:\n- x\n- y\nb: z", want: map[string]any{"a": []any{"x", "y"}, "b": "z"}},
+		{name: "sequence of mappings", doc: "a:\n  - k: 1\n    v: 2\n  - k: 3", want: map[string]any{"a": []any{
+			map[string]any{"k": "1", "v": "2"},
+			map[string]any{"k": "3"},
+		}}},
+		{name: "nested sequences", doc: "-\n  - a\n- b", want: []any{[]any{"a"}, "b"}},
+		{name: "literal", doc: "a: |\n  x\n\n  y\nb: 1", want: map[string]any{"a": "x\n\ny\n", "b": "1"}},
+		{name: "literal stripped", doc: "a: |-\n  x\n  y\n", want: map[string]any{"a": "x\ny"}},
+		{name: "folded", doc: "a: >\n  x\n  y\n", want: map[string]any{"a": "x y\n"}},
+		{name: "empty literal", doc: "a: |\nb: 1", want: map[string]any{"a": "", "b": "1"}},
+		{name: "crlf", doc: "a: 1\r\nb:\r\n  c: 2\r\n", want: map[string]any{"a": "1", "b": map[string]any{"c": "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("parseYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{name: "over-indented key", doc: "a: 1\n  b: 2", err: "line 2: unexpected indentation"},
+		{name: "dedented past root", doc: "  a: 1\nb: 2", err: "line 2: unexpected indentation"},
+		{name: "not a key", doc: "a: 1\nb", err: "line 2: expected a key"},
+		{name: "item in mapping", doc: "a: 1\n- b", err: "line 2: expected a key"},
+		{name: "unterminated quoted key", doc: `"a: 1`, err: "line 1: expected a key"},
+		{name: "invalid escape", doc: `a: "\q"`, err: "line 1: invalid syntax"},
+		{name: "invalid escape in item", doc: "a:\n  - \"\\q\"", err: "line 2: invalid syntax"},
+		{name: "invalid escape in flow", doc: `a: ["\q"]`, err: "line 1: invalid syntax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(tt.doc))
+			if err == nil {
+				t.Fatal("parseYAML succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseYAML: %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
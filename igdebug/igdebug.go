@@ -0,0 +1,66 @@
+// Package igdebug serves optional debug endpoints for programs embedding the
+// library: pprof profiles and expvar counters, including the library
+// counters of ig.ReadStats, so operators can profile the overhead of the
+// wrapper in long-lived deployments.
+//
+// Importing the package registers the net/http/pprof handlers on
+// http.DefaultServeMux, as importing net/http/pprof does.
+package igdebug
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// StatsVar is the name of the expvar variable holding ig.ReadStats.
+const StatsVar = "ig"
+
+var publish sync.Once
+
+// Handler returns a handler serving pprof under /debug/pprof/ and expvar,
+// with the library counters as StatsVar, under /debug/vars.
+func Handler() http.Handler {
+	publish.Do(func() {
+		expvar.Publish(StatsVar, expvar.Func(func() any { return ig.ReadStats() }))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// ListenAndServe serves Handler on addr, e.g. "127.0.0.1:6060", until ctx is
+// done. Debug endpoints expose internals of the process: don't listen on
+// public interfaces.
+func ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: Handler(), ReadHeaderTimeout: 10 * time.Second}
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package capture provides bounded buffers for the output of child
+// processes.
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// Buffer keeps the first Limit bytes written to it and counts the rest. It
+// is safe for a writer and concurrent readers. The zero value is an
+// unbounded buffer.
+type Buffer struct {
+	// Limit is the maximum number of bytes kept, unbounded if <= 0. Set it
+	// before the first write.
+	Limit int
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	dropped int64
+}
+
+// New returns a buffer keeping at most limit bytes.
+func New(limit int) *Buffer {
+	return &Buffer{Limit: limit}
+}
+
+// Write never fails, so the writer isn't disturbed once the limit is
+// reached: the excess is discarded instead.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keep := p
+	if b.Limit > 0 {
+		room := b.Limit - b.buf.Len()
+		if room < 0 {
+			room = 0
+		}
+		if len(keep) > room {
+			keep = keep[:room]
+		}
+	}
+	b.buf.Write(keep)
+	b.dropped += int64(len(p) - len(keep))
+
+	return len(p), nil
+}
+
+// String returns the kept bytes, followed by a truncation marker if bytes
+// were discarded.
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dropped == 0 {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s\n[... truncated %d bytes]\n", b.buf.String(), b.dropped)
+}
+
+// Truncated returns how many bytes were discarded.
+func (b *Buffer) Truncated() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
+
+// Reset empties the buffer, keeping its limit.
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf.Reset()
+	b.dropped = 0
+}
@@ -0,0 +1,21 @@
+// Package stats holds the process-wide counters of the library, read
+// through ig.ReadStats.
+package stats
+
+import "sync/atomic"
+
+var (
+	// EventsDecoded counts the gadget output lines decoded as JSON events.
+	EventsDecoded atomic.Int64
+	// EventsDropped counts the events lost by the library: output past the
+	// maximum event size and events dropped by slow stream subscribers.
+	EventsDropped atomic.Int64
+	// BytesRead counts the bytes of gadget output read.
+	BytesRead atomic.Int64
+	// SinkBytes counts the bytes serialized by sinks.
+	SinkBytes atomic.Int64
+	// SessionsStarted counts the gadget sessions started.
+	SessionsStarted atomic.Int64
+	// ActiveSessions is the number of gadget sessions still running.
+	ActiveSessions atomic.Int64
+)
@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// StatusError is the error of an HTTP request answered with an unexpected
+// status, for Classify.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// RetryableStatus reports whether an HTTP request answered with code may
+// succeed if retried: timeouts, throttling and server errors.
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Classify is a Backoff.Retryable treating errors as transient unless known
+// otherwise: cancellations and StatusErrors of statuses RetryableStatus
+// rejects are permanent.
+func Classify(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		return RetryableStatus(serr.StatusCode)
+	}
+	return true
+}
+
+// Transient is a stricter Backoff.Retryable, only retrying errors known to
+// be transient: network timeouts, refused and reset connections, connections
+// cut short, and StatusErrors of statuses RetryableStatus accepts.
+func Transient(err error) bool {
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		return RetryableStatus(serr.StatusCode)
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF)
+}
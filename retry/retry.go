@@ -0,0 +1,112 @@
+// Package retry runs operations with exponential backoff, as the library
+// does for pushes and uploads: policies with jitter, and classification of
+// errors into the ones worth retrying and the permanent ones. Extensions of
+// the library, such as custom sinks, use it to behave the same way.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Backoff configures Do.
+type Backoff struct {
+	// Attempts is the maximum number of attempts, 1 if zero.
+	Attempts int
+	// Initial is the delay before the second attempt.
+	Initial time.Duration
+	// Max caps the delay between attempts, unbounded if zero.
+	Max time.Duration
+	// Multiplier scales the delay after each attempt, 2 if zero.
+	Multiplier float64
+	// Jitter is the fraction of each delay, from 0 to 1, cut at random so
+	// clients failing together don't retry together: with 0.5, delays are
+	// between half and all of their value.
+	Jitter float64
+	// Retryable, if not nil, classifies errors: those it rejects are not
+	// retried, as if Permanent. See Classify.
+	Retryable func(error) bool
+}
+
+// Delay returns the delay after the given failed attempt, starting at 1,
+// with jitter.
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.delay(attempt)
+	if b.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * min(b.Jitter, 1) * float64(d))
+	}
+	return d
+}
+
+func (b Backoff) delay(attempt int) time.Duration {
+	mult := b.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+	d := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		d *= mult
+		if b.Max > 0 && d >= float64(b.Max) {
+			return b.Max
+		}
+	}
+	return time.Duration(d)
+}
+
+type permanent struct{ err error }
+
+func (p *permanent) Error() string { return p.err.Error() }
+func (p *permanent) Unwrap() error { return p.err }
+
+// Permanent marks err as not worth retrying: Do returns it right away,
+// unwrapped.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanent{err: err}
+}
+
+// Do calls fn until it succeeds, returns a Permanent error, the attempts are
+// exhausted or ctx is done, sleeping according to b in between. onRetry, if
+// not nil, is called before each sleep with the failed attempt, its error and
+// the upcoming delay. The last error is returned.
+func Do(ctx context.Context, b Backoff, fn func(attempt int) error, onRetry func(attempt int, err error, delay time.Duration)) error {
+	attempts := b.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(attempt)
+		if err == nil {
+			return nil
+		}
+		var p *permanent
+		if errors.As(err, &p) {
+			return p.err
+		}
+		if b.Retryable != nil && !b.Retryable(err) {
+			return err
+		}
+		if attempt == attempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := b.Delay(attempt)
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package scaffold generates the skeleton of a new gadget project: an eBPF
+// program stub, its metadata and build files, and a Go test driving the
+// gadget through this module, ready to build with ig image build and to
+// grow into a real gadget.
+package scaffold
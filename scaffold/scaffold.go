@@ -0,0 +1,130 @@
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateSuffix ends the names of template files, removed from the names
+// of the generated files.
+const templateSuffix = ".tmpl"
+
+//go:embed templates
+var embedded embed.FS
+
+// Templates are the default templates of Generate.
+var Templates fs.FS = mustSub(embedded, "templates")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Options configures Generate. They are also the data of the templates.
+type Options struct {
+	// Name of the gadget, lowercase letters, digits and underscores, e.g.
+	// "trace_foo".
+	Name string
+	// Description of the gadget, derived from Name if empty.
+	Description string
+	// DataSource is the name of the data source of the gadget, "events" if
+	// empty.
+	DataSource string
+	// Image is the image the test builds and runs, "localhost/<Name>:latest"
+	// if empty.
+	Image string
+	// Package is the Go package of the test, Name if empty.
+	Package string
+	// Templates replace the default ones: every file of the tree is
+	// rendered with text/template, and those ending with ".tmpl" lose that
+	// suffix.
+	Templates fs.FS
+	// Force overwrites existing files.
+	Force bool
+}
+
+var nameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Generate renders the templates into dir, creating it if needed, and
+// returns the paths of the generated files. It refuses to overwrite files
+// unless Force is set.
+func Generate(dir string, opts Options) ([]string, error) {
+	if !nameRe.MatchString(opts.Name) {
+		return nil, fmt.Errorf("invalid gadget name %q: use lowercase letters, digits and underscores", opts.Name)
+	}
+	if opts.Description == "" {
+		opts.Description = "Gadget " + strings.ReplaceAll(opts.Name, "_", " ")
+	}
+	if opts.DataSource == "" {
+		opts.DataSource = "events"
+	}
+	if opts.Image == "" {
+		opts.Image = "localhost/" + opts.Name + ":latest"
+	}
+	if opts.Package == "" {
+		opts.Package = opts.Name
+	}
+	templates := opts.Templates
+	if templates == nil {
+		templates = Templates
+	}
+
+	files := map[string][]byte{}
+	err := fs.WalkDir(templates, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(templates, path)
+		if err != nil {
+			return err
+		}
+		t, err := template.New(path).Option("missingkey=error").Parse(string(b))
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := t.Execute(&out, opts); err != nil {
+			return err
+		}
+		files[strings.TrimSuffix(path, templateSuffix)] = out.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rendering templates: %w", err)
+	}
+
+	var paths []string
+	for name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(path); err == nil && !opts.Force {
+			return nil, fmt.Errorf("%s already exists", path)
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		name, _ := filepath.Rel(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, files[filepath.ToSlash(name)], 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
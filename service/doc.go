@@ -0,0 +1,4 @@
+// Package service installs gadgets as systemd services, turning a run spec
+// into a persistent node agent that survives reboots and restarts on
+// failure.
+package service
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// UnitDir is where Install writes unit files.
+var UnitDir = "/etc/systemd/system"
+
+// Restart policies, as understood by systemd's Restart=.
+const (
+	RestartNo        = "no"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// Spec describes a gadget service.
+type Spec struct {
+	// Name of the unit, without the ".service" suffix.
+	Name string
+	// Description of the unit, derived from the gadgets if empty.
+	Description string
+	// IGPath is the absolute path of the ig binary.
+	IGPath string
+	// Gadgets run together for the lifetime of the service, with JSON
+	// output.
+	Gadgets []ig.GadgetSpec
+	// Restart is the restart policy, RestartOnFailure if empty.
+	Restart string
+	// RestartDelay is how long systemd waits before restarting, 5s if zero.
+	RestartDelay time.Duration
+	// Output is the file the JSON events are appended to. If empty, they go
+	// to the journal.
+	Output string
+	// Env are "KEY=value" variables set for ig.
+	Env []string
+}
+
+// Unit renders the systemd unit of spec.
+func Unit(spec Spec) (string, error) {
+	if spec.Name == "" {
+		return "", errors.New("service name is required")
+	}
+	if !filepath.IsAbs(spec.IGPath) {
+		return "", fmt.Errorf("ig path %q must be absolute", spec.IGPath)
+	}
+	if len(spec.Gadgets) == 0 {
+		return "", errors.New("at least one gadget is required")
+	}
+	restart := spec.Restart
+	if restart == "" {
+		restart = RestartOnFailure
+	}
+	delay := spec.RestartDelay
+	if delay == 0 {
+		delay = 5 * time.Second
+	}
+	desc := spec.Description
+	if desc == "" {
+		names := make([]string, len(spec.Gadgets))
+		for i, g := range spec.Gadgets {
+			names[i] = ig.GadgetName(g.Image)
+		}
+		desc = "Inspektor Gadget " + strings.Join(names, ", ")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=%s\nAfter=network-online.target\nWants=network-online.target\n\n", desc)
+	fmt.Fprintf(&b, "[Service]\nType=simple\nExecStart=%s\n", execStart(spec))
+	fmt.Fprintf(&b, "Restart=%s\nRestartSec=%d\n", restart, int(delay.Seconds()))
+	// ig flushes and exits on SIGINT, as on Ctrl-C.
+	b.WriteString("KillMode=control-group\nKillSignal=SIGINT\nTimeoutStopSec=30\n")
+	for _, e := range spec.Env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(strings.ReplaceAll(e, "%", "%%")))
+	}
+	if spec.Output != "" {
+		fmt.Fprintf(&b, "StandardOutput=append:%s\n", spec.Output)
+	}
+	b.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+
+	return b.String(), nil
+}
+
+func runArgs(spec Spec, g ig.GadgetSpec) []string {
+	return append([]string{spec.IGPath, "run", g.Image, "-o", "json"}, g.Flags...)
+}
+
+// execStart runs a single gadget directly, and several through a shell
+// waiting for all of them. systemd sends the stop signal to every process of
+// the unit, so each ig gets it.
+func execStart(spec Spec) string {
+	var line string
+	if len(spec.Gadgets) == 1 {
+		args := runArgs(spec, spec.Gadgets[0])
+		for i, a := range args {
+			args[i] = systemdQuote(a)
+		}
+		line = strings.Join(args, " ")
+	} else {
+		var script strings.Builder
+		for _, g := range spec.Gadgets {
+			args := runArgs(spec, g)
+			for i, a := range args {
+				args[i] = shellQuote(a)
+			}
+			script.WriteString(strings.Join(args, " ") + " & ")
+		}
+		script.WriteString("wait")
+		line = "/bin/sh -c " + systemdQuote(script.String())
+	}
+
+	// systemd expands $ and % in ExecStart unless doubled.
+	return strings.NewReplacer("$", "$$", "%", "%%").Replace(line)
+}
+
+// systemdQuote quotes s as a single word of a unit setting.
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func unitPath(name string) string {
+	return filepath.Join(UnitDir, name+".service")
+}
+
+// Install writes the unit of spec, then enables and starts it.
+func Install(ctx context.Context, spec Spec) error {
+	unit, err := Unit(spec)
+	if err != nil {
+		return fmt.Errorf("installing service: %w", err)
+	}
+	if err := os.WriteFile(unitPath(spec.Name), []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("installing service %s: %w", spec.Name, err)
+	}
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		return fmt.Errorf("installing service %s: %w", spec.Name, err)
+	}
+	if _, err := systemctl(ctx, "enable", "--now", spec.Name+".service"); err != nil {
+		return fmt.Errorf("installing service %s: %w", spec.Name, err)
+	}
+	return nil
+}
+
+// Uninstall stops and disables the service and removes its unit. Removing
+// a service that isn't installed is not an error.
+func Uninstall(ctx context.Context, name string) error {
+	path := unitPath(name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if _, err := systemctl(ctx, "disable", "--now", name+".service"); err != nil {
+		return fmt.Errorf("uninstalling service %s: %w", name, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("uninstalling service %s: %w", name, err)
+	}
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		return fmt.Errorf("uninstalling service %s: %w", name, err)
+	}
+	return nil
+}
+
+// Status is the state of a service, as reported by systemd.
+type Status struct {
+	// Installed is false if the unit file doesn't exist.
+	Installed bool
+	// ActiveState is e.g. "active", "failed" or "activating".
+	ActiveState string
+	// SubState is e.g. "running", "dead" or "auto-restart".
+	SubState string
+	// MainPID is the pid of the service, 0 if it isn't running.
+	MainPID int
+	// Restarts is how many times systemd restarted the service.
+	Restarts int
+}
+
+// Running reports whether the service is up.
+func (s Status) Running() bool {
+	return s.ActiveState == "active" && s.SubState == "running"
+}
+
+// GetStatus returns the status of the service.
+func GetStatus(ctx context.Context, name string) (Status, error) {
+	if _, err := os.Stat(unitPath(name)); errors.Is(err, os.ErrNotExist) {
+		return Status{}, nil
+	}
+
+	out, err := systemctl(ctx, "show", name+".service", "--property=ActiveState,SubState,MainPID,NRestarts")
+	if err != nil {
+		return Status{}, fmt.Errorf("getting status of service %s: %w", name, err)
+	}
+
+	st := Status{Installed: true}
+	for _, line := range strings.Split(out, "\n") {
+		k, v, _ := strings.Cut(line, "=")
+		switch k {
+		case "ActiveState":
+			st.ActiveState = v
+		case "SubState":
+			st.SubState = v
+		case "MainPID":
+			st.MainPID, _ = strconv.Atoi(v)
+		case "NRestarts":
+			st.Restarts, _ = strconv.Atoi(v)
+		}
+	}
+	return st, nil
+}
+
+func systemctl(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("systemctl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
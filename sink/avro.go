@@ -0,0 +1,177 @@
+package sink
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// avroMagic starts Avro object container files.
+var avroMagic = []byte("Obj\x01")
+
+// AvroSchema returns the Avro schema of the events of schema: a record per
+// object, every field nullable since events may lack any of them.
+func AvroSchema(schema ig.Schema) ([]byte, error) {
+	return json.Marshal(avroRecord(identifier(schema.Gadget+"_"+schema.DataSource), schema.Tree()))
+}
+
+func avroRecord(name string, nodes []*ig.SchemaNode) map[string]any {
+	fields := make([]map[string]any, 0, len(nodes))
+	for _, n := range nodes {
+		var typ any
+		if n.Field == nil {
+			typ = avroRecord(name+"_"+identifier(n.Name), n.Children)
+		} else {
+			typ = avroTypes[n.Field.Type]
+		}
+		f := map[string]any{
+			"name":    identifier(n.Name),
+			"type":    []any{"null", typ},
+			"default": nil,
+		}
+		if n.Field != nil && n.Field.Description != "" {
+			f["doc"] = n.Field.Description
+		}
+		fields = append(fields, f)
+	}
+	return map[string]any{"type": "record", "name": name, "fields": fields}
+}
+
+// avroTypes maps JSON types to Avro types. Schemas don't type array
+// elements; they are written as strings.
+var avroTypes = map[string]any{
+	ig.TypeString:  "string",
+	ig.TypeInteger: "long",
+	ig.TypeNumber:  "double",
+	ig.TypeBoolean: "boolean",
+	ig.TypeArray:   map[string]any{"type": "array", "items": "string"},
+}
+
+// Avro returns a serializer writing events as an Avro object container
+// file with the schema of AvroSchema, one block per event so files stay
+// readable up to the last event if ig or the program dies.
+func Avro(schema ig.Schema) Serializer {
+	return &avroSerializer{schema: schema, tree: schema.Tree()}
+}
+
+type avroSerializer struct {
+	schema ig.Schema
+	tree   []*ig.SchemaNode
+	sync   [16]byte
+	buf    []byte
+}
+
+func (a *avroSerializer) Begin(w io.Writer) error {
+	s, err := AvroSchema(a.schema)
+	if err != nil {
+		return err
+	}
+	if _, err := rand.Read(a.sync[:]); err != nil {
+		return err
+	}
+
+	b := append([]byte(nil), avroMagic...)
+	b = avroLong(b, 2)
+	b = avroBytes(b, []byte("avro.schema"))
+	b = avroBytes(b, s)
+	b = avroBytes(b, []byte("avro.codec"))
+	b = avroBytes(b, []byte("null"))
+	b = avroLong(b, 0)
+	b = append(b, a.sync[:]...)
+	_, err = w.Write(b)
+	return err
+}
+
+func (a *avroSerializer) Encode(w io.Writer, e ig.Event) error {
+	record, err := avroRecordValue(nil, a.tree, e.Fields)
+	if err != nil {
+		return err
+	}
+	a.buf = avroLong(a.buf[:0], 1)
+	a.buf = avroLong(a.buf, int64(len(record)))
+	a.buf = append(a.buf, record...)
+	a.buf = append(a.buf, a.sync[:]...)
+	_, err = w.Write(a.buf)
+	return err
+}
+
+// avroRecordValue encodes fields as a record of nodes. Every field is a
+// union of null, branch 0, and its type, branch 1.
+func avroRecordValue(b []byte, nodes []*ig.SchemaNode, fields map[string]any) ([]byte, error) {
+	for _, n := range nodes {
+		v, ok := fields[n.Name]
+		if !ok || v == nil {
+			b = avroLong(b, 0)
+			continue
+		}
+
+		if n.Field == nil {
+			m, ok := v.(map[string]any)
+			if !ok {
+				b = avroLong(b, 0)
+				continue
+			}
+			b = avroLong(b, 1)
+			var err error
+			if b, err = avroRecordValue(b, n.Children, m); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		b = avroLong(b, 1)
+		switch n.Field.Type {
+		case ig.TypeInteger:
+			i, ok := toInt64(v)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			b = avroLong(b, i)
+		case ig.TypeNumber:
+			f, ok := toFloat64(v)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+		case ig.TypeBoolean:
+			t, ok := v.(bool)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			if t {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		case ig.TypeArray:
+			items, ok := v.([]any)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			if len(items) > 0 {
+				b = avroLong(b, int64(len(items)))
+				for _, item := range items {
+					b = avroBytes(b, []byte(toString(item)))
+				}
+			}
+			b = avroLong(b, 0)
+		default:
+			b = avroBytes(b, []byte(toString(v)))
+		}
+	}
+	return b, nil
+}
+
+// avroLong appends v zigzag varint-encoded, as Avro ints and longs are.
+func avroLong(b []byte, v int64) []byte {
+	return binary.AppendVarint(b, v)
+}
+
+func avroBytes(b []byte, v []byte) []byte {
+	b = avroLong(b, int64(len(v)))
+	return append(b, v...)
+}
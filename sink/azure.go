@@ -0,0 +1,39 @@
+package sink
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AzureBlob is an ObjectStore of an Azure Blob Storage container,
+// authorized with a shared access signature.
+type AzureBlob struct {
+	// ContainerURL is the URL of the container, e.g.
+	// "https://account.blob.core.windows.net/captures".
+	ContainerURL string
+	// SAS is the shared access signature, with write permission, without
+	// the leading "?".
+	SAS string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Put uploads the object as a block blob in a single request, so objects
+// are limited to 5000MiB. Metadata names are turned into C# identifiers, as
+// Azure requires, with underscores.
+func (a *AzureBlob) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, metadata map[string]string) error {
+	u := strings.TrimSuffix(a.ContainerURL, "/") + "/" + s3Escape(key) + "?" + strings.TrimPrefix(a.SAS, "?")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", "2021-08-06")
+	for k, v := range metadata {
+		req.Header.Set("x-ms-meta-"+identifier(k), v)
+	}
+	return doPut(a.Client, req)
+}
@@ -0,0 +1,12 @@
+// Package sink writes the event streams of running gadgets to files and
+// other destinations, each sink with its own Serializer: JSON lines, Avro or
+// length-delimited protobuf, the binary formats following the schema of the
+// gadget as exported by ig.Schema.
+//
+// A Rotating sink splits long captures into files by size or age, and an
+// Uploader pushes the completed ones to S3, GCS or Azure Blob Storage.
+// WithMetadata tags every event with the run that produced it, so stored
+// captures can be partitioned and filtered without external bookkeeping.
+// Sinks count the bytes they write; Limit caps them with a Budget, per sink
+// or per run, stopping, dropping or rotating past it.
+package sink
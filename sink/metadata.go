@@ -0,0 +1,126 @@
+package sink
+
+import (
+	"os"
+	"sort"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// MetadataField is the field of events holding the metadata attached by
+// WithMetadata.
+const MetadataField = "run"
+
+// Metadata describes the run of a gadget that produced a capture.
+type Metadata struct {
+	RunID string
+	Image string
+	// Digest is the digest of Image, such as "sha256:...", if known.
+	Digest    string
+	IGVersion string
+	Host      string
+	Labels    map[string]string
+}
+
+// NewMetadata returns the metadata of a new run of image by i, with a new
+// run ID. Set RunID to the one of the session, or pass the ID to the session
+// with ig.WithRunID, to correlate captures with errors and audit records.
+func NewMetadata(i *ig.IG, image string, labels map[string]string) Metadata {
+	host, _ := os.Hostname()
+	digest, _ := i.ImageDigest(image)
+	return Metadata{
+		RunID:     ig.NewRunID(),
+		Image:     image,
+		Digest:    digest,
+		IGVersion: i.Version().String(),
+		Host:      host,
+		Labels:    labels,
+	}
+}
+
+// fields returns the metadata as the value of MetadataField. Empty fields are
+// left out.
+func (m Metadata) fields() map[string]any {
+	f := map[string]any{}
+	for _, kv := range m.pairs() {
+		f[kv[0]] = kv[1]
+	}
+	if len(m.Labels) > 0 {
+		labels := make(map[string]any, len(m.Labels))
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		f["labels"] = labels
+	}
+	return f
+}
+
+func (m Metadata) pairs() [][2]string {
+	var pairs [][2]string
+	for _, kv := range [][2]string{
+		{"id", m.RunID},
+		{"image", m.Image},
+		{"digest", m.Digest},
+		{"igVersion", m.IGVersion},
+		{"host", m.Host},
+	} {
+		if kv[1] != "" {
+			pairs = append(pairs, kv)
+		}
+	}
+	return pairs
+}
+
+// ObjectMetadata returns the metadata as object metadata for an Uploader,
+// labels prefixed with "label_", so stored captures can be filtered without
+// being downloaded.
+func (m Metadata) ObjectMetadata() map[string]string {
+	md := map[string]string{}
+	for _, kv := range m.pairs() {
+		md["run_"+identifier(kv[0])] = kv[1]
+	}
+	for k, v := range m.Labels {
+		md["label_"+identifier(k)] = v
+	}
+	return md
+}
+
+// Schema returns s with the fields of the metadata, so serializers following
+// a schema, such as Avro and Protobuf, encode them too.
+func (m Metadata) Schema(s ig.Schema) ig.Schema {
+	fields := append([]ig.FieldSchema(nil), s.Fields...)
+	for _, kv := range m.pairs() {
+		fields = append(fields, ig.FieldSchema{Path: MetadataField + "." + kv[0], Type: ig.TypeString})
+	}
+	for k := range m.Labels {
+		fields = append(fields, ig.FieldSchema{Path: MetadataField + ".labels." + k, Type: ig.TypeString})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	s.Fields = fields
+	return s
+}
+
+// WithMetadata returns a sink writing every event to s with the metadata m
+// in its MetadataField, replacing any field of that name. The events of the
+// caller are left untouched.
+func WithMetadata(s Sink, m Metadata) Sink {
+	return &metadataSink{Sink: s, fields: m.fields()}
+}
+
+type metadataSink struct {
+	Sink
+	fields map[string]any
+}
+
+func (s *metadataSink) Write(e ig.Event) error {
+	if e.Fields == nil {
+		return s.Sink.Write(e)
+	}
+	fields := make(map[string]any, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	fields[MetadataField] = s.fields
+	e.Fields = fields
+	return s.Sink.Write(e)
+}
@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireLen    = 2
+)
+
+// Protobuf returns a serializer writing events as the messages of
+// schema.Proto, each prefixed with its varint-encoded length as done by the
+// delimited I/O of protobuf libraries. Fields missing from the schema are
+// dropped.
+func Protobuf(schema ig.Schema) Serializer {
+	return &protoSerializer{tree: schema.Tree()}
+}
+
+type protoSerializer struct {
+	tree []*ig.SchemaNode
+	buf  []byte
+}
+
+func (*protoSerializer) Begin(io.Writer) error {
+	return nil
+}
+
+func (p *protoSerializer) Encode(w io.Writer, e ig.Event) error {
+	msg, err := protoMessage(nil, p.tree, e.Fields)
+	if err != nil {
+		return err
+	}
+	p.buf = binary.AppendUvarint(p.buf[:0], uint64(len(msg)))
+	p.buf = append(p.buf, msg...)
+	_, err = w.Write(p.buf)
+	return err
+}
+
+func protoMessage(b []byte, nodes []*ig.SchemaNode, fields map[string]any) ([]byte, error) {
+	for _, n := range nodes {
+		v, ok := fields[n.Name]
+		if !ok || v == nil {
+			continue
+		}
+
+		if n.Field == nil {
+			m, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			sub, err := protoMessage(nil, n.Children, m)
+			if err != nil {
+				return nil, err
+			}
+			b = protoBytes(b, n.Number, sub)
+			continue
+		}
+
+		switch n.Field.Type {
+		case ig.TypeInteger:
+			i, ok := toInt64(v)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			b = protoTag(b, n.Number, wireVarint)
+			b = binary.AppendUvarint(b, uint64(i))
+		case ig.TypeNumber:
+			f, ok := toFloat64(v)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			b = protoTag(b, n.Number, wireI64)
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+		case ig.TypeBoolean:
+			t, ok := v.(bool)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			b = protoTag(b, n.Number, wireVarint)
+			if t {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		case ig.TypeArray:
+			items, ok := v.([]any)
+			if !ok {
+				return nil, fieldError(n, v)
+			}
+			for _, item := range items {
+				b = protoBytes(b, n.Number, []byte(toString(item)))
+			}
+		default:
+			b = protoBytes(b, n.Number, []byte(toString(v)))
+		}
+	}
+	return b, nil
+}
+
+func protoTag(b []byte, number, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(number)<<3|uint64(wireType))
+}
+
+func protoBytes(b []byte, number int, v []byte) []byte {
+	b = protoTag(b, number, wireLen)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,160 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// ErrQuotaExceeded is returned by the writes of a Limited sink with
+// OverflowStop past its quota.
+var ErrQuotaExceeded = errors.New("sink quota exceeded")
+
+// OverflowPolicy decides what a Limited sink does with events past its
+// quota.
+type OverflowPolicy int
+
+const (
+	// OverflowStop fails writes with ErrQuotaExceeded, and calls
+	// Budget.OnExceeded, e.g. to stop the run.
+	OverflowStop OverflowPolicy = iota
+	// OverflowDrop drops the events, counting them.
+	OverflowDrop
+	// OverflowRotate completes the current file of a Rotating sink and
+	// removes its oldest files, uploaded or not, to make room: the capture
+	// keeps its most recent events.
+	OverflowRotate
+)
+
+// Counter is implemented by the sinks counting the bytes they serialized,
+// Writer and Rotating.
+type Counter interface {
+	Bytes() int64
+}
+
+// Budget is a byte quota shared by the sinks limited by it: one Budget per
+// sink gives per-sink quotas, one per run caps the sinks of a run together.
+// A write is only refused once the quota is reached, so the sinks may
+// exceed it by one event.
+type Budget struct {
+	// MaxBytes is the quota. Unbounded if zero.
+	MaxBytes int64
+	Policy   OverflowPolicy
+	// OnExceeded, if not nil, is called once, the first time the quota is
+	// reached. It runs in its own goroutine, so it may use the Budget and
+	// stop the session whose events the sinks write, which waits for the
+	// writer to drain them.
+	OnExceeded func()
+
+	mu       sync.Mutex
+	used     int64
+	dropped  int64
+	exceeded bool
+}
+
+// Used returns how many bytes the sinks of b use: the bytes they
+// serialized, minus those removed by OverflowRotate.
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
+
+// Dropped returns how many events the sinks of b refused or dropped.
+func (b *Budget) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
+
+// Exceeded reports whether the quota was reached.
+func (b *Budget) Exceeded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.exceeded
+}
+
+// Limited is a Sink enforcing a Budget on another.
+type Limited struct {
+	s      Sink
+	c      Counter
+	budget *Budget
+}
+
+// Limit returns s limited by b. s must be a Counter, and a *Rotating for
+// OverflowRotate: wrap the Limited sink with WithMetadata, not the reverse.
+func Limit(s Sink, b *Budget) (*Limited, error) {
+	c, ok := s.(Counter)
+	if !ok {
+		return nil, fmt.Errorf("sink %T doesn't count bytes", s)
+	}
+	if _, ok := s.(*Rotating); b.Policy == OverflowRotate && !ok {
+		return nil, fmt.Errorf("rotating on overflow needs a rotating sink, not %T", s)
+	}
+	return &Limited{s: s, c: c, budget: b}, nil
+}
+
+// Write writes e, applying the overflow policy if the quota is reached.
+func (l *Limited) Write(e ig.Event) error {
+	if e.Fields == nil {
+		return nil
+	}
+
+	exceeded, err := l.write(e)
+	if exceeded && l.budget.OnExceeded != nil {
+		go l.budget.OnExceeded()
+	}
+	return err
+}
+
+// write writes e under the lock of the budget, reporting whether it was the
+// first write past the quota.
+func (l *Limited) write(e ig.Event) (exceeded bool, err error) {
+	b := l.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.MaxBytes > 0 && b.used >= b.MaxBytes {
+		exceeded = !b.exceeded
+		b.exceeded = true
+		switch b.Policy {
+		case OverflowStop:
+			b.dropped++
+			return exceeded, ErrQuotaExceeded
+		case OverflowDrop:
+			b.dropped++
+			return exceeded, nil
+		case OverflowRotate:
+			freed, err := l.s.(*Rotating).reclaim(b.used - b.MaxBytes + 1)
+			b.used -= freed
+			if err != nil {
+				return exceeded, fmt.Errorf("reclaiming capture files: %w", err)
+			}
+			if b.used >= b.MaxBytes {
+				// Other sinks of the budget hold the rest.
+				b.dropped++
+				return exceeded, nil
+			}
+		}
+	}
+
+	before := l.c.Bytes()
+	err = l.s.Write(e)
+	b.used += l.c.Bytes() - before
+	return exceeded, err
+}
+
+// Bytes returns how many bytes the underlying sink serialized.
+func (l *Limited) Bytes() int64 {
+	return l.c.Bytes()
+}
+
+// Close closes the underlying sink.
+func (l *Limited) Close() error {
+	return l.s.Close()
+}
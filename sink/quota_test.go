@@ -0,0 +1,118 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func limited(t *testing.T, b *Budget) *Limited {
+	t.Helper()
+	w, err := New(nopCloser{io.Discard}, JSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := Limit(w, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func event() ig.Event {
+	return ig.Event{Fields: map[string]any{"comm": "cat", "pid": float64(42)}}
+}
+
+func TestLimitedOnExceededUsesBudget(t *testing.T) {
+	type state struct {
+		used     int64
+		exceeded bool
+	}
+	called := make(chan state, 1)
+	b := &Budget{MaxBytes: 1, Policy: OverflowStop}
+	b.OnExceeded = func() {
+		called <- state{used: b.Used(), exceeded: b.Exceeded()}
+	}
+	l := limited(t, b)
+
+	if err := l.Write(event()); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := l.Write(event()); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("write past quota: got %v, want ErrQuotaExceeded", err)
+		}
+	}
+
+	select {
+	case got := <-called:
+		if !got.exceeded || got.used != l.Bytes() {
+			t.Errorf("OnExceeded saw used %d, exceeded %t; want %d, true", got.used, got.exceeded, l.Bytes())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnExceeded not called")
+	}
+	select {
+	case <-called:
+		t.Error("OnExceeded called more than once")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := b.Dropped(); got != 3 {
+		t.Errorf("Dropped() = %d, want 3", got)
+	}
+}
+
+// fakeIG writes an ig binary printing JSON events until interrupted.
+func fakeIG(t *testing.T) *ig.IG {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ig")
+	script := `#!/bin/sh
+if [ "$1" = version ]; then echo v0.30.0; exit 0; fi
+while :; do echo '{"comm":"cat","pid":42}'; done
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	i, err := ig.New(ig.WithPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestLimitedOnExceededStopsSession(t *testing.T) {
+	s, err := fakeIG(t).Start(context.Background(), "trace_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan error, 1)
+	l := limited(t, &Budget{MaxBytes: 4 << 10, Policy: OverflowStop, OnExceeded: func() {
+		stopped <- s.Stop()
+	}})
+
+	drained := make(chan error, 1)
+	go func() { drained <- Drain(l, s.Events()) }()
+
+	select {
+	case err := <-drained:
+		if !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Drain: got %v, want ErrQuotaExceeded", err)
+		}
+	case <-time.After(10 * time.Second):
+		s.Signal(os.Kill)
+		t.Fatal("session not stopped by OnExceeded")
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+}
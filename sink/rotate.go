@@ -0,0 +1,189 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// RotateOptions configures a Rotating sink.
+type RotateOptions struct {
+	// Dir holds the capture files.
+	Dir string
+	// Name prefixes file names, followed by the creation time and Ext, e.g.
+	// "trace_exec-20240102T150405.000000000.avro".
+	Name string
+	// Ext is the extension of files, ".json" if empty.
+	Ext string
+	// Serializer returns the serializer of a new file. Defaults to JSON.
+	Serializer func() Serializer
+	// MaxBytes rotates files once they reach this size. Unbounded if zero.
+	MaxBytes int64
+	// MaxAge rotates files written to for longer than this. Unbounded if
+	// zero. It is checked when events are written.
+	MaxAge time.Duration
+	// Rotated, if not nil, is called with the path of every completed file,
+	// before the next one is created, e.g. Uploader.Enqueue.
+	Rotated func(path string)
+}
+
+// Rotating is a Sink splitting events into capture files by size or age.
+type Rotating struct {
+	opts RotateOptions
+
+	mu      sync.Mutex
+	cur     *Writer
+	path    string
+	size    *countingFile
+	created time.Time
+	// written counts the bytes of the completed files; files are the
+	// completed files, oldest first.
+	written int64
+	files   []rotatedFile
+}
+
+type rotatedFile struct {
+	path string
+	size int64
+}
+
+// NewRotating returns a rotating sink. Files are only created once events
+// are written.
+func NewRotating(opts RotateOptions) (*Rotating, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if opts.Ext == "" {
+		opts.Ext = ".json"
+	}
+	if opts.Serializer == nil {
+		opts.Serializer = JSON
+	}
+	return &Rotating{opts: opts}, nil
+}
+
+// Write writes e to the current file, rotating it if it's full.
+func (r *Rotating) Write(e ig.Event) error {
+	if e.Fields == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cur == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	if err := r.cur.Write(e); err != nil {
+		return err
+	}
+
+	full := r.opts.MaxBytes > 0 && r.size.n+int64(r.cur.buf.Buffered()) >= r.opts.MaxBytes
+	old := r.opts.MaxAge > 0 && time.Since(r.created) >= r.opts.MaxAge
+	if full || old {
+		return r.rotate()
+	}
+	return nil
+}
+
+// Close completes the current file, if any.
+func (r *Rotating) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cur == nil {
+		return nil
+	}
+	return r.rotate()
+}
+
+func (r *Rotating) open() error {
+	r.created = time.Now()
+	name := fmt.Sprintf("%s-%s%s", r.opts.Name, r.created.UTC().Format("20060102T150405.000000000"), r.opts.Ext)
+	r.path = filepath.Join(r.opts.Dir, name)
+
+	f, err := os.Create(r.path)
+	if err != nil {
+		return err
+	}
+	r.size = &countingFile{File: f}
+	r.cur, err = New(r.size, r.opts.Serializer())
+	if err != nil {
+		f.Close()
+		return errors.Join(err, os.Remove(r.path))
+	}
+	return nil
+}
+
+// Bytes returns how many bytes r serialized, across files.
+func (r *Rotating) Bytes() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.bytes()
+}
+
+func (r *Rotating) bytes() int64 {
+	if r.cur == nil {
+		return r.written
+	}
+	return r.written + r.cur.Bytes()
+}
+
+// reclaim completes the current file and removes the oldest completed files
+// until at least need bytes were freed, or none is left. It returns how many
+// bytes were freed.
+func (r *Rotating) reclaim(need int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cur != nil {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	var freed int64
+	for freed < need && len(r.files) > 0 {
+		f := r.files[0]
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return freed, err
+		}
+		r.files = r.files[1:]
+		freed += f.size
+	}
+	return freed, nil
+}
+
+func (r *Rotating) rotate() error {
+	size := r.cur.Bytes()
+	err := r.cur.Close()
+	r.cur = nil
+	r.written += size
+	r.files = append(r.files, rotatedFile{path: r.path, size: size})
+	if err != nil {
+		return fmt.Errorf("closing %s: %w", r.path, err)
+	}
+	if r.opts.Rotated != nil {
+		r.opts.Rotated(r.path)
+	}
+	return nil
+}
+
+// countingFile counts the bytes written to a file.
+type countingFile struct {
+	*os.File
+	n int64
+}
+
+func (f *countingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.n += int64(n)
+	return n, err
+}
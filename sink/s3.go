@@ -0,0 +1,185 @@
+package sink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/retry"
+)
+
+// S3 is an ObjectStore of Amazon S3 or an S3-compatible service, such as
+// MinIO or Google Cloud Storage with HMAC keys (see GCS). Requests are
+// signed with AWS Signature Version 4.
+type S3 struct {
+	Bucket string
+	Region string
+	// Endpoint is the URL of the service, with the bucket in the path, e.g.
+	// "http://minio:9000". If empty, the virtual-hosted AWS endpoint of the
+	// bucket in Region is used.
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	// metaPrefix prefixes metadata headers, "x-amz-meta-" if empty.
+	metaPrefix string
+}
+
+// GCS returns an ObjectStore of a Google Cloud Storage bucket, through its
+// XML API with the HMAC key of a service account.
+func GCS(bucket, accessID, secret string) *S3 {
+	return &S3{
+		Bucket:          bucket,
+		Region:          "auto",
+		Endpoint:        "https://storage.googleapis.com",
+		AccessKeyID:     accessID,
+		SecretAccessKey: secret,
+		metaPrefix:      "x-goog-meta-",
+	}
+}
+
+// Put uploads the object with a single PUT, so objects are limited to 5GB.
+func (s *S3) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, metadata map[string]string) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(h.Sum(nil))
+
+	var u string
+	if s.Endpoint == "" {
+		u = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, s3Escape(key))
+	} else {
+		u = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.Endpoint, "/"), s.Bucket, s3Escape(key))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	metaPrefix := s.metaPrefix
+	if metaPrefix == "" {
+		metaPrefix = "x-amz-meta-"
+	}
+	for k, v := range metadata {
+		req.Header.Set(metaPrefix+strings.ToLower(k), v)
+	}
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	return doPut(s.Client, req)
+}
+
+// sign adds the Signature Version 4 authorization header to req.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape escapes the segments of an object key as Signature Version 4
+// expects: everything but unreserved characters.
+func s3Escape(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func doPut(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &retry.StatusError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("PUT %s: %s: %s", redact(req.URL), resp.Status, strings.TrimSpace(string(msg))),
+		}
+	}
+	return nil
+}
+
+// redact drops the query of u, holding the SAS token of Azure URLs.
+func redact(u *url.URL) string {
+	r := *u
+	r.RawQuery = ""
+	return r.String()
+}
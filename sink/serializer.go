@@ -0,0 +1,95 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Serializer encodes events for a sink. Serializers may keep state between
+// calls, so every sink needs its own.
+type Serializer interface {
+	// Begin writes what precedes the first event, such as a file header.
+	Begin(w io.Writer) error
+	// Encode writes one event.
+	Encode(w io.Writer, e ig.Event) error
+}
+
+// JSON returns a serializer writing events as JSON lines.
+func JSON() Serializer {
+	return jsonSerializer{}
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Begin(io.Writer) error {
+	return nil
+}
+
+func (jsonSerializer) Encode(w io.Writer, e ig.Event) error {
+	return json.NewEncoder(w).Encode(e.Fields)
+}
+
+// toInt64 converts a decoded JSON value to a schema integer.
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// toFloat64 converts a decoded JSON value to a schema number.
+func toFloat64(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toString converts a decoded JSON value to a schema string. Objects and
+// arrays are rendered as JSON.
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any, []any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// fieldError reports a value not matching the type of its schema field.
+func fieldError(n *ig.SchemaNode, v any) error {
+	return fmt.Errorf("field %s: %v is not of type %s", n.Field.Path, v, n.Field.Type)
+}
+
+// identifier turns a gadget, data source or field name into an identifier
+// valid as Avro name or Azure metadata name.
+func identifier(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, s)
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "_" + s
+	}
+	return s
+}
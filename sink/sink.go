@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/internal/stats"
+)
+
+// Sink consumes events.
+type Sink interface {
+	Write(e ig.Event) error
+	Close() error
+}
+
+// Writer is a Sink serializing events to an io.WriteCloser.
+type Writer struct {
+	mu  sync.Mutex
+	dst io.WriteCloser
+	buf *bufio.Writer
+	ser Serializer
+	// n counts the bytes serialized.
+	n int64
+}
+
+// New returns a sink serializing events to w with ser. It writes the header
+// of ser, if any, right away.
+func New(w io.WriteCloser, ser Serializer) (*Writer, error) {
+	s := &Writer{dst: w, buf: bufio.NewWriter(w), ser: ser}
+	if err := ser.Begin(s.counted()); err != nil {
+		return nil, fmt.Errorf("writing header: %w", err)
+	}
+	return s, nil
+}
+
+// Create returns a sink serializing events to the file path with ser,
+// truncating it if it exists.
+func Create(path string, ser Serializer) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(f, ser)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write serializes e. Events that aren't JSON objects, such as warnings
+// printed by ig, are skipped.
+func (s *Writer) Write(e ig.Event) error {
+	if e.Fields == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ser.Encode(s.counted(), e)
+}
+
+// Bytes returns how many bytes s serialized, header included, whether
+// flushed or still buffered.
+func (s *Writer) Bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.n
+}
+
+// counted returns the buffer of s, counting the bytes written to it.
+func (s *Writer) counted() io.Writer {
+	return countingWriter{w: s.buf, n: &s.n}
+}
+
+// countingWriter counts the bytes written to w in n and stats.SinkBytes.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	stats.SinkBytes.Add(int64(n))
+	return n, err
+}
+
+// Close flushes buffered events and closes the underlying writer.
+func (s *Writer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return errors.Join(s.buf.Flush(), s.dst.Close())
+}
+
+// Drain writes every event of events to s until the channel is closed, then
+// closes s. It keeps reading after errors so the gadget is never blocked,
+// and returns the first of them.
+func Drain(s Sink, events <-chan ig.Event) error {
+	var firstErr error
+	for e := range events {
+		if err := s.Write(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return errors.Join(firstErr, s.Close())
+}
@@ -0,0 +1,137 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/retry"
+)
+
+// RetainUntilKey is the metadata key of the retention time of uploaded
+// captures, as RFC 3339. Lifecycle rules or cleanup jobs on the storage side
+// act on it.
+const RetainUntilKey = "retain_until"
+
+// ObjectStore is a bucket of S3, GCS, Azure Blob Storage or alike.
+type ObjectStore interface {
+	// Put stores size bytes of body as the object key, with metadata.
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64, metadata map[string]string) error
+}
+
+// Uploader pushes completed capture files to an object store, e.g. as the
+// Rotated callback of a Rotating sink, so node captures land in central
+// storage.
+type Uploader struct {
+	Store ObjectStore
+	// Prefix is prepended to file names to get object keys, e.g.
+	// "captures/node-1/".
+	Prefix string
+	// Retention, if not zero, sets RetainUntilKey to the upload time plus
+	// Retention.
+	Retention time.Duration
+	// Metadata is added to every object.
+	Metadata map[string]string
+	// Remove deletes files once uploaded.
+	Remove bool
+	// Backoff retries failed uploads. Defaults to 5 attempts from 1s to 30s,
+	// with jitter, retrying errors retry.Classify accepts.
+	Backoff *retry.Backoff
+	// OnError, if not nil, is called with the files Enqueue failed to
+	// upload.
+	OnError func(path string, err error)
+
+	mu      sync.Mutex
+	queue   []string
+	running bool
+	pending sync.WaitGroup
+}
+
+var defaultUploadBackoff = retry.Backoff{
+	Attempts:  5,
+	Initial:   time.Second,
+	Max:       30 * time.Second,
+	Jitter:    0.2,
+	Retryable: retry.Classify,
+}
+
+// Upload uploads the file path, retrying according to Backoff.
+func (u *Uploader) Upload(ctx context.Context, file string) error {
+	key := path.Join(u.Prefix, filepath.Base(file))
+	metadata := map[string]string{}
+	for k, v := range u.Metadata {
+		metadata[k] = v
+	}
+	if u.Retention > 0 {
+		metadata[RetainUntilKey] = time.Now().Add(u.Retention).UTC().Format(time.RFC3339)
+	}
+
+	b := defaultUploadBackoff
+	if u.Backoff != nil {
+		b = *u.Backoff
+	}
+	err := retry.Do(ctx, b, func(int) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		return u.Store.Put(ctx, key, f, fi.Size(), metadata)
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", file, err)
+	}
+
+	if u.Remove {
+		return os.Remove(file)
+	}
+	return nil
+}
+
+// Enqueue uploads file in the background, one file at a time in order.
+func (u *Uploader) Enqueue(file string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.queue = append(u.queue, file)
+	u.pending.Add(1)
+	if !u.running {
+		u.running = true
+		go u.run()
+	}
+}
+
+// run uploads queued files until the queue is empty.
+func (u *Uploader) run() {
+	for {
+		u.mu.Lock()
+		if len(u.queue) == 0 {
+			u.running = false
+			u.mu.Unlock()
+			return
+		}
+		file := u.queue[0]
+		u.queue = u.queue[1:]
+		u.mu.Unlock()
+
+		if err := u.Upload(context.Background(), file); err != nil && u.OnError != nil {
+			u.OnError(file, err)
+		}
+		u.pending.Done()
+	}
+}
+
+// Wait waits for the files enqueued so far to be uploaded, or to fail.
+func (u *Uploader) Wait() {
+	u.pending.Wait()
+}
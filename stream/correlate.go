@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// CorrelationRule describes how Correlate joins events into composites.
+type CorrelationRule struct {
+	// Root matches the events opening a composite, e.g. FromGadget("trace_exec").
+	Root func(ig.Event) bool
+	// Key joins related events to the composite of their root, e.g. ByPID.
+	Key KeyFunc
+	// Window is how long after its root a composite accepts related events.
+	Window time.Duration
+}
+
+// Composite is a root event and the events from other gadgets that share its
+// key within the correlation window, e.g. an exec and the connections the
+// new process made.
+type Composite struct {
+	Key     string
+	Root    ig.Event
+	Related []ig.Event
+}
+
+// Correlate joins the events of in into composites according to rule. A
+// composite is emitted once its window closed, when a new root with the same
+// key arrives, or when in is closed. Events that are neither roots nor
+// related to an open composite are dropped.
+func Correlate(in <-chan ig.Event, rule CorrelationRule) <-chan Composite {
+	out := make(chan Composite, 64)
+
+	go func() {
+		defer close(out)
+
+		open := map[string]*Composite{}
+		flush := func(key string) {
+			out <- *open[key]
+			delete(open, key)
+		}
+		expire := func(now time.Time) {
+			for key, c := range open {
+				if now.Sub(c.Root.Received) >= rule.Window {
+					flush(key)
+				}
+			}
+		}
+
+		tick := rule.Window / 4
+		if tick < 10*time.Millisecond {
+			tick = 10 * time.Millisecond
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					for key := range open {
+						flush(key)
+					}
+					return
+				}
+				expire(ev.Received)
+
+				key, ok := rule.Key(ev)
+				if !ok {
+					continue
+				}
+				if rule.Root(ev) {
+					if _, exists := open[key]; exists {
+						flush(key)
+					}
+					open[key] = &Composite{Key: key, Root: ev}
+					continue
+				}
+				if c, exists := open[key]; exists {
+					c.Related = append(c.Related, ev)
+				}
+			case now := <-ticker.C:
+				expire(now)
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,5 @@
+// Package stream provides processing stages for the event streams of
+// running gadgets. Stages consume a channel of ig.Event, typically from a
+// GadgetSession or GadgetSet, and return a derived channel closed once
+// their input is.
+package stream
@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+	"github.com/pawarpranav83/ig-testing-framework/internal/stats"
+)
+
+// SlowConsumerPolicy decides what a Fanout does with an event for a
+// subscriber whose buffer is full.
+type SlowConsumerPolicy int
+
+const (
+	// Block waits for the subscriber, slowing down every other subscriber
+	// and, eventually, the gadget.
+	Block SlowConsumerPolicy = iota
+	// DropNewest drops the event.
+	DropNewest
+	// DropOldest drops the oldest buffered event to make room.
+	DropOldest
+	// Disconnect unsubscribes the subscriber, closing its channel.
+	Disconnect
+)
+
+// Fanout feeds one event stream to several independent subscribers, each
+// with its own buffer and slow-consumer policy, e.g. a file sink, a metrics
+// exporter and a live UI on a single capture.
+type Fanout struct {
+	in <-chan ig.Event
+
+	mu    sync.Mutex
+	subs  map[*Subscription]struct{}
+	done  bool
+	start sync.Once
+}
+
+// NewFanout returns a fanout of in. Nothing is read from in until Start, so
+// subscribers registered before Start see every event; later ones only see
+// the events from when they subscribed.
+func NewFanout(in <-chan ig.Event) *Fanout {
+	return &Fanout{in: in, subs: map[*Subscription]struct{}{}}
+}
+
+// Start starts forwarding events to subscribers, until in is closed.
+func (f *Fanout) Start() {
+	f.start.Do(func() { go f.run() })
+}
+
+func (f *Fanout) run() {
+	for ev := range f.in {
+		f.mu.Lock()
+		subs := make([]*Subscription, 0, len(f.subs))
+		for s := range f.subs {
+			subs = append(subs, s)
+		}
+		f.mu.Unlock()
+
+		for _, s := range subs {
+			s.deliver(ev)
+		}
+	}
+
+	f.mu.Lock()
+	f.done = true
+	subs := f.subs
+	f.subs = nil
+	f.mu.Unlock()
+
+	for s := range subs {
+		s.close()
+	}
+}
+
+// Subscribe adds a subscriber with a buffer of size events, handling
+// overflows according to policy. Its channel is closed once in is, or on
+// Unsubscribe.
+func (f *Fanout) Subscribe(size int, policy SlowConsumerPolicy) *Subscription {
+	s := &Subscription{
+		f:      f,
+		ch:     make(chan ig.Event, size),
+		quit:   make(chan struct{}),
+		policy: policy,
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.done {
+		s.close()
+		return s
+	}
+	f.subs[s] = struct{}{}
+	return s
+}
+
+// Subscription is one subscriber of a Fanout.
+type Subscription struct {
+	f      *Fanout
+	ch     chan ig.Event
+	policy SlowConsumerPolicy
+
+	// quit releases a blocked delivery; mu serializes deliveries with the
+	// closing of ch.
+	quit     chan struct{}
+	quitOnce sync.Once
+	mu       sync.Mutex
+	closed   bool
+
+	dropped atomic.Uint64
+}
+
+// Events returns the events of the subscription.
+func (s *Subscription) Events() <-chan ig.Event {
+	return s.ch
+}
+
+// Dropped returns how many events the subscription lost to its policy.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) drop() {
+	s.dropped.Add(1)
+	stats.EventsDropped.Add(1)
+}
+
+// Unsubscribe stops the delivery of events and closes the channel.
+func (s *Subscription) Unsubscribe() {
+	s.f.mu.Lock()
+	delete(s.f.subs, s)
+	s.f.mu.Unlock()
+
+	s.close()
+}
+
+func (s *Subscription) close() {
+	s.quitOnce.Do(func() { close(s.quit) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func (s *Subscription) deliver(ev ig.Event) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+
+	select {
+	case s.ch <- ev:
+		s.mu.Unlock()
+		return
+	default:
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- ev:
+		case <-s.quit:
+		}
+	case DropNewest:
+		s.drop()
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- ev:
+				s.mu.Unlock()
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.drop()
+			default:
+			}
+		}
+	case Disconnect:
+		s.drop()
+		s.mu.Unlock()
+		s.Unsubscribe()
+		return
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// KeyFunc extracts a join key from an event. It returns false if the event
+// has no key.
+type KeyFunc func(ig.Event) (string, bool)
+
+// KeyByField returns a KeyFunc using the first of paths present in the
+// event, so the same key works across gadgets with different schemas.
+func KeyByField(paths ...string) KeyFunc {
+	return func(e ig.Event) (string, bool) {
+		for _, p := range paths {
+			if v, ok := e.Field(p); ok && v != nil {
+				return fmt.Sprint(v), true
+			}
+		}
+		return "", false
+	}
+}
+
+var (
+	// ByPID keys events by process ID.
+	ByPID = KeyByField("proc.pid", "pid")
+	// ByContainer keys events by container, preferring its ID.
+	ByContainer = KeyByField("runtime.containerId", "runtime.containerName", "k8s.containerName", "container")
+)
+
+// FromGadget returns a predicate matching events emitted by the gadget
+// named name ("trace_exec"), whatever registry or tag its image used.
+func FromGadget(name string) func(ig.Event) bool {
+	return func(e ig.Event) bool {
+		return ig.GadgetName(e.Gadget) == name
+	}
+}
+
+// containerFields are the fields identifying the container of an event.
+var containerFields = []string{"runtime.containerId", "runtime.containerName", "k8s.containerName", "container"}
+
+// InContainer returns a predicate matching events from the container with
+// the given name or ID (a prefix of at least 12 characters for IDs, as
+// printed by container runtimes).
+func InContainer(nameOrID string) func(ig.Event) bool {
+	return func(e ig.Event) bool {
+		for _, p := range containerFields {
+			v, ok := e.Field(p)
+			if !ok {
+				continue
+			}
+			s := fmt.Sprint(v)
+			if s == nameOrID || (len(nameOrID) >= 12 && strings.HasPrefix(s, nameOrID)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OnHost matches events from processes outside any container.
+func OnHost(e ig.Event) bool {
+	for _, p := range containerFields {
+		if v, ok := e.Field(p); ok && v != nil && v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Not negates a predicate, e.g. Not(InContainer("web")) to match the events
+// a filter on container "web" must exclude.
+func Not(pred func(ig.Event) bool) func(ig.Event) bool {
+	return func(e ig.Event) bool {
+		return !pred(e)
+	}
+}
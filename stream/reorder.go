@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Reorder re-emits the events of in in timestamp order (see ig.Event.Time),
+// ties broken by Seq. Each event is held back for at least window after it
+// arrived, so events up to window late are put back in place; later ones
+// stay out of order. Gadgets reading per-CPU buffers typically need a few
+// milliseconds.
+func Reorder(in <-chan ig.Event, window time.Duration) <-chan ig.Event {
+	out := make(chan ig.Event, 64)
+
+	go func() {
+		defer close(out)
+
+		var pending ig.EventQueue
+		release := func(now time.Time) {
+			for pending.Len() > 0 && now.Sub(pending.Peek().Received) >= window {
+				out <- pending.Pop()
+			}
+		}
+
+		tick := window / 4
+		if tick < time.Millisecond {
+			tick = time.Millisecond
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					for pending.Len() > 0 {
+						out <- pending.Pop()
+					}
+					return
+				}
+				pending.Push(ev)
+				release(time.Now())
+			case now := <-ticker.C:
+				release(now)
+			}
+		}
+	}()
+
+	return out
+}
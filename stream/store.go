@@ -0,0 +1,407 @@
+package stream
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Store retains the events of a run in memory so they can be re-sliced with
+// queries without re-running the gadget.
+type Store struct {
+	mu     sync.RWMutex
+	events []ig.Event
+	max    int
+}
+
+// NewStore returns a store keeping the last max events, or every event if
+// max is 0.
+func NewStore(max int) *Store {
+	return &Store{max: max}
+}
+
+// Add stores an event, dropping the oldest one if the store is full.
+func (s *Store) Add(ev ig.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.max > 0 && len(s.events) == s.max {
+		copy(s.events, s.events[1:])
+		s.events = s.events[:len(s.events)-1]
+	}
+	s.events = append(s.events, ev)
+}
+
+// Tee stores every event of in and forwards it on the returned channel,
+// closed once in is.
+func (s *Store) Tee(in <-chan ig.Event) <-chan ig.Event {
+	out := make(chan ig.Event, 64)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			s.Add(ev)
+			out <- ev
+		}
+	}()
+	return out
+}
+
+// Len returns the number of stored events.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.events)
+}
+
+// Query starts a query over the stored events.
+func (s *Store) Query() *Query {
+	return &Query{store: s}
+}
+
+// Query selects stored events. Build it fluently, or parse it with
+// ParseQuery:
+//
+//	store.Query().Where("proc.comm", "==", "curl").OrderBy("proc.pid", true).Limit(10).Events()
+type Query struct {
+	store   *Store
+	filters []func(ig.Event) bool
+	groupBy string
+	orderBy string
+	desc    bool
+	limit   int
+	err     error
+}
+
+// Filter keeps the events for which fn returns true.
+func (q *Query) Filter(fn func(ig.Event) bool) *Query {
+	q.filters = append(q.filters, fn)
+	return q
+}
+
+// Where keeps the events whose field at path compares to value with op: one
+// of ==, !=, <, <=, >, >= or ~ (regexp match). Numbers compare numerically,
+// anything else as strings.
+func (q *Query) Where(path, op string, value any) *Query {
+	fn, err := comparison(op, value)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	return q.Filter(func(e ig.Event) bool {
+		v, ok := e.Field(path)
+		return ok && fn(v)
+	})
+}
+
+// GroupBy makes Groups group the selected events by the field at path.
+func (q *Query) GroupBy(path string) *Query {
+	q.groupBy = path
+	return q
+}
+
+// OrderBy sorts the selected events by the field at path, descending if desc
+// is set. Events missing the field come last. Without OrderBy events keep
+// their arrival order.
+func (q *Query) OrderBy(path string, desc bool) *Query {
+	q.orderBy = path
+	q.desc = desc
+	return q
+}
+
+// Limit caps the number of returned events or groups.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Events returns the selected events.
+func (q *Query) Events() ([]ig.Event, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	q.store.mu.RLock()
+	var out []ig.Event
+	for _, ev := range q.store.events {
+		if q.keep(ev) {
+			out = append(out, ev)
+		}
+	}
+	q.store.mu.RUnlock()
+
+	if q.orderBy != "" {
+		sort.SliceStable(out, func(i, j int) bool {
+			a, aok := out[i].Field(q.orderBy)
+			b, bok := out[j].Field(q.orderBy)
+			switch {
+			case !aok || !bok:
+				return aok && !bok
+			case q.desc:
+				return compareValues(b, a) < 0
+			}
+			return compareValues(a, b) < 0
+		})
+	}
+	if q.limit > 0 && len(out) > q.limit {
+		out = out[:q.limit]
+	}
+	return out, nil
+}
+
+// Group is a set of events sharing the value of the GroupBy field.
+type Group struct {
+	Key    string
+	Events []ig.Event
+}
+
+// Groups returns the selected events grouped by the GroupBy field, largest
+// group first. Within a group, events are ordered as by Events. Events
+// missing the field are grouped under an empty key. Limit applies to groups.
+func (q *Query) Groups() ([]Group, error) {
+	if q.groupBy == "" {
+		return nil, fmt.Errorf("query has no group by field")
+	}
+
+	limit := q.limit
+	q.limit = 0
+	events, err := q.Events()
+	q.limit = limit
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	var groups []Group
+	for _, ev := range events {
+		key := ""
+		if v, ok := ev.Field(q.groupBy); ok {
+			key = fmt.Sprint(v)
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Key: key})
+		}
+		groups[i].Events = append(groups[i].Events, ev)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Events) != len(groups[j].Events) {
+			return len(groups[i].Events) > len(groups[j].Events)
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+func (q *Query) keep(ev ig.Event) bool {
+	for _, f := range q.filters {
+		if !f(ev) {
+			return false
+		}
+	}
+	return true
+}
+
+func comparison(op string, value any) (func(any) bool, error) {
+	if op == "~" {
+		re, err := regexp.Compile(fmt.Sprint(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp: %w", err)
+		}
+		return func(v any) bool { return re.MatchString(fmt.Sprint(v)) }, nil
+	}
+
+	var ok func(c int) bool
+	switch op {
+	case "==", "=":
+		ok = func(c int) bool { return c == 0 }
+	case "!=":
+		ok = func(c int) bool { return c != 0 }
+	case "<":
+		ok = func(c int) bool { return c < 0 }
+	case "<=":
+		ok = func(c int) bool { return c <= 0 }
+	case ">":
+		ok = func(c int) bool { return c > 0 }
+	case ">=":
+		ok = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+	return func(v any) bool { return ok(compareValues(v, value)) }, nil
+}
+
+// compareValues compares numerically if both values are numbers (or numeric
+// strings), and as strings otherwise.
+func compareValues(a, b any) int {
+	af, aok := toNumber(a)
+	bf, bok := toNumber(b)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// toNumber is ig.Number, also parsing numeric strings.
+func toNumber(v any) (float64, bool) {
+	if s, ok := v.(string); ok {
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	return ig.Number(v)
+}
+
+// ParseQuery parses a textual query, for interactive tooling, e.g.
+//
+//	where proc.comm == curl and dst.port >= 1024 group by proc.pid order by timestamp desc limit 10
+//
+// Values may be double-quoted to contain spaces, or to be taken literally
+// rather than as keywords. Clauses must appear in that order and are all
+// optional.
+func (s *Store) ParseQuery(text string) (*Query, error) {
+	toks, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	q := s.Query()
+	next := func() (string, bool) {
+		if len(toks) == 0 {
+			return "", false
+		}
+		t := toks[0]
+		toks = toks[1:]
+		return t.text, true
+	}
+	peek := func(want string) bool {
+		return len(toks) > 0 && !toks[0].quoted && strings.EqualFold(toks[0].text, want)
+	}
+	expect := func(what string) (string, error) {
+		t, ok := next()
+		if !ok {
+			return "", fmt.Errorf("expected %s at end of query", what)
+		}
+		return t, nil
+	}
+
+	if peek("where") {
+		next()
+		for {
+			path, err := expect("field")
+			if err != nil {
+				return nil, err
+			}
+			op, err := expect("operator")
+			if err != nil {
+				return nil, err
+			}
+			value, err := expect("value")
+			if err != nil {
+				return nil, err
+			}
+			q.Where(path, op, value)
+			if q.err != nil {
+				return nil, q.err
+			}
+			if !peek("and") {
+				break
+			}
+			next()
+		}
+	}
+	if peek("group") {
+		next()
+		if !peek("by") {
+			return nil, fmt.Errorf("expected \"by\" after \"group\"")
+		}
+		next()
+		path, err := expect("field")
+		if err != nil {
+			return nil, err
+		}
+		q.GroupBy(path)
+	}
+	if peek("order") {
+		next()
+		if !peek("by") {
+			return nil, fmt.Errorf("expected \"by\" after \"order\"")
+		}
+		next()
+		path, err := expect("field")
+		if err != nil {
+			return nil, err
+		}
+		desc := false
+		if peek("desc") || peek("asc") {
+			t, _ := next()
+			desc = strings.EqualFold(t, "desc")
+		}
+		q.OrderBy(path, desc)
+	}
+	if peek("limit") {
+		next()
+		t, err := expect("number")
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(t)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", t)
+		}
+		q.Limit(n)
+	}
+	if len(toks) > 0 {
+		return nil, fmt.Errorf("unexpected %q in query", toks[0].text)
+	}
+
+	return q, nil
+}
+
+// queryToken is a word of a textual query.
+type queryToken struct {
+	text string
+	// quoted tokens are never keywords.
+	quoted bool
+}
+
+func tokenize(text string) ([]queryToken, error) {
+	var toks []queryToken
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote at offset %d", i)
+			}
+			toks = append(toks, queryToken{text: text[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		default:
+			end := strings.IndexAny(text[i:], " \t\n")
+			if end < 0 {
+				end = len(text) - i
+			}
+			toks = append(toks, queryToken{text: text[i : i+end]})
+			i += end
+		}
+	}
+	return toks, nil
+}
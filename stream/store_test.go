@@ -0,0 +1,194 @@
+package stream
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+func testStore() *Store {
+	s := NewStore(0)
+	for _, fields := range []map[string]any{
+		{"id": "a", "proc": map[string]any{"comm": "curl", "pid": float64(30)}, "dst": map[string]any{"port": float64(443)}},
+		{"id": "b", "proc": map[string]any{"comm": "wget", "pid": float64(4)}, "dst": map[string]any{"port": float64(80)}},
+		{"id": "c", "proc": map[string]any{"comm": "curl", "pid": float64(200)}, "dst": map[string]any{"port": float64(8080)}},
+		{"id": "d", "proc": map[string]any{"comm": "ssh client", "pid": float64(7)}},
+		{"id": "e", "proc": map[string]any{"comm": "and", "pid": float64(9)}},
+	} {
+		s.Add(ig.Event{Fields: fields})
+	}
+	return s
+}
+
+func ids(events []ig.Event) []string {
+	var ids []string
+	for _, ev := range events {
+		id, _ := ev.Field("id")
+		ids = append(ids, id.(string))
+	}
+	return ids
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"a", "b", "c", "d", "e"}},
+		{query: "where proc.comm == curl", want: []string{"a", "c"}},
+		{query: "WHERE proc.comm = curl", want: []string{"a", "c"}},
+		{query: "where proc.comm != curl", want: []string{"b", "d", "e"}},
+		{query: "where proc.comm == curl and dst.port >= 1024", want: []string{"c"}},
+		{query: "where dst.port > 80 and dst.port < 8080 and proc.comm == curl", want: []string{"a"}},
+		{query: `where proc.comm ~ "^(curl|wget)$"`, want: []string{"a", "b", "c"}},
+		// Numbers compare numerically, not as strings.
+		{query: "where proc.pid <= 30", want: []string{"a", "b", "d", "e"}},
+		{query: `where proc.pid < "10"`, want: []string{"b", "d", "e"}},
+		// Quoted values hold spaces and keywords.
+		{query: `where proc.comm == "ssh client"`, want: []string{"d"}},
+		{query: `where proc.comm == "and"`, want: []string{"e"}},
+		{query: `where proc.comm == ""`, want: nil},
+		// Events without the field never match, whatever the operator.
+		{query: "where dst.port != 80", want: []string{"a", "c"}},
+		{query: "where unknown.field == x", want: nil},
+		{query: "where unknown.field != x", want: nil},
+		{query: "order by proc.pid", want: []string{"b", "d", "e", "a", "c"}},
+		{query: "order by proc.pid desc", want: []string{"c", "a", "e", "d", "b"}},
+		{query: "order by proc.pid ASC limit 2", want: []string{"b", "d"}},
+		{query: "order by proc.comm", want: []string{"e", "a", "c", "d", "b"}},
+		// Events missing the field come last, in arrival order.
+		{query: "order by dst.port desc", want: []string{"c", "a", "b", "d", "e"}},
+		{query: "order by unknown", want: []string{"a", "b", "c", "d", "e"}},
+		{query: "where proc.comm == curl order by proc.pid desc limit 1", want: []string{"c"}},
+		{query: "limit 3", want: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := testStore().ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery: %v", err)
+			}
+			events, err := q.Events()
+			if err != nil {
+				t.Fatalf("Events: %v", err)
+			}
+			if got := ids(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryGroups(t *testing.T) {
+	tests := []struct {
+		query string
+		want  map[string][]string
+		keys  []string
+	}{
+		{
+			query: "group by proc.comm",
+			keys:  []string{"curl", "and", "ssh client", "wget"},
+			want:  map[string][]string{"curl": {"a", "c"}, "and": {"e"}, "ssh client": {"d"}, "wget": {"b"}},
+		},
+		{
+			query: "where proc.pid >= 7 group by proc.comm order by proc.pid desc limit 2",
+			keys:  []string{"curl", "and"},
+			want:  map[string][]string{"curl": {"c", "a"}, "and": {"e"}},
+		},
+		{
+			query: "group by dst.port",
+			// Events missing the field are grouped under an empty key, and
+			// ties ordered by key.
+			keys: []string{"", "443", "80", "8080"},
+			want: map[string][]string{"": {"d", "e"}, "443": {"a"}},
+		},
+		{
+			query: "group by unknown",
+			keys:  []string{""},
+			want:  map[string][]string{"": {"a", "b", "c", "d", "e"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := testStore().ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery: %v", err)
+			}
+			groups, err := q.Groups()
+			if err != nil {
+				t.Fatalf("Groups: %v", err)
+			}
+			var keys []string
+			for _, g := range groups {
+				keys = append(keys, g.Key)
+				if want, ok := tt.want[g.Key]; ok && !reflect.DeepEqual(ids(g.Events), want) {
+					t.Errorf("group %q: events %q, want %q", g.Key, ids(g.Events), want)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("groups %q, want %q", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{query: "where", err: "expected field at end of query"},
+		{query: "where proc.comm", err: "expected operator at end of query"},
+		{query: "where proc.comm ==", err: "expected value at end of query"},
+		{query: "where proc.comm == curl and", err: "expected field at end of query"},
+		{query: "where proc.comm is curl", err: `unknown operator "is"`},
+		{query: `where proc.comm ~ "("`, err: "invalid regexp"},
+		{query: `where proc.comm == "curl`, err: "unterminated quote at offset 19"},
+		{query: "where proc.comm == curl or proc.comm == wget", err: `unexpected "or" in query`},
+		{query: "group proc.comm", err: `expected "by" after "group"`},
+		{query: "group by", err: "expected field at end of query"},
+		{query: "order proc.pid", err: `expected "by" after "order"`},
+		{query: "order by", err: "expected field at end of query"},
+		{query: "order by proc.pid sideways", err: `unexpected "sideways" in query`},
+		{query: "limit", err: "expected number at end of query"},
+		{query: "limit ten", err: `invalid limit "ten"`},
+		{query: "limit 0", err: `invalid limit "0"`},
+		{query: "limit -1", err: `invalid limit "-1"`},
+		// Clauses must appear in order.
+		{query: "limit 1 where proc.pid > 1", err: `unexpected "where" in query`},
+		{query: "order by proc.pid group by proc.comm", err: `unexpected "group" in query`},
+		// Quoted keywords are values, not clauses.
+		{query: `"where" proc.comm == curl`, err: `unexpected "where" in query`},
+		{query: `where proc.comm == curl "limit" 1`, err: `unexpected "limit" in query`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := testStore().ParseQuery(tt.query)
+			if err == nil {
+				t.Fatal("ParseQuery succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseQuery: %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestStoreMax(t *testing.T) {
+	s := NewStore(2)
+	for _, id := range []string{"a", "b", "c"} {
+		s.Add(ig.Event{Fields: map[string]any{"id": id}})
+	}
+	events, err := s.Query().Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(events), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events %q, want %q", got, want)
+	}
+	if _, err := s.Query().Groups(); err == nil {
+		t.Error("Groups without GroupBy succeeded")
+	}
+}
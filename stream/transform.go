@@ -0,0 +1,547 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+// Transformer reshapes events with an expression of a small subset of jq,
+// so events can be reshaped from configuration rather than Go code:
+//
+//	.                         the event
+//	.proc.comm, ."k8s".pod    a field, null if missing
+//	{comm: .proc.comm, pid}   an object; "pid" is short for "pid: .pid"
+//	[.proc.pid, .proc.ppid]   an array
+//	"s", 1, true, null        literals
+//	del(.args, .proc.creds)   the input without the given fields
+//	select(.proc.uid == 0)    the input if the condition holds, else no event
+//	a | b                     b applied to the output of a
+//
+// Conditions compare a field to a literal with the operators of Query.Where
+// (== != < <= > >= ~), joined with "and" and "or", "and" binding tighter.
+// Results that aren't objects are wrapped as {"value": result}.
+type Transformer struct {
+	expr string
+	eval evalFunc
+}
+
+// evalFunc evaluates an expression on v. It returns false when the
+// expression produces nothing, as select does.
+type evalFunc func(v any) (any, bool)
+
+// CompileTransform compiles a transformation expression.
+func CompileTransform(expr string) (*Transformer, error) {
+	toks, err := lexTransform(expr)
+	if err != nil {
+		return nil, fmt.Errorf("transform %q: %w", expr, err)
+	}
+	p := &transformParser{toks: toks}
+	eval, err := p.pipe()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transform %q: %w", expr, err)
+	}
+	return &Transformer{expr: expr, eval: eval}, nil
+}
+
+// String returns the expression of t.
+func (t *Transformer) String() string {
+	return t.expr
+}
+
+// Apply transforms the fields of an event, returning false if the event is
+// filtered out. fields isn't modified.
+func (t *Transformer) Apply(fields map[string]any) (map[string]any, bool) {
+	v, ok := t.eval(fields)
+	if !ok {
+		return nil, false
+	}
+	if m, isMap := v.(map[string]any); isMap {
+		return m, true
+	}
+	return map[string]any{"value": v}, true
+}
+
+// Stage transforms every JSON event of in, with Raw re-encoded from the new
+// fields. Other events pass through unchanged.
+func (t *Transformer) Stage(in <-chan ig.Event) <-chan ig.Event {
+	out := make(chan ig.Event, 64)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			if ev.Fields != nil {
+				fields, ok := t.Apply(ev.Fields)
+				if !ok {
+					continue
+				}
+				ev.Fields = fields
+				if raw, err := json.Marshal(fields); err == nil {
+					ev.Raw = string(raw)
+				}
+			}
+			out <- ev
+		}
+	}()
+	return out
+}
+
+// Transform compiles expr and applies it to the events of in, see
+// Transformer.
+func Transform(in <-chan ig.Event, expr string) (<-chan ig.Event, error) {
+	t, err := CompileTransform(expr)
+	if err != nil {
+		return nil, err
+	}
+	return t.Stage(in), nil
+}
+
+// token is a lexed token: punctuation, an operator, an identifier, or a
+// literal with its decoded value.
+type token struct {
+	text    string
+	literal bool
+	value   any
+	// spaced is set for tokens following whitespace, which ends paths.
+	spaced bool
+}
+
+func (t token) String() string {
+	return t.text
+}
+
+func lexTransform(expr string) ([]token, error) {
+	var toks []token
+	spaced := false
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		if c == ' ' || c == '\t' || c == '\n' {
+			spaced = true
+			i++
+			continue
+		}
+		start := len(toks)
+		switch {
+		case strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "<="), strings.HasPrefix(expr[i:], ">="):
+			toks = append(toks, token{text: expr[i : i+2]})
+			i += 2
+		case strings.IndexByte(".|{}[](),:<>~", c) >= 0:
+			toks = append(toks, token{text: expr[i : i+1]})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			toks = append(toks, token{text: expr[i : end+1], literal: true, value: s})
+			i = end + 1
+		case c == '-' || ('0' <= c && c <= '9'):
+			end := i + 1
+			for end < len(expr) && strings.IndexByte("0123456789.eE+-", expr[end]) >= 0 {
+				end++
+			}
+			f, err := strconv.ParseFloat(expr[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", expr[i:end])
+			}
+			toks = append(toks, token{text: expr[i:end], literal: true, value: f})
+			i = end
+		case isIdentByte(c, true):
+			end := i + 1
+			for end < len(expr) && isIdentByte(expr[end], false) {
+				end++
+			}
+			toks = append(toks, token{text: expr[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+		}
+		toks[start].spaced = spaced
+		spaced = false
+	}
+	return toks, nil
+}
+
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (!first && '0' <= c && c <= '9')
+}
+
+type transformParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *transformParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos].text
+	}
+	return ""
+}
+
+func (p *transformParser) next() (token, error) {
+	if p.pos >= len(p.toks) {
+		return token{}, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *transformParser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.text != text || t.literal {
+		return fmt.Errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *transformParser) pipe() (evalFunc, error) {
+	first, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	stages := []evalFunc{first}
+	for p.peek() == "|" {
+		p.pos++
+		f, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, f)
+	}
+	if len(stages) == 1 {
+		return first, nil
+	}
+	return func(v any) (any, bool) {
+		for _, f := range stages {
+			var ok bool
+			if v, ok = f(v); !ok {
+				return nil, false
+			}
+		}
+		return v, true
+	}, nil
+}
+
+func (p *transformParser) term() (evalFunc, error) {
+	if p.pos < len(p.toks) && p.toks[p.pos].literal {
+		v := p.toks[p.pos].value
+		p.pos++
+		return func(any) (any, bool) { return v, true }, nil
+	}
+
+	switch p.peek() {
+	case ".":
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, bool) { return lookupPath(v, path), true }, nil
+	case "{":
+		return p.object()
+	case "[":
+		return p.array()
+	case "true", "false", "null":
+		t, _ := p.next()
+		v := map[string]any{"true": true, "false": false, "null": nil}[t.text]
+		return func(any) (any, bool) { return v, true }, nil
+	case "del":
+		return p.del()
+	case "select":
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(v any) (any, bool) { return v, cond(v) }, nil
+	}
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// path parses ".", ".a.b" or ."a b".c into its keys. A path ends at
+// whitespace: ".a .b" is two paths.
+func (p *transformParser) path() ([]string, error) {
+	var keys []string
+	for p.peek() == "." && (len(keys) == 0 || !p.toks[p.pos].spaced) {
+		p.pos++
+		if p.pos >= len(p.toks) || p.toks[p.pos].spaced {
+			if len(keys) > 0 {
+				return nil, fmt.Errorf("expected field after %q", "."+strings.Join(keys, "."))
+			}
+			break
+		}
+		t := p.toks[p.pos]
+		switch {
+		case t.literal:
+			s, ok := t.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid field %s", t.text)
+			}
+			keys = append(keys, s)
+		case isIdentByte(t.text[0], true):
+			keys = append(keys, t.text)
+		default:
+			if len(keys) > 0 {
+				return nil, fmt.Errorf("expected field after %q", "."+strings.Join(keys, "."))
+			}
+			return keys, nil
+		}
+		p.pos++
+	}
+	return keys, nil
+}
+
+func lookupPath(v any, path []string) any {
+	for _, k := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func (p *transformParser) object() (evalFunc, error) {
+	p.pos++ // {
+	var keys []string
+	var vals []evalFunc
+	for p.peek() != "}" {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		key := t.text
+		if t.literal {
+			s, ok := t.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key %s", t.text)
+			}
+			key = s
+		} else if !isIdentByte(key[0], true) {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+
+		var val evalFunc
+		if p.peek() == ":" {
+			p.pos++
+			if val, err = p.pipe(); err != nil {
+				return nil, err
+			}
+		} else {
+			path := []string{key}
+			val = func(v any) (any, bool) { return lookupPath(v, path), true }
+		}
+		keys = append(keys, key)
+		vals = append(vals, val)
+
+		if p.peek() != "}" {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.pos++ // }
+
+	return func(v any) (any, bool) {
+		out := make(map[string]any, len(keys))
+		for i, k := range keys {
+			val, ok := vals[i](v)
+			if !ok {
+				return nil, false
+			}
+			out[k] = val
+		}
+		return out, true
+	}, nil
+}
+
+func (p *transformParser) array() (evalFunc, error) {
+	p.pos++ // [
+	var items []evalFunc
+	for p.peek() != "]" {
+		item, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.peek() != "]" {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.pos++ // ]
+
+	return func(v any) (any, bool) {
+		out := make([]any, 0, len(items))
+		for _, item := range items {
+			if val, ok := item(v); ok {
+				out = append(out, val)
+			}
+		}
+		return out, true
+	}, nil
+}
+
+func (p *transformParser) del() (evalFunc, error) {
+	p.pos++ // del
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var paths [][]string
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return nil, fmt.Errorf("del needs fields")
+		}
+		paths = append(paths, path)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return func(v any) (any, bool) {
+		v = copyValue(v)
+		for _, path := range paths {
+			if m, ok := lookupPath(v, path[:len(path)-1]).(map[string]any); ok {
+				delete(m, path[len(path)-1])
+			}
+		}
+		return v, true
+	}, nil
+}
+
+// copyValue deep-copies the objects and arrays of v, so transformations
+// never modify events shared with other consumers.
+func copyValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = copyValue(e)
+		}
+		return s
+	}
+	return v
+}
+
+func (p *transformParser) or() (func(any) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(v any) bool { return l(v) || right(v) }
+	}
+	return left, nil
+}
+
+func (p *transformParser) and() (func(any) bool, error) {
+	left, err := p.condition()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(v any) bool { return l(v) && right(v) }
+	}
+	return left, nil
+}
+
+// condition parses a comparison of a field with a literal.
+func (p *transformParser) condition() (func(any) bool, error) {
+	if p.peek() != "." {
+		return nil, fmt.Errorf("expected a field to compare, got %q", p.peek())
+	}
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	val, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	switch {
+	case val.literal:
+		value = val.value
+	case val.text == "true" || val.text == "false":
+		value = val.text == "true"
+	case val.text == "null":
+		if op.text != "==" && op.text != "!=" {
+			return nil, fmt.Errorf("null only compares with == and !=")
+		}
+	default:
+		return nil, fmt.Errorf("expected a literal after %s, got %q", op.text, val.text)
+	}
+
+	cmp, err := comparison(op.text, value)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) bool {
+		fv := lookupPath(v, path)
+		if value == nil {
+			return (op.text == "==") == (fv == nil)
+		}
+		return fv != nil && cmp(fv)
+	}, nil
+}
@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pawarpranav83/ig-testing-framework/ig"
+)
+
+func transformInput() map[string]any {
+	return map[string]any{
+		"proc": map[string]any{
+			"comm":  "curl",
+			"pid":   float64(42),
+			"creds": map[string]any{"uid": float64(0), "gid": float64(0)},
+		},
+		"k8s":  map[string]any{"pod name": "web-0", "namespace": "default"},
+		"args": "curl -s example.com",
+		"pid":  float64(7),
+	}
+}
+
+func TestTransformApply(t *testing.T) {
+	tests := []struct {
+		expr string
+		want map[string]any
+		// filtered is set when the expression produces no event.
+		filtered bool
+	}{
+		{expr: ".", want: transformInput()},
+		{expr: ".proc.comm", want: map[string]any{"value": "curl"}},
+		{expr: `."k8s"."pod name"`, want: map[string]any{"value": "web-0"}},
+		{expr: ".missing.field", want: map[string]any{"value": nil}},
+		{expr: ".args.nested", want: map[string]any{"value": nil}},
+		{expr: ".proc | .creds | .uid", want: map[string]any{"value": float64(0)}},
+		{expr: ".proc | .comm | .x", want: map[string]any{"value": nil}},
+		{expr: `"s"`, want: map[string]any{"value": "s"}},
+		{expr: "-1.5e1", want: map[string]any{"value": float64(-15)}},
+		{expr: "true", want: map[string]any{"value": true}},
+		{expr: "null", want: map[string]any{"value": nil}},
+		{expr: "[.proc.pid, .pid, 1]", want: map[string]any{"value": []any{float64(42), float64(7), float64(1)}}},
+		{expr: "[]", want: map[string]any{"value": []any{}}},
+		{expr: "{}", want: map[string]any{}},
+		{
+			expr: `{comm: .proc.comm, pid, "pod": ."k8s"."pod name"}`,
+			want: map[string]any{"comm": "curl", "pid": float64(7), "pod": "web-0"},
+		},
+		{
+			expr: "{id: {pid: .proc.pid, uid: .proc.creds.uid}, tags: [.k8s.namespace]}",
+			want: map[string]any{
+				"id":   map[string]any{"pid": float64(42), "uid": float64(0)},
+				"tags": []any{"default"},
+			},
+		},
+		{expr: "{pid: .proc | .pid}", want: map[string]any{"pid": float64(42)}},
+		{
+			expr: "del(.proc, .k8s.namespace)",
+			want: map[string]any{"k8s": map[string]any{"pod name": "web-0"}, "args": "curl -s example.com", "pid": float64(7)},
+		},
+		{expr: "del(.missing.field) | .pid", want: map[string]any{"value": float64(7)}},
+		{expr: `select(.proc.comm == "curl") | .pid`, want: map[string]any{"value": float64(7)}},
+		{expr: `select(.proc.comm == "wget")`, filtered: true},
+		{expr: `select(.proc.comm != "wget") | .proc.comm`, want: map[string]any{"value": "curl"}},
+		{expr: "select(.proc.pid > 40) | .pid", want: map[string]any{"value": float64(7)}},
+		{expr: "select(.proc.pid >= 43)", filtered: true},
+		{expr: "select(.proc.pid < 43 and .proc.pid <= 42) | .pid", want: map[string]any{"value": float64(7)}},
+		{expr: `select(.proc.comm ~ "^cu") | .pid`, want: map[string]any{"value": float64(7)}},
+		{expr: `select(.proc.comm ~ "^wg")`, filtered: true},
+		{expr: "select(.missing == null) | .pid", want: map[string]any{"value": float64(7)}},
+		{expr: "select(.missing != null)", filtered: true},
+		{expr: "select(.missing > 1)", filtered: true},
+		{expr: "select(.proc.creds.uid == 0 and .proc.comm == true)", filtered: true},
+		// "and" binds tighter than "or": false or (true and true).
+		{expr: `select(.pid == 1 or .pid == 7 and .proc.comm == "curl") | .pid`, want: map[string]any{"value": float64(7)}},
+		// (true or false) and false would filter; true or (false and false)
+		// doesn't.
+		{expr: `select(.pid == 7 or .pid == 1 and .pid == 2) | .pid`, want: map[string]any{"value": float64(7)}},
+		{expr: `{comm: .proc.comm, root: select(.proc.creds.uid == 1)}`, filtered: true},
+		{expr: ".proc | select(.pid == 42) | {comm}", want: map[string]any{"comm": "curl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			tr, err := CompileTransform(tt.expr)
+			if err != nil {
+				t.Fatalf("CompileTransform: %v", err)
+			}
+			in := transformInput()
+			got, ok := tr.Apply(in)
+			if ok == tt.filtered {
+				t.Fatalf("Apply kept the event: %t, want %t (got %v)", ok, !tt.filtered, got)
+			}
+			if !tt.filtered && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply = %#v, want %#v", got, tt.want)
+			}
+			if !reflect.DeepEqual(in, transformInput()) {
+				t.Errorf("Apply modified its input: %#v", in)
+			}
+		})
+	}
+}
+
+func TestCompileTransformErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "", err: "unexpected end of expression"},
+		{expr: ".a |", err: "unexpected end of expression"},
+		{expr: "| .a", err: `unexpected "|"`},
+		{expr: ".a .b", err: `unexpected "."`},
+		{expr: ".a. b", err: `expected field after ".a"`},
+		{expr: ".a.", err: `expected field after ".a"`},
+		{expr: `."a`, err: "unterminated string"},
+		{expr: `"\q"`, err: "invalid string"},
+		{expr: "1.2.3", err: "invalid number"},
+		{expr: ".a ; .b", err: `unexpected ';'`},
+		{expr: "{a: .a", err: "unexpected end of expression"},
+		{expr: "{a: .a b: .b}", err: `expected ",", got "b"`},
+		{expr: "{1: .a}", err: "invalid key 1"},
+		{expr: "{.a}", err: `invalid key "."`},
+		{expr: "[.a .b]", err: `expected ",", got "."`},
+		{expr: "del()", err: "del needs fields"},
+		{expr: "del(.a", err: "unexpected end of expression"},
+		{expr: "del .a", err: `expected "(", got "."`},
+		{expr: "select(.a)", err: "unexpected end of expression"},
+		{expr: `select("a" == .a)`, err: "expected a field to compare"},
+		{expr: "select(.a == .b)", err: "expected a literal after =="},
+		{expr: "select(.a < null)", err: "null only compares with == and !="},
+		{expr: `select(.a ~ "(")`, err: "invalid regexp"},
+		{expr: "select(.a = 1)", err: `unexpected '='`},
+		{expr: "select(.a : 1)", err: `unknown operator ":"`},
+		{expr: "select(.a == 1", err: "unexpected end of expression"},
+		{expr: "map(.a)", err: `unexpected "map"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := CompileTransform(tt.expr)
+			if err == nil {
+				t.Fatal("CompileTransform succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("CompileTransform: %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTransformStage(t *testing.T) {
+	in := make(chan ig.Event, 3)
+	in <- ig.Event{Raw: `{"comm":"curl","pid":1}`, Fields: map[string]any{"comm": "curl", "pid": float64(1)}}
+	in <- ig.Event{Raw: "not json"}
+	in <- ig.Event{Raw: `{"comm":"wget","pid":2}`, Fields: map[string]any{"comm": "wget", "pid": float64(2)}}
+	close(in)
+
+	out, err := Transform(in, `select(.comm == "curl") | {pid}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raws []string
+	for ev := range out {
+		raws = append(raws, ev.Raw)
+	}
+	want := []string{`{"pid":1}`, "not json"}
+	if !reflect.DeepEqual(raws, want) {
+		t.Errorf("Stage events = %q, want %q", raws, want)
+	}
+}
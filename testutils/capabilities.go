@@ -0,0 +1,125 @@
+package testutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// CapabilityProbe is a busybox command whose success depends on a single
+// capability, so trace_capabilities and seccomp advise tests get
+// deterministic denials and grants to assert on.
+type CapabilityProbe struct {
+	// Capability checked by the kernel, e.g. "CAP_SYS_ADMIN".
+	Capability string
+	// Syscall that performs the check.
+	Syscall string
+	// Command is the shell command exercising Syscall.
+	Command string
+}
+
+// Probes shipped with the package. Each one only needs busybox and leaves no
+// trace outside the container it runs in.
+var (
+	ProbeMount = CapabilityProbe{
+		Capability: "CAP_SYS_ADMIN",
+		Syscall:    "mount",
+		Command:    "mkdir -p /tmp/ig-mnt && mount -t tmpfs ig-test /tmp/ig-mnt && umount /tmp/ig-mnt",
+	}
+	ProbeSetns = CapabilityProbe{
+		Capability: "CAP_SYS_ADMIN",
+		Syscall:    "setns",
+		Command:    "nsenter --net=/proc/1/ns/net true",
+	}
+	ProbeChown = CapabilityProbe{
+		Capability: "CAP_CHOWN",
+		Syscall:    "fchownat",
+		Command:    "touch /tmp/ig-cap-chown && chown 1234:1234 /tmp/ig-cap-chown",
+	}
+	ProbeMknod = CapabilityProbe{
+		Capability: "CAP_MKNOD",
+		Syscall:    "mknodat",
+		Command:    "rm -f /tmp/ig-cap-null && mknod /tmp/ig-cap-null c 1 3",
+	}
+	ProbeDACOverride = CapabilityProbe{
+		Capability: "CAP_DAC_OVERRIDE",
+		Syscall:    "openat",
+		Command:    "touch /tmp/ig-cap-dac && chmod 000 /tmp/ig-cap-dac && echo x > /tmp/ig-cap-dac",
+	}
+	ProbeChroot = CapabilityProbe{
+		Capability: "CAP_SYS_CHROOT",
+		Syscall:    "chroot",
+		Command:    "chroot / true",
+	}
+)
+
+// CapabilityProbes lists every probe shipped with the package.
+var CapabilityProbes = []CapabilityProbe{
+	ProbeMount,
+	ProbeSetns,
+	ProbeChown,
+	ProbeMknod,
+	ProbeDACOverride,
+	ProbeChroot,
+}
+
+// ProbeResult is the outcome of running a CapabilityProbe.
+type ProbeResult struct {
+	Probe CapabilityProbe
+	// Allowed reports whether the command succeeded.
+	Allowed bool
+	// Output is the combined stdout and stderr of the command.
+	Output string
+}
+
+// RunIn runs the probe inside c. A denied operation is not an error; err is
+// only set if the command couldn't be run at all.
+func (p CapabilityProbe) RunIn(ctx context.Context, c *TestContainer) (ProbeResult, error) {
+	res := ProbeResult{Probe: p}
+
+	out, err := exec.CommandContext(ctx, string(c.runtime), "exec", c.ID, "sh", "-c", p.Command).CombinedOutput()
+	res.Output = string(out)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.Allowed = true
+	case errors.As(err, &exitErr):
+		// The runtime CLI propagates the exit code of the command.
+	default:
+		return res, fmt.Errorf("running probe %s in container %s: %w", p.Syscall, c.Name, err)
+	}
+
+	return res, nil
+}
+
+// RestrictedContainerOptions configures StartRestrictedTestContainer.
+type RestrictedContainerOptions struct {
+	// Runtime selects the container runtime CLI. Detected if empty.
+	Runtime Runtime
+	// Capabilities granted to the container after dropping all of them,
+	// e.g. "CAP_SYS_ADMIN" or "SYS_ADMIN".
+	Capabilities []string
+	// Seccomp is passed as "--security-opt seccomp=<Seccomp>": a profile
+	// path or "unconfined". The runtime's default profile is used if empty.
+	Seccomp string
+}
+
+// StartRestrictedTestContainer starts a busybox container with every
+// capability dropped except opts.Capabilities, so probes are denied or
+// granted deterministically. Callers must remove it with RemoveTestContainer.
+func StartRestrictedTestContainer(ctx context.Context, opts RestrictedContainerOptions) (*TestContainer, error) {
+	args := []string{"--cap-drop", "ALL"}
+	for _, c := range opts.Capabilities {
+		args = append(args, "--cap-add", c)
+	}
+	if opts.Seccomp != "" {
+		args = append(args, "--security-opt", "seccomp="+opts.Seccomp)
+	}
+
+	return StartTestContainer(ctx, ContainerOptions{
+		Runtime: opts.Runtime,
+		Args:    args,
+	})
+}
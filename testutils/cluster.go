@@ -0,0 +1,141 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ClusterProvider is the tool used to provision a local Kubernetes cluster.
+type ClusterProvider string
+
+const (
+	Kind     ClusterProvider = "kind"
+	Minikube ClusterProvider = "minikube"
+)
+
+// Cluster is a disposable local Kubernetes cluster for end-to-end tests of
+// cluster gadgets driven through kubectl-gadget.
+type Cluster struct {
+	// Name is the cluster (kind) or profile (minikube) name.
+	Name string
+	// Provider is the tool that created the cluster.
+	Provider ClusterProvider
+	// Kubeconfig is the path of a kubeconfig file dedicated to this cluster.
+	Kubeconfig string
+	// Context is the kubeconfig context selecting this cluster.
+	Context string
+
+	dir string
+}
+
+type clusterConfig struct {
+	name     string
+	provider ClusterProvider
+	args     []string
+}
+
+// ClusterOption configures CreateCluster.
+type ClusterOption func(*clusterConfig)
+
+// WithClusterName sets the cluster name instead of generating a random one.
+func WithClusterName(name string) ClusterOption {
+	return func(c *clusterConfig) {
+		c.name = name
+	}
+}
+
+// WithClusterProvider selects kind (the default) or minikube.
+func WithClusterProvider(p ClusterProvider) ClusterOption {
+	return func(c *clusterConfig) {
+		c.provider = p
+	}
+}
+
+// WithClusterArgs passes extra arguments to "kind create cluster" or
+// "minikube start", e.g. a node image or Kubernetes version.
+func WithClusterArgs(args ...string) ClusterOption {
+	return func(c *clusterConfig) {
+		c.args = append(c.args, args...)
+	}
+}
+
+// CreateCluster provisions a new cluster and writes its credentials to a
+// private kubeconfig file, leaving the user's kubeconfig untouched. Callers
+// must Delete it.
+func CreateCluster(ctx context.Context, opts ...ClusterOption) (*Cluster, error) {
+	cfg := clusterConfig{
+		name:     RandomName("ig-test"),
+		provider: Kind,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	dir, err := os.MkdirTemp("", "ig-cluster-")
+	if err != nil {
+		return nil, fmt.Errorf("creating kubeconfig dir: %w", err)
+	}
+
+	c := &Cluster{
+		Name:       cfg.name,
+		Provider:   cfg.provider,
+		Kubeconfig: filepath.Join(dir, "kubeconfig"),
+		dir:        dir,
+	}
+
+	switch cfg.provider {
+	case Kind:
+		c.Context = "kind-" + c.Name
+		args := append([]string{"create", "cluster", "--name", c.Name, "--kubeconfig", c.Kubeconfig, "--wait", "5m"}, cfg.args...)
+		_, err = run(ctx, "kind", args...)
+	case Minikube:
+		c.Context = c.Name
+		args := append([]string{"start", "--profile", c.Name, "--wait", "all"}, cfg.args...)
+		_, err = runWithEnv(ctx, c.Env(), "minikube", args...)
+	default:
+		err = fmt.Errorf("unknown cluster provider %q", cfg.provider)
+	}
+	if err != nil {
+		c.Delete(context.Background())
+		return nil, fmt.Errorf("creating cluster %s: %w", c.Name, err)
+	}
+
+	return c, nil
+}
+
+// Env returns the environment variables that point kubectl and kubectl-gadget
+// at this cluster.
+func (c *Cluster) Env() []string {
+	return []string{"KUBECONFIG=" + c.Kubeconfig}
+}
+
+// KubectlArgs returns the global flags that point kubectl and kubectl-gadget
+// at this cluster, for callers that can't change the environment.
+func (c *Cluster) KubectlArgs() []string {
+	return []string{"--kubeconfig", c.Kubeconfig, "--context", c.Context}
+}
+
+// Delete destroys the cluster and removes its kubeconfig.
+func (c *Cluster) Delete(ctx context.Context) error {
+	var err error
+	switch c.Provider {
+	case Kind:
+		_, err = run(ctx, "kind", "delete", "cluster", "--name", c.Name, "--kubeconfig", c.Kubeconfig)
+	case Minikube:
+		_, err = runWithEnv(ctx, c.Env(), "minikube", "delete", "--profile", c.Name)
+	}
+	dir := c.dir
+	if dir == "" && c.Kubeconfig != "" {
+		// Decoded from JSON, e.g. by a shared fixture.
+		dir = filepath.Dir(c.Kubeconfig)
+	}
+	if rmErr := os.RemoveAll(dir); err == nil && rmErr != nil {
+		err = rmErr
+	}
+	if err != nil {
+		return fmt.Errorf("deleting cluster %s: %w", c.Name, err)
+	}
+	return nil
+}
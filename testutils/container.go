@@ -0,0 +1,159 @@
+package testutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// Images commonly used as gadget test workloads.
+const (
+	BusyboxImage = "docker.io/library/busybox:latest"
+	NginxImage   = "docker.io/library/nginx:latest"
+)
+
+// ContainerOptions configures StartTestContainer.
+type ContainerOptions struct {
+	// Name of the container. A random "ig-test-" name is used if empty.
+	Name string
+	// Image to run. Defaults to BusyboxImage.
+	Image string
+	// Command is a shell script run with "sh -c" as the container process.
+	// If empty, the image's default command is used, except for the default
+	// busybox image which sleeps forever so the container stays around for
+	// Exec.
+	Command string
+	// Runtime selects the container runtime CLI. Detected if empty.
+	Runtime Runtime
+	// Privileged runs the container with all capabilities.
+	Privileged bool
+	// Args are extra arguments passed to "<runtime> run" before the image,
+	// e.g. "--cap-add", "NET_ADMIN" or "--publish", "127.0.0.1::80".
+	Args []string
+}
+
+// TestContainer is a container started by StartTestContainer.
+type TestContainer struct {
+	ID    string
+	Name  string
+	Image string
+
+	runtime Runtime
+}
+
+// StartTestContainer starts a detached container and returns once the runtime
+// reports it started. Callers must remove it with RemoveTestContainer.
+func StartTestContainer(ctx context.Context, opts ContainerOptions) (*TestContainer, error) {
+	if opts.Runtime == "" {
+		rt, err := DetectRuntime()
+		if err != nil {
+			return nil, err
+		}
+		opts.Runtime = rt
+	}
+	if opts.Name == "" {
+		opts.Name = RandomName("ig-test")
+	}
+	if opts.Image == "" {
+		opts.Image = BusyboxImage
+		if opts.Command == "" {
+			opts.Command = "sleep inf"
+		}
+	}
+
+	args := []string{"run", "--detach", "--name", opts.Name}
+	if opts.Privileged {
+		args = append(args, "--privileged")
+	}
+	args = append(args, opts.Args...)
+	args = append(args, opts.Image)
+	if opts.Command != "" {
+		args = append(args, "sh", "-c", opts.Command)
+	}
+
+	id, err := opts.Runtime.run(ctx, args...)
+	if err != nil {
+		// The container may have been created even though it failed to start.
+		opts.Runtime.run(context.Background(), "rm", "--force", opts.Name)
+		return nil, fmt.Errorf("starting container %s: %w", opts.Name, err)
+	}
+
+	return &TestContainer{
+		ID:      id,
+		Name:    opts.Name,
+		Image:   opts.Image,
+		runtime: opts.Runtime,
+	}, nil
+}
+
+// RemoveTestContainer force-removes c, stopping it if needed.
+func RemoveTestContainer(ctx context.Context, c *TestContainer) error {
+	if _, err := c.runtime.run(ctx, "rm", "--force", c.ID); err != nil {
+		return fmt.Errorf("removing container %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+// Exec runs argv inside the container and returns its stdout.
+func (c *TestContainer) Exec(ctx context.Context, argv ...string) (string, error) {
+	out, err := c.runtime.run(ctx, append([]string{"exec", c.ID}, argv...)...)
+	if err != nil {
+		return "", fmt.Errorf("exec in container %s: %w", c.Name, err)
+	}
+	return out, nil
+}
+
+// ExecShell runs script with "sh -c" inside the container and returns its
+// stdout.
+func (c *TestContainer) ExecShell(ctx context.Context, script string) (string, error) {
+	return c.Exec(ctx, "sh", "-c", script)
+}
+
+// PID returns the host PID of the container's init process.
+func (c *TestContainer) PID(ctx context.Context) (int, error) {
+	out, err := c.runtime.run(ctx, "inspect", "--format", "{{.State.Pid}}", c.ID)
+	if err != nil {
+		return 0, fmt.Errorf("inspecting container %s: %w", c.Name, err)
+	}
+	pid, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("parsing pid of container %s: %w", c.Name, err)
+	}
+	return pid, nil
+}
+
+// Wait blocks until the container exits and returns its exit code.
+func (c *TestContainer) Wait(ctx context.Context) (int, error) {
+	out, err := c.runtime.run(ctx, "wait", c.ID)
+	if err != nil {
+		return 0, fmt.Errorf("waiting for container %s: %w", c.Name, err)
+	}
+	code, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("parsing exit code of container %s: %w", c.Name, err)
+	}
+	return code, nil
+}
+
+// OOMKilled reports whether the runtime saw the container's process killed
+// by the OOM killer.
+func (c *TestContainer) OOMKilled(ctx context.Context) (bool, error) {
+	out, err := c.runtime.run(ctx, "inspect", "--format", "{{.State.OOMKilled}}", c.ID)
+	if err != nil {
+		return false, fmt.Errorf("inspecting container %s: %w", c.Name, err)
+	}
+	return strconv.ParseBool(out)
+}
+
+// Logs returns the combined output of the container process so far. The
+// runtime replays the container's stderr on its own stderr, so both streams
+// are captured, interleaved as the runtime wrote them.
+func (c *TestContainer) Logs(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, string(c.runtime), "logs", c.ID).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("getting logs of container %s: %w: %s", c.Name, err, bytes.TrimSpace(out))
+	}
+	return string(out), nil
+}
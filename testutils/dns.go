@@ -0,0 +1,262 @@
+package testutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNS query types served by DNSServer.
+const (
+	DNSTypeA    uint16 = 1
+	DNSTypeAAAA uint16 = 28
+)
+
+const (
+	dnsClassIN    = 1
+	dnsRcodeOK    = 0
+	dnsRcodeFmt   = 1
+	dnsRcodeNX    = 3
+	dnsHeaderSize = 12
+)
+
+// DNSServerOptions configures StartDNSServer.
+type DNSServerOptions struct {
+	// Addr is the UDP address to listen on. Defaults to "127.0.0.1:0", a
+	// random loopback port. Containers using busybox nslookup can't pick a
+	// port, so exposing the server to them requires port 53.
+	Addr string
+	// Records maps names (with or without trailing dot) to the IPv4 and IPv6
+	// addresses returned for A and AAAA queries. Names are case-insensitive.
+	// Unknown names are answered with NXDOMAIN.
+	Records map[string][]string
+	// TTL of returned records. Defaults to 60s.
+	TTL time.Duration
+}
+
+// DNSQuery is a query received by DNSServer.
+type DNSQuery struct {
+	Name string
+	Type uint16
+	From net.Addr
+	Time time.Time
+}
+
+// DNSServer is an embedded authoritative DNS server for hermetic trace_dns
+// tests. It answers A and AAAA queries over UDP from a fixed record set and
+// logs every query it receives.
+type DNSServer struct {
+	// Addr is the address the server listens on.
+	Addr string
+
+	conn    net.PacketConn
+	ttl     uint32
+	records map[string][]net.IP
+	done    chan struct{}
+
+	mu      sync.Mutex
+	queries []DNSQuery
+}
+
+// StartDNSServer starts serving in the background. Callers must Close it.
+func StartDNSServer(opts DNSServerOptions) (*DNSServer, error) {
+	if opts.Addr == "" {
+		opts.Addr = "127.0.0.1:0"
+	}
+	if opts.TTL == 0 {
+		opts.TTL = time.Minute
+	}
+
+	records := make(map[string][]net.IP, len(opts.Records))
+	for name, addrs := range opts.Records {
+		for _, a := range addrs {
+			ip := net.ParseIP(a)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q for %s", a, name)
+			}
+			key := canonicalDNSName(name)
+			records[key] = append(records[key], ip)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp", opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", opts.Addr, err)
+	}
+
+	s := &DNSServer{
+		Addr:    conn.LocalAddr().String(),
+		conn:    conn,
+		ttl:     uint32(opts.TTL / time.Second),
+		records: records,
+		done:    make(chan struct{}),
+	}
+	go s.serve()
+
+	return s, nil
+}
+
+// Queries returns every query received so far, in arrival order.
+func (s *DNSServer) Queries() []DNSQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]DNSQuery(nil), s.queries...)
+}
+
+// QueriesFor returns the queries received so far for name.
+func (s *DNSServer) QueriesFor(name string) []DNSQuery {
+	name = canonicalDNSName(name)
+
+	var out []DNSQuery
+	for _, q := range s.Queries() {
+		if q.Name == name {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// Close stops the server.
+func (s *DNSServer) Close() error {
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+func (s *DNSServer) serve() {
+	defer close(s.done)
+
+	buf := make([]byte, 512)
+	for {
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		resp := s.handle(buf[:n], from)
+		if resp != nil {
+			s.conn.WriteTo(resp, from)
+		}
+	}
+}
+
+func (s *DNSServer) handle(msg []byte, from net.Addr) []byte {
+	if len(msg) < dnsHeaderSize {
+		return nil
+	}
+	id := binary.BigEndian.Uint16(msg[0:2])
+	flags := binary.BigEndian.Uint16(msg[2:4])
+	if flags&0x8000 != 0 {
+		// Not a query.
+		return nil
+	}
+
+	name, qtype, qclass, qend, err := parseDNSQuestion(msg)
+	if err != nil || binary.BigEndian.Uint16(msg[4:6]) != 1 {
+		return dnsResponse(id, flags, dnsRcodeFmt, nil, nil)
+	}
+
+	s.mu.Lock()
+	s.queries = append(s.queries, DNSQuery{Name: name, Type: qtype, From: from, Time: time.Now()})
+	s.mu.Unlock()
+
+	question := msg[dnsHeaderSize:qend]
+	ips, ok := s.records[name]
+	if !ok || qclass != dnsClassIN {
+		return dnsResponse(id, flags, dnsRcodeNX, question, nil)
+	}
+
+	var answers [][]byte
+	for _, ip := range ips {
+		var rdata []byte
+		switch {
+		case qtype == DNSTypeA && ip.To4() != nil:
+			rdata = ip.To4()
+		case qtype == DNSTypeAAAA && ip.To4() == nil:
+			rdata = ip.To16()
+		default:
+			continue
+		}
+		answers = append(answers, dnsAnswer(qtype, s.ttl, rdata))
+	}
+
+	return dnsResponse(id, flags, dnsRcodeOK, question, answers)
+}
+
+// parseDNSQuestion parses the first question of msg, returning the offset
+// just past it.
+func parseDNSQuestion(msg []byte) (name string, qtype, qclass uint16, end int, err error) {
+	var labels []string
+
+	off := dnsHeaderSize
+	for {
+		if off >= len(msg) {
+			return "", 0, 0, 0, errors.New("truncated name")
+		}
+		l := int(msg[off])
+		off++
+		if l == 0 {
+			break
+		}
+		if l&0xc0 != 0 {
+			// Compression pointers never appear in the question of a query.
+			return "", 0, 0, 0, errors.New("unexpected compression pointer")
+		}
+		if off+l > len(msg) {
+			return "", 0, 0, 0, errors.New("truncated label")
+		}
+		labels = append(labels, string(msg[off:off+l]))
+		off += l
+	}
+	if off+4 > len(msg) {
+		return "", 0, 0, 0, errors.New("truncated question")
+	}
+
+	qtype = binary.BigEndian.Uint16(msg[off : off+2])
+	qclass = binary.BigEndian.Uint16(msg[off+2 : off+4])
+
+	return canonicalDNSName(strings.Join(labels, ".")), qtype, qclass, off + 4, nil
+}
+
+func dnsAnswer(qtype uint16, ttl uint32, rdata []byte) []byte {
+	rr := make([]byte, 12, 12+len(rdata))
+	// Pointer to the name in the question, which always starts right after
+	// the header.
+	binary.BigEndian.PutUint16(rr[0:2], 0xc000|dnsHeaderSize)
+	binary.BigEndian.PutUint16(rr[2:4], qtype)
+	binary.BigEndian.PutUint16(rr[4:6], dnsClassIN)
+	binary.BigEndian.PutUint32(rr[6:10], ttl)
+	binary.BigEndian.PutUint16(rr[10:12], uint16(len(rdata)))
+	return append(rr, rdata...)
+}
+
+func dnsResponse(id, qflags, rcode uint16, question []byte, answers [][]byte) []byte {
+	// QR and AA set; opcode and RD copied from the query.
+	flags := uint16(0x8000|0x0400) | qflags&0x7900 | rcode
+
+	msg := make([]byte, dnsHeaderSize, 512)
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	binary.BigEndian.PutUint16(msg[2:4], flags)
+	if question != nil {
+		binary.BigEndian.PutUint16(msg[4:6], 1)
+	}
+	binary.BigEndian.PutUint16(msg[6:8], uint16(len(answers)))
+
+	msg = append(msg, question...)
+	for _, a := range answers {
+		msg = append(msg, a...)
+	}
+	return msg
+}
+
+func canonicalDNSName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
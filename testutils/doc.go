@@ -0,0 +1,8 @@
+// Package testutils provides the workload half of gadget integration tests:
+// disposable registries, containers and traffic generators that a test can
+// start, point a gadget at, and tear down again.
+//
+// Helpers shell out to the container runtime CLI found on the host (docker,
+// podman or nerdctl), so they work the same on developer machines and CI
+// runners without linking against any runtime client library.
+package testutils
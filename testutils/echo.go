@@ -0,0 +1,191 @@
+package testutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Peer is a remote endpoint that connected (TCP) or sent a datagram (UDP) to
+// an EchoServer.
+type Peer struct {
+	Addr net.Addr
+	Time time.Time
+}
+
+// EchoServer echoes back whatever it receives and records its peers, so
+// trace_tcpconnect/trace_udp events can be matched against sockets the test
+// controls.
+type EchoServer struct {
+	// Network is "tcp" or "udp".
+	Network string
+	// Addr is the address the server listens on.
+	Addr string
+
+	closer io.Closer
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	peers  []Peer
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// StartTCPEchoServer listens on addr ("127.0.0.1:0" for a random loopback
+// port if empty) and echoes every accepted connection. Callers must Close it.
+func StartTCPEchoServer(addr string) (*EchoServer, error) {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on tcp %s: %w", addr, err)
+	}
+
+	s := &EchoServer{
+		Network: "tcp",
+		Addr:    l.Addr().String(),
+		closer:  l,
+		conns:   make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.acceptLoop(l)
+
+	return s, nil
+}
+
+// StartUDPEchoServer listens on addr ("127.0.0.1:0" for a random loopback
+// port if empty) and echoes every datagram back to its sender. Callers must
+// Close it.
+func StartUDPEchoServer(addr string) (*EchoServer, error) {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on udp %s: %w", addr, err)
+	}
+
+	s := &EchoServer{
+		Network: "udp",
+		Addr:    conn.LocalAddr().String(),
+		closer:  conn,
+	}
+	s.wg.Add(1)
+	go s.packetLoop(conn)
+
+	return s, nil
+}
+
+// Port returns the port the server listens on.
+func (s *EchoServer) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// Peers returns the peers seen so far, in arrival order. UDP peers are
+// recorded once per datagram.
+func (s *EchoServer) Peers() []Peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Peer(nil), s.peers...)
+}
+
+// Close stops the server and closes every open connection.
+func (s *EchoServer) Close() error {
+	err := s.closer.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *EchoServer) record(addr net.Addr) {
+	s.mu.Lock()
+	s.peers = append(s.peers, Peer{Addr: addr, Time: time.Now()})
+	s.mu.Unlock()
+}
+
+// retryDelay returns how long to wait before retrying after a failure that
+// followed one of delay, doubling from 5ms up to 1s like net/http does, so a
+// persistent error such as EMFILE doesn't spin.
+func retryDelay(delay time.Duration) time.Duration {
+	if delay == 0 {
+		return 5 * time.Millisecond
+	}
+	return min(2*delay, time.Second)
+}
+
+func (s *EchoServer) acceptLoop(l net.Listener) {
+	defer s.wg.Done()
+
+	var delay time.Duration
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			delay = retryDelay(delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+
+		s.record(c.RemoteAddr())
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			io.Copy(c, c)
+			c.Close()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *EchoServer) packetLoop(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 64*1024)
+	var delay time.Duration
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			delay = retryDelay(delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+
+		s.record(from)
+		conn.WriteTo(buf[:n], from)
+	}
+}
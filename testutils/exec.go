@@ -0,0 +1,40 @@
+package testutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// run executes name with args and returns its trimmed stdout. On failure the
+// returned error includes stderr so test failures are self-explanatory.
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	return runWithEnv(ctx, nil, name, args...)
+}
+
+// runWithEnv is like run but appends env to the inherited environment.
+func runWithEnv(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s %s: %w: %s",
+			name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// shellQuote quotes s for safe interpolation into a POSIX shell script.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,77 @@
+package testutils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FileActivity describes the files touched by a filesystem workload. Every
+// file is created and written, reopened read-only and read, then deleted, in
+// that order.
+type FileActivity struct {
+	Nonce Nonce
+	// Dir is the directory holding the files.
+	Dir string
+	// Files are the exact paths touched, in the order they were touched.
+	Files []string
+}
+
+// GenerateFileActivity touches count nonce-named files under dir from the
+// calling process. If dir is empty a fresh temporary directory is used and
+// removed afterwards.
+func GenerateFileActivity(dir string, n Nonce, count int) (*FileActivity, error) {
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "ig-fs-")
+		if err != nil {
+			return nil, fmt.Errorf("creating temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	a := &FileActivity{Nonce: n, Dir: dir}
+	for i := 0; i < count; i++ {
+		p := filepath.Join(dir, n.FileName(i))
+		if err := touchFile(p); err != nil {
+			return a, err
+		}
+		a.Files = append(a.Files, p)
+	}
+
+	return a, nil
+}
+
+func touchFile(p string) error {
+	if err := os.WriteFile(p, []byte("ig-test\n"), 0o644); err != nil {
+		return fmt.Errorf("creating %s: %w", p, err)
+	}
+	if _, err := os.ReadFile(p); err != nil {
+		return fmt.Errorf("reading %s: %w", p, err)
+	}
+	if err := os.Remove(p); err != nil {
+		return fmt.Errorf("removing %s: %w", p, err)
+	}
+	return nil
+}
+
+// FileActivityWorkload returns a workload performing the same operations as
+// GenerateFileActivity under dir ("/tmp" if empty), for running inside a test
+// container. Its FileActivity reports the paths as seen in the container.
+func FileActivityWorkload(n Nonce, dir string, count int) (Workload, *FileActivity) {
+	if dir == "" {
+		dir = "/tmp"
+	}
+
+	a := &FileActivity{Nonce: n, Dir: dir}
+	for i := 0; i < count; i++ {
+		a.Files = append(a.Files, path.Join(dir, n.FileName(i)))
+	}
+
+	script := fmt.Sprintf(`mkdir -p %s
+for i in $(seq 0 %d); do f=%s/%s-$i; echo ig-test > "$f" && cat "$f" >/dev/null && rm "$f"; done`,
+		shellQuote(dir), count-1, shellQuote(dir), shellQuote("ig-"+string(n)))
+
+	return Workload{Nonce: n, Script: script}, a
+}
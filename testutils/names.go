@@ -0,0 +1,10 @@
+package testutils
+
+import "fmt"
+
+// RandomName returns prefix followed by a short random suffix, suitable for
+// naming containers and clusters that must not collide across test runs. The
+// suffix derives from GetSeed.
+func RandomName(prefix string) string {
+	return fmt.Sprintf("%s-%08x", prefix, randUint32())
+}
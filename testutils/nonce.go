@@ -0,0 +1,62 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// NonceEnv is the environment variable workloads get their nonce in, so
+// custom scripts can embed it in their activity, e.g.
+// touch "/tmp/$IG_TEST_NONCE".
+const NonceEnv = "IG_TEST_NONCE"
+
+// Nonce is a per-test marker embedded in generated activity so assertions can
+// pick out exactly the events a test caused amid background noise. It is
+// lowercase hex, which keeps it valid in DNS labels, paths and process names.
+type Nonce string
+
+// NewNonce returns a fresh random nonce, derived from GetSeed.
+func NewNonce() Nonce {
+	return Nonce(fmt.Sprintf("%08x", randUint32()))
+}
+
+func (n Nonce) String() string {
+	return string(n)
+}
+
+// Domain returns a unique name under zone, e.g. "1a2b3c4d.ig-test.local".
+func (n Nonce) Domain(zone string) string {
+	return string(n) + "." + strings.TrimSuffix(zone, ".")
+}
+
+// Path returns a unique URL path, e.g. "/ig-1a2b3c4d".
+func (n Nonce) Path() string {
+	return "/ig-" + string(n)
+}
+
+// BinaryName returns a unique executable name. It fits in the 15 characters
+// the kernel keeps of a task's comm, so it matches comm fields verbatim.
+func (n Nonce) BinaryName() string {
+	return "ig-" + string(n)
+}
+
+// FileName returns the i-th unique file name, e.g. "ig-1a2b3c4d-0".
+func (n Nonce) FileName(i int) string {
+	return fmt.Sprintf("ig-%s-%d", n, i)
+}
+
+type nonceKey struct{}
+
+// WithNonce returns a copy of ctx carrying n, so the code generating the
+// activity of a test and the code checking its events share one nonce, e.g.
+// the workload passed to ig.RunAround and the assertions after it.
+func WithNonce(ctx context.Context, n Nonce) context.Context {
+	return context.WithValue(ctx, nonceKey{}, n)
+}
+
+// NonceFromContext returns the nonce carried by ctx, if any.
+func NonceFromContext(ctx context.Context) (Nonce, bool) {
+	n, ok := ctx.Value(nonceKey{}).(Nonce)
+	return n, ok
+}
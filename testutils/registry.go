@@ -0,0 +1,136 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultRegistryImage is the image used by StartRegistry.
+const DefaultRegistryImage = "docker.io/library/registry:2"
+
+// Registry is a disposable OCI registry running in a local container. It
+// serves plain HTTP on the loopback interface and needs no credentials, so
+// image push/pull tests don't depend on external registries.
+type Registry struct {
+	// Address is the host:port the registry listens on.
+	Address string
+
+	container *TestContainer
+}
+
+type registryConfig struct {
+	image   string
+	runtime Runtime
+	timeout time.Duration
+}
+
+// RegistryOption configures StartRegistry.
+type RegistryOption func(*registryConfig)
+
+// WithRegistryImage overrides the registry image to run.
+func WithRegistryImage(image string) RegistryOption {
+	return func(c *registryConfig) {
+		c.image = image
+	}
+}
+
+// WithRegistryRuntime selects the container runtime CLI instead of detecting
+// one.
+func WithRegistryRuntime(rt Runtime) RegistryOption {
+	return func(c *registryConfig) {
+		c.runtime = rt
+	}
+}
+
+// WithRegistryStartTimeout bounds how long StartRegistry waits for the
+// registry to answer requests.
+func WithRegistryStartTimeout(d time.Duration) RegistryOption {
+	return func(c *registryConfig) {
+		c.timeout = d
+	}
+}
+
+// StartRegistry starts a registry container on a random loopback port and
+// waits until it serves the /v2/ endpoint. Callers must Close it.
+func StartRegistry(ctx context.Context, opts ...RegistryOption) (*Registry, error) {
+	cfg := registryConfig{
+		image:   DefaultRegistryImage,
+		timeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c, err := StartTestContainer(ctx, ContainerOptions{
+		Name:    RandomName("ig-registry"),
+		Image:   cfg.image,
+		Runtime: cfg.runtime,
+		Args:    []string{"--publish", "127.0.0.1::5000"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting registry: %w", err)
+	}
+
+	r := &Registry{container: c}
+
+	port, err := c.runtime.run(ctx, "port", c.ID, "5000/tcp")
+	if err != nil {
+		r.Close(context.Background())
+		return nil, fmt.Errorf("getting registry port: %w", err)
+	}
+	// Some runtimes print one mapping per line; we only published one.
+	r.Address = strings.TrimSpace(strings.SplitN(port, "\n", 2)[0])
+
+	if err := r.waitReady(ctx, cfg.timeout); err != nil {
+		r.Close(context.Background())
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Registry) waitReady(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := "http://" + r.Address + "/v2/"
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for registry at %s: %w", r.Address, ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// Image returns the reference of repo (e.g. "gadgets/trace_exec:latest")
+// hosted on this registry.
+func (r *Registry) Image(repo string) string {
+	return r.Address + "/" + repo
+}
+
+// InsecureFlags returns the ig image push/pull flags needed to talk plain HTTP
+// to this registry.
+func (r *Registry) InsecureFlags() []string {
+	return []string{"--insecure-registries", r.Address}
+}
+
+// Close removes the registry container and everything pushed to it.
+func (r *Registry) Close(ctx context.Context) error {
+	return RemoveTestContainer(ctx, r.container)
+}
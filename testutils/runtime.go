@@ -0,0 +1,67 @@
+package testutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// Runtime is the CLI used to manage test containers. The supported CLIs share
+// the docker command line syntax for everything the helpers need.
+type Runtime string
+
+const (
+	Docker  Runtime = "docker"
+	Podman  Runtime = "podman"
+	Nerdctl Runtime = "nerdctl"
+)
+
+// ErrNoRuntime is returned when no supported container runtime CLI is found.
+var ErrNoRuntime = errors.New("no container runtime CLI found in PATH (tried docker, podman, nerdctl)")
+
+// DetectRuntime returns the first supported container runtime CLI found in
+// PATH.
+func DetectRuntime() (Runtime, error) {
+	for _, rt := range []Runtime{Docker, Podman, Nerdctl} {
+		if _, err := exec.LookPath(string(rt)); err == nil {
+			return rt, nil
+		}
+	}
+
+	return "", ErrNoRuntime
+}
+
+func (rt Runtime) run(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, string(rt), args...)
+}
+
+// Restart restarts the daemon behind rt with systemctl and waits for it to
+// answer again, to test recovery from runtime restarts. Podman has no
+// daemon, so restarting it is a no-op.
+func (rt Runtime) Restart(ctx context.Context) error {
+	var unit string
+	switch rt {
+	case Docker:
+		unit = "docker"
+	case Nerdctl:
+		unit = "containerd"
+	default:
+		return nil
+	}
+
+	if _, err := run(ctx, "systemctl", "restart", unit); err != nil {
+		return fmt.Errorf("restarting %s: %w", rt, err)
+	}
+	for {
+		if _, err := rt.run(ctx, "info"); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s after restart: %w", rt, ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
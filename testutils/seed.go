@@ -0,0 +1,81 @@
+package testutils
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SeedEnv is the environment variable fixing the random seed, to reproduce
+// the names and nonces of a failed run exactly.
+const SeedEnv = "IG_TEST_SEED"
+
+var (
+	seedMu sync.Mutex
+	seed   int64
+	rng    *rand.Rand
+
+	// seedErr records an unparsable SeedEnv, which falls back to a time-based
+	// seed instead of failing every test binary importing this package.
+	seedErr error
+)
+
+func init() {
+	s := time.Now().UnixNano()
+	if v, ok := os.LookupEnv(SeedEnv); ok {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			seedErr = fmt.Errorf("invalid %s=%q: %w", SeedEnv, v, err)
+			fmt.Fprintf(os.Stderr, "warning: %s, using random seed %d\n", seedErr, s)
+		} else {
+			s = parsed
+		}
+	}
+	SetSeed(s)
+}
+
+// GetSeed returns the seed all random names and nonces derive from. It comes
+// from SeedEnv if set and valid, and from the current time otherwise.
+func GetSeed() int64 {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+
+	return seed
+}
+
+// SetSeed reseeds the generator behind random names and nonces, e.g. from a
+// test flag. Sequences only reproduce if tests draw names in the same order,
+// so parallel tests may still differ between runs.
+func SetSeed(s int64) {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+
+	seed = s
+	rng = rand.New(rand.NewPCG(uint64(s), 0))
+}
+
+// SeedHint returns a sentence telling how to reproduce the current run, for
+// failure messages.
+func SeedHint() string {
+	s := GetSeed()
+	hint := fmt.Sprintf("random seed %d, rerun with %s=%d to reproduce", s, SeedEnv, s)
+	if seedErr != nil {
+		hint += fmt.Sprintf("; ignored %s", seedErr)
+	}
+	return hint
+}
+
+// SeedError returns why SeedEnv was ignored, or nil if it was unset or valid.
+func SeedError() error {
+	return seedErr
+}
+
+func randUint32() uint32 {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+
+	return rng.Uint32()
+}
@@ -0,0 +1,86 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultTestZone is the DNS zone used for generated queries when none is
+// given. It's reserved for local use, so queries never leak onto the
+// internet.
+const DefaultTestZone = "ig-test.local"
+
+// Workload is a shell script generating activity tagged with a nonce. It only
+// relies on tools available in busybox, so it runs in the default test
+// container as well as on the host.
+type Workload struct {
+	Nonce  Nonce
+	Script string
+}
+
+// RunIn runs the workload inside c and returns its stdout. The nonce of the
+// workload, or else the one of ctx, is exported as NonceEnv.
+func (w Workload) RunIn(ctx context.Context, c *TestContainer) (string, error) {
+	return c.ExecShell(ctx, w.script(ctx))
+}
+
+// RunLocal runs the workload on the host with /bin/sh and returns its
+// stdout. The nonce of the workload, or else the one of ctx, is exported as
+// NonceEnv.
+func (w Workload) RunLocal(ctx context.Context) (string, error) {
+	return run(ctx, "/bin/sh", "-c", w.script(ctx))
+}
+
+// script returns the script of w, exporting its nonce first.
+func (w Workload) script(ctx context.Context) string {
+	n := w.Nonce
+	if n == "" {
+		n, _ = NonceFromContext(ctx)
+	}
+	if n == "" {
+		return w.Script
+	}
+	return fmt.Sprintf("export %s=%s\n%s", NonceEnv, shellQuote(string(n)), w.Script)
+}
+
+// HTTPRequests returns a workload issuing count HTTP GETs of n.Path() to
+// target ("host:port"), using curl when available and wget otherwise. Request
+// failures are ignored: the connections are the activity being generated.
+func HTTPRequests(n Nonce, target string, count int) Workload {
+	url := shellQuote("http://" + target + n.Path())
+	return Workload{
+		Nonce: n,
+		Script: fmt.Sprintf(`if command -v curl >/dev/null 2>&1; then fetch="curl -s -o /dev/null"; else fetch="wget -q -O /dev/null"; fi
+for i in $(seq 1 %d); do $fetch %s || true; done`, count, url),
+	}
+}
+
+// DNSQueries returns a workload resolving n.Domain(zone) count times. Queries
+// go to server, a bare address since nslookup takes no port, or to the
+// configured resolver if server is empty. Lookup failures are ignored.
+func DNSQueries(n Nonce, zone, server string, count int) Workload {
+	if zone == "" {
+		zone = DefaultTestZone
+	}
+	target := shellQuote(n.Domain(zone))
+	if server != "" {
+		target += " " + shellQuote(server)
+	}
+	return Workload{
+		Nonce:  n,
+		Script: fmt.Sprintf(`for i in $(seq 1 %d); do nslookup %s >/dev/null 2>&1 || true; done`, count, target),
+	}
+}
+
+// ExecBinary returns a workload that creates an executable named
+// n.BinaryName() in /tmp and runs it count times, so exec events carry the
+// nonce as comm.
+func ExecBinary(n Nonce, count int) Workload {
+	path := shellQuote("/tmp/" + n.BinaryName())
+	return Workload{
+		Nonce: n,
+		Script: fmt.Sprintf(`printf '#!/bin/sh\nexit 0\n' > %[1]s && chmod +x %[1]s
+for i in $(seq 1 %[2]d); do %[1]s; done
+rm -f %[1]s`, path, count),
+	}
+}
@@ -0,0 +1,57 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SignalWorkload returns a workload that starts a victim process named
+// n.BinaryName(), sends it signal (a name such as "USR1" or "HUP") count times
+// from the workload's shell, then kills it. The victim ignores signal so it
+// survives every delivery. The workload prints the victim's PID as seen from
+// where it runs.
+func SignalWorkload(n Nonce, signal string, count int) Workload {
+	signal = strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	victim := shellQuote("/tmp/" + n.BinaryName())
+
+	return Workload{
+		Nonce: n,
+		Script: fmt.Sprintf(`printf '#!/bin/sh\ntrap "" %[2]s\nwhile :; do sleep 1; done\n' > %[1]s && chmod +x %[1]s
+%[1]s & victim=$!
+sleep 0.5
+for i in $(seq 1 %[3]d); do kill -s %[2]s $victim; done
+kill -s KILL $victim
+wait $victim 2>/dev/null
+rm -f %[1]s
+echo $victim`, victim, signal, count),
+	}
+}
+
+// OOMWorkload returns a workload that starts a process named n.BinaryName()
+// which allocates memory until it is killed. Run it under a memory limit,
+// e.g. with StartOOMTestContainer, to get a controlled trace_oomkill victim.
+func OOMWorkload(n Nonce) Workload {
+	hog := shellQuote("/tmp/" + n.BinaryName())
+
+	return Workload{
+		Nonce: n,
+		// Doubling a shell variable grows memory exponentially with nothing
+		// but a POSIX shell, and keeps the nonce as the victim's comm.
+		Script: fmt.Sprintf(`printf '#!/bin/sh\nx=ig-oom\nwhile :; do x="$x$x"; done\n' > %[1]s && chmod +x %[1]s
+exec %[1]s`, hog),
+	}
+}
+
+// StartOOMTestContainer starts a busybox container limited to memoryLimit
+// (e.g. "64m", swap disabled) whose only process is the OOMWorkload hog. Use
+// TestContainer.Wait and OOMKilled to observe the kill. Callers must remove
+// it with RemoveTestContainer.
+func StartOOMTestContainer(ctx context.Context, n Nonce, memoryLimit string, rt Runtime) (*TestContainer, error) {
+	return StartTestContainer(ctx, ContainerOptions{
+		Image:   BusyboxImage,
+		Command: OOMWorkload(n).Script,
+		Runtime: rt,
+		Args:    []string{"--memory", memoryLimit, "--memory-swap", memoryLimit},
+	})
+}